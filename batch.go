@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"go-eCache/internal/xcontext"
+)
+
+const (
+	// defaultBatchWindowMillis will be used as the default window collecting the keys of a batch
+	defaultBatchWindowMillis = 1
+	// defaultMaxBatchSize will be used as the default max number of keys of a batch
+	defaultMaxBatchSize = 100
+)
+
+// loaderBatcher coalesces the concurrent DataLoader calls of the same batch into one call with the union of their keys.
+// A batch is dispatched once its window elapses or it reaches the max batch size, whichever comes first.
+type loaderBatcher struct {
+	window       time.Duration
+	maxBatchSize int
+
+	mu      sync.Mutex
+	batches map[loaderBatchKey]*loaderBatch // the batches collecting keys
+}
+
+// loaderBatchKey identifies a batch by the batch name and the function of the DataLoader,
+// so that the calls with different DataLoaders are never batched together.
+// Note that the closures of the same function literal share the same function, thus are batched together.
+type loaderBatchKey struct {
+	name   string
+	loader uintptr
+}
+
+func newLoaderBatchKey(name string, loader DataLoader) loaderBatchKey {
+	return loaderBatchKey{name: name, loader: reflect.ValueOf(loader).Pointer()}
+}
+
+// loaderBatch is a DataLoader call shared by the keys of multiple calls
+type loaderBatch struct {
+	ctx    context.Context
+	cancel context.CancelFunc // cancels ctx once the loader returns, nil if ctx has no deadline
+	loader DataLoader
+	keys   []string
+	keySet map[string]struct{}
+
+	done    chan struct{} // closed once the loader returns
+	dataMap map[string]interface{}
+	err     error
+}
+
+func newLoaderBatcher(config BatchConfig) *loaderBatcher {
+	if config.WindowMillis == 0 {
+		config.WindowMillis = defaultBatchWindowMillis
+	}
+	if config.MaxBatchSize == 0 {
+		config.MaxBatchSize = defaultMaxBatchSize
+	}
+
+	return &loaderBatcher{
+		window:       time.Duration(config.WindowMillis) * time.Millisecond,
+		maxBatchSize: config.MaxBatchSize,
+		batches:      make(map[loaderBatchKey]*loaderBatch),
+	}
+}
+
+// load loads keys by the batch of key, and returns the data of keys in the same order as keys.
+// The loader of the first call of a batch is called for the whole batch, with the values and the deadline of the call's ctx
+// but without its cancellation, while each call waits for the batch until its own ctx is done.
+func (b *loaderBatcher) load(ctx context.Context, key loaderBatchKey, loader DataLoader, keys []string) ([]interface{}, error) {
+	batch := b.join(ctx, key, loader, keys)
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if batch.dataMap == nil {
+		return nil, batch.err
+	}
+	dataList := make([]interface{}, len(keys))
+	for idx, key := range keys {
+		dataList[idx] = batch.dataMap[key]
+	}
+	return dataList, batch.err
+}
+
+// join adds keys to the batch of key, a new batch is started if there is no batch collecting keys
+func (b *loaderBatcher) join(ctx context.Context, key loaderBatchKey, loader DataLoader, keys []string) *loaderBatch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch, ok := b.batches[key]
+	if !ok {
+		batch = &loaderBatch{
+			ctx:    xcontext.Detach(ctx),
+			loader: loader,
+			keySet: make(map[string]struct{}),
+			done:   make(chan struct{}),
+		}
+		// the loader is not left running once the first call of the batch times out
+		if deadline, ok := ctx.Deadline(); ok {
+			batch.ctx, batch.cancel = context.WithDeadline(batch.ctx, deadline)
+		}
+		b.batches[key] = batch
+		time.AfterFunc(b.window, func() {
+			b.dispatch(key, batch)
+		})
+	}
+
+	for _, key := range keys {
+		if _, ok := batch.keySet[key]; !ok {
+			batch.keySet[key] = struct{}{}
+			batch.keys = append(batch.keys, key)
+		}
+	}
+
+	if len(batch.keys) >= b.maxBatchSize {
+		delete(b.batches, key)
+		go batch.run()
+	}
+
+	return batch
+}
+
+// dispatch runs the batch once its window elapses, unless it is already dispatched due to the max batch size
+func (b *loaderBatcher) dispatch(key loaderBatchKey, batch *loaderBatch) {
+	b.mu.Lock()
+	if b.batches[key] != batch {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.batches, key)
+	b.mu.Unlock()
+
+	batch.run()
+}
+
+// run calls the loader with the keys of the batch, the panic of the loader is reported as errDataLoaderPanic
+func (batch *loaderBatch) run() {
+	defer close(batch.done)
+	if batch.cancel != nil {
+		defer batch.cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			batch.dataMap = nil
+			batch.err = errDataLoaderPanic
+		}
+	}()
+
+	dataList, err := batch.loader(batch.ctx, batch.keys)
+	batch.err = err
+	if len(dataList) != len(batch.keys) {
+		return
+	}
+
+	batch.dataMap = make(map[string]interface{}, len(batch.keys))
+	for idx, key := range batch.keys {
+		batch.dataMap[key] = dataList[idx]
+	}
+}
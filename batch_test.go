@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestBatchingRedisCache(t *testing.T, config BatchConfig) *RedisCache {
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{Address: server.addr(), ManufacturerConfig: ManufacturerConfig{BatchConfig: config}})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+	return c
+}
+
+// recordingLoader loads "val_<key>" for each key, and records the keys of each call
+type recordingLoader struct {
+	mu    sync.Mutex
+	calls [][]string
+	err   error
+	delay time.Duration
+}
+
+func (l *recordingLoader) load(ctx context.Context, keys []string) ([]interface{}, error) {
+	l.mu.Lock()
+	l.calls = append(l.calls, keys)
+	l.mu.Unlock()
+	time.Sleep(l.delay)
+
+	dataList := make([]interface{}, len(keys))
+	for idx, key := range keys {
+		dataList[idx] = "val_" + key
+	}
+	return dataList, l.err
+}
+
+func (l *recordingLoader) callCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.calls)
+}
+
+// loadConcurrently loads keys by concurrent Loads, and returns the value and error of each key
+func loadConcurrently(ctx context.Context, c Cache, loader DataLoader, keys []string, opts ...OperationOption) (map[string]string, map[string]error) {
+	var mu sync.Mutex
+	vals := make(map[string]string, len(keys))
+	errs := make(map[string]error, len(keys))
+
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			var val string
+			err := c.Load(ctx, loader, key, &val, time.Minute, opts...)
+			mu.Lock()
+			vals[key], errs[key] = val, err
+			mu.Unlock()
+		}(key)
+	}
+	wg.Wait()
+
+	return vals, errs
+}
+
+func TestLoadBatching(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBatchingRedisCache(t, BatchConfig{WindowMillis: 50})
+	loader := &recordingLoader{}
+
+	keys := []string{"k1", "k2", "k3", "k4", "k5"}
+	vals, errs := loadConcurrently(ctx, c, loader.load, keys, WithBatching("test"))
+	for _, key := range keys {
+		if errs[key] != nil || vals[key] != "val_"+key {
+			t.Fatalf("expect val_%v, got: %v, err: %v", key, vals[key], errs[key])
+		}
+	}
+	if loader.callCount() != 1 || len(loader.calls[0]) != len(keys) {
+		t.Fatalf("expect one loader call with all keys, got: %v", loader.calls)
+	}
+
+	// the loads without batching call the loader separately
+	loader = &recordingLoader{}
+	_, _ = loadConcurrently(ctx, c, loader.load, []string{"k6", "k7"})
+	if loader.callCount() != 2 {
+		t.Fatalf("expect separate loader calls without batching, got: %v", loader.calls)
+	}
+}
+
+func TestLoadBatchingMaxBatchSize(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBatchingRedisCache(t, BatchConfig{WindowMillis: 10000, MaxBatchSize: 2})
+	loader := &recordingLoader{}
+
+	keys := []string{"k1", "k2", "k3", "k4"}
+	start := time.Now()
+	vals, errs := loadConcurrently(ctx, c, loader.load, keys, WithBatching("test"))
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expect the batches dispatched once full, took: %v", elapsed)
+	}
+	for _, key := range keys {
+		if errs[key] != nil || vals[key] != "val_"+key {
+			t.Fatalf("expect val_%v, got: %v, err: %v", key, vals[key], errs[key])
+		}
+	}
+	if loader.callCount() != 2 {
+		t.Fatalf("expect 2 loader calls, got: %v", loader.calls)
+	}
+}
+
+func TestLoadBatchingError(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBatchingRedisCache(t, BatchConfig{WindowMillis: 50})
+	loaderErr := errors.New("loader err")
+	loader := &recordingLoader{err: loaderErr}
+
+	_, errs := loadConcurrently(ctx, c, loader.load, []string{"k1", "k2", "k3"}, WithBatching("test"))
+	for key, err := range errs {
+		if err != loaderErr {
+			t.Fatalf("expect loader err for %v, got: %v", key, err)
+		}
+	}
+
+	panicLoader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		panic("loader panic")
+	}
+	_, errs = loadConcurrently(ctx, c, panicLoader, []string{"k4", "k5"}, WithBatching("test"))
+	for key, err := range errs {
+		if err != errDataLoaderPanic {
+			t.Fatalf("expect data loader panic for %v, got: %v", key, err)
+		}
+	}
+}
+
+func TestLoadBatchingContextTimeout(t *testing.T) {
+	c := newTestBatchingRedisCache(t, BatchConfig{WindowMillis: 10})
+	loader := &recordingLoader{delay: 200 * time.Millisecond}
+
+	errCh := make(chan error, 2)
+	for idx := 0; idx < 2; idx++ {
+		go func(idx int) {
+			ctx := context.Background()
+			if idx == 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+				defer cancel()
+			}
+			var val string
+			err := c.Load(ctx, loader.load, fmt.Sprintf("k%v", idx), &val, time.Minute, WithBatching("test"))
+			if idx == 0 && err != context.DeadlineExceeded {
+				err = fmt.Errorf("expect the load timed out, got: %v", err)
+			} else if idx == 1 && (err != nil || val != "val_k1") {
+				err = fmt.Errorf("expect val_k1 unaffected by the other load, got: %v, err: %v", val, err)
+			} else {
+				err = nil
+			}
+			errCh <- err
+		}(idx)
+	}
+	for idx := 0; idx < 2; idx++ {
+		if err := <-errCh; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadBatchingDifferentLoaders(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBatchingRedisCache(t, BatchConfig{WindowMillis: 50})
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 2)
+	for _, prefix := range []string{"a_", "b_"} {
+		loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+			return []interface{}{"a_" + keys[0]}, nil
+		}
+		if prefix == "b_" {
+			loader = func(ctx context.Context, keys []string) ([]interface{}, error) {
+				return []interface{}{"b_" + keys[0]}, nil
+			}
+		}
+		wg.Add(1)
+		go func(prefix string, loader DataLoader) {
+			defer wg.Done()
+			var val string
+			if err := c.Load(ctx, loader, prefix+"key", &val, time.Minute, WithBatching("test")); err != nil || val != prefix+prefix+"key" {
+				errCh <- fmt.Errorf("expect the load served by its own loader, got: %v, err: %v", val, err)
+			}
+		}(prefix, loader)
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadBatchingDeadline(t *testing.T) {
+	c := newTestBatchingRedisCache(t, BatchConfig{WindowMillis: 10})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var loaderDeadline time.Time
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		loaderDeadline, _ = ctx.Deadline()
+		return []interface{}{"val"}, nil
+	}
+	var val string
+	if err := c.Load(ctx, loader, "key", &val, time.Minute, WithBatching("test")); err != nil || val != "val" {
+		t.Fatalf("expect val, got: %v, err: %v", val, err)
+	}
+	if deadline, _ := ctx.Deadline(); !loaderDeadline.Equal(deadline) {
+		t.Fatalf("expect the deadline of the first load applied to the loader, got: %v", loaderDeadline)
+	}
+}
@@ -24,9 +24,84 @@ type OperationOption func(*cacheOperationOptions)
 
 // Cache is the interface of a cache store
 type Cache interface {
+	// Get gets the value of key and sets it to receiver, which must be a pointer.
+	// Returns ErrCacheMiss if the key does not exist.
+	Get(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) error
+
+	// GetWithMeta is similar like Get, but also returns the meta of the item, e.g. the remaining TTL, refer to ItemMeta.
+	// Returns ErrCacheMiss if the key does not exist.
+	GetWithMeta(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) (ItemMeta, error)
+
+	// GetWithVersion is similar like Get, but also returns the version of the item, which changes on every write.
+	// The version is used to update the item by CompareAndSwap. Returns ErrCacheMiss if the key does not exist.
+	GetWithVersion(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) (int64, error)
+
+	// TTL returns the meta of the item of key without decoding the value, refer to ItemMeta.
+	// Returns ErrCacheMiss if the key does not exist.
+	TTL(ctx context.Context, key string, opts ...OperationOption) (ItemMeta, error)
+
+	// GetMany gets the values of the keys of receiverMap and sets them to the receivers.
+	// For key not cached, the receiver is handled by NonExistKeyStrategy, refer to WithNonExistKeyStrategy.
+	GetMany(ctx context.Context, receiverMap map[string]interface{}, opts ...OperationOption) error
+
+	// Set sets value to key, replacing any existing value.
+	// If expire is DefaultExpiration, it will use the default expiration of the cache.
+	Set(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error
+
+	// SetMany sets multiple values, replacing any existing values.
+	// If expire is DefaultExpiration, it will use the default expiration of the cache.
+	SetMany(ctx context.Context, valueMap map[string]interface{}, expire time.Duration, opts ...OperationOption) error
+
+	// Add sets value to key only if the key does not exist. Returns ErrNotStored otherwise.
+	// The tombstone cached by WithNegativeCaching and the data past hard expiration kept by WithStaleIfError are regarded as non-existent.
+	Add(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error
+
+	// Replace sets value to key only if the key already exists. Returns ErrNotStored otherwise.
+	Replace(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error
+
+	// CompareAndSwap sets value to key only if the version of the item is still version, i.e. the item is not modified
+	// since it is read by GetWithVersion. Returns ErrNotStored if the key does not exist or the version does not match.
+	// If expire is DefaultExpiration, it will use the default expiration of the cache.
+	CompareAndSwap(ctx context.Context, key string, value interface{}, version int64, expire time.Duration, opts ...OperationOption) error
+
+	// Delete deletes key. Returns ErrCacheMiss if the key does not exist.
+	Delete(ctx context.Context, key string, opts ...OperationOption) error
+
+	// DeleteMany deletes multiple keys, keys not existing are ignored. Apply WithDeletedKeys to receive whether each key existed.
+	DeleteMany(ctx context.Context, keys []string, opts ...OperationOption) error
+
+	// InvalidateTags invalidates all the data tagged by any of tags, refer to WithTags.
+	InvalidateTags(ctx context.Context, tags ...string) error
+
+	// Increment increments the integer value of key by delta and returns the new value.
+	// If the key does not exist, it is created with 0 before the operation, unless WithInitNonExistKey(false) is applied,
+	// in which case ErrCacheMiss is returned.
+	Increment(ctx context.Context, key string, delta uint64, opts ...OperationOption) (int64, error)
+
+	// Decrement decrements the integer value of key by delta and returns the new value.
+	// Refer to Increment for the behavior on non-exist key.
+	Decrement(ctx context.Context, key string, delta uint64, opts ...OperationOption) (int64, error)
+
+	// Expire updates the expiration of key. Returns ErrCacheMiss if the key does not exist,
+	// and ErrNotStored if the key keeps being modified concurrently.
+	Expire(ctx context.Context, key string, expire time.Duration, opts ...OperationOption) error
+
 	// Load is similar like Get, but if the key doesn't exist, it will invoke loader to load the data and store to cache
 	// If expire is DefaultExpiration, it will use the default expiration of the cache.
 	Load(ctx context.Context, loader DataLoader, key string, receiver interface{}, expire time.Duration, opts ...OperationOption) error
+
+	// LoadMany is similar like GetMany, but for keys don't exist, it will invoke loader to load the data and store to cache
+	// If expire is DefaultExpiration, it will use the default expiration of the cache.
+	LoadMany(ctx context.Context, loader DataLoader, receiverMap map[string]interface{}, expire time.Duration, opts ...OperationOption) error
+
+	// Flush deletes all keys in the cache
+	Flush(ctx context.Context) error
+
+	// Ping checks the accessibility of the cache
+	Ping(ctx context.Context) error
+
+	// Close releases all open resources, should not perform any operations after close
+	Close(ctx context.Context) error
 }
 
 type composableCacheInner interface {
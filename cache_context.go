@@ -0,0 +1,53 @@
+package cache
+
+import "context"
+
+type cacheContextKey int
+
+const (
+	cacheBypassContextKey cacheContextKey = iota
+	forceRefreshContextKey
+	readOnlyContextKey
+)
+
+// WithCacheBypass returns a copy of ctx with which cache operations skip the cache entirely:
+// reads miss, writes are skipped, and Load/LoadMany call the DataLoader without caching the loaded data.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassContextKey, true)
+}
+
+// WithForceRefresh returns a copy of ctx with which cached data are ignored:
+// reads miss, and Load/LoadMany reload data by the DataLoader and overwrite the cached data.
+func WithForceRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceRefreshContextKey, true)
+}
+
+// WithReadOnly returns a copy of ctx with which the cache is never modified:
+// writes are skipped, Add, Replace and CompareAndSwap return ErrNotStored,
+// and Load/LoadMany neither write the loaded data back nor refresh soft expired data.
+//
+// It is designed for shadow or replay traffic, whose DataLoader may load data different from the real traffic.
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyContextKey, true)
+}
+
+func isCacheBypassCtx(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassContextKey).(bool)
+	return bypass
+}
+
+func isForceRefreshCtx(ctx context.Context) bool {
+	forceRefresh, _ := ctx.Value(forceRefreshContextKey).(bool)
+	return forceRefresh
+}
+
+func isReadOnlyCtx(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyContextKey).(bool)
+	return readOnly
+}
+
+// isIsolatedCtx returns if the operation with ctx should not be synchronized with other operations,
+// i.e. the data loaded by it are neither cached nor shared with others
+func isIsolatedCtx(ctx context.Context) bool {
+	return isCacheBypassCtx(ctx) || isReadOnlyCtx(ctx)
+}
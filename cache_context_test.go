@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheContext(t *testing.T) {
+	ctx := context.Background()
+	c := newTestInMemoryCache(t)
+
+	var loadCount int
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		loadCount++
+		values := make([]interface{}, len(keys))
+		for idx := range keys {
+			values[idx] = "loaded"
+		}
+		return values, nil
+	}
+	var val string
+
+	// bypass skips the cache entirely
+	bypassCtx := WithCacheBypass(ctx)
+	if err := c.Set(bypassCtx, "bypass", "v", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	if err := c.Get(ctx, "bypass", &val); err != ErrCacheMiss {
+		t.Fatalf("expect set skipped by bypass, got: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.Load(bypassCtx, loader, "bypass", &val, time.Minute, WithWaitRistretto()); err != nil || val != "loaded" {
+			t.Fatalf("expect loaded, got: %v, err: %v", val, err)
+		}
+	}
+	if loadCount != 2 {
+		t.Fatalf("expect loader called every time by bypass, got: %v", loadCount)
+	}
+	if err := c.Get(ctx, "bypass", &val); err != ErrCacheMiss {
+		t.Fatalf("expect loaded data not cached by bypass, got: %v", err)
+	}
+
+	// force-refresh reloads and overwrites
+	if err := c.Set(ctx, "refresh", "v", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	refreshCtx := WithForceRefresh(ctx)
+	if err := c.Get(refreshCtx, "refresh", &val); err != ErrCacheMiss {
+		t.Fatalf("expect cached data ignored by force-refresh, got: %v", err)
+	}
+	if err := c.Load(refreshCtx, loader, "refresh", &val, time.Minute, WithWaitRistretto()); err != nil || val != "loaded" {
+		t.Fatalf("expect reloaded, got: %v, err: %v", val, err)
+	}
+	if err := c.Get(ctx, "refresh", &val); err != nil || val != "loaded" {
+		t.Fatalf("expect cached data overwritten by force-refresh, got: %v, err: %v", val, err)
+	}
+
+	// read-only prevents writes
+	readOnlyCtx := WithReadOnly(ctx)
+	if err := c.Delete(readOnlyCtx, "refresh"); err != nil {
+		t.Fatalf("delete err: %v", err)
+	}
+	if err := c.Get(readOnlyCtx, "refresh", &val); err != nil || val != "loaded" {
+		t.Fatalf("expect delete skipped by read-only, got: %v, err: %v", val, err)
+	}
+	if err := c.Add(readOnlyCtx, "readonly", "v", time.Minute); err != ErrNotStored {
+		t.Fatalf("expect not stored on add by read-only, got: %v", err)
+	}
+	if err := c.Load(readOnlyCtx, loader, "readonly", &val, time.Minute, WithWaitRistretto()); err != nil || val != "loaded" {
+		t.Fatalf("expect loaded, got: %v, err: %v", val, err)
+	}
+	if err := c.Get(ctx, "readonly", &val); err != ErrCacheMiss {
+		t.Fatalf("expect loaded data not cached by read-only, got: %v", err)
+	}
+}
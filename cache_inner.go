@@ -30,6 +30,10 @@ type innerCache interface {
 	// error if it does not.
 	replace(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...innerOperationOption) error
 
+	// compareAndSwap sets a new value for the cache key only if match returns true for its current value, atomically.
+	// Returns ErrNotStored if the key does not exist, match returns false or the key is modified concurrently.
+	compareAndSwap(ctx context.Context, key string, match func(value interface{}) bool, value interface{}, expire time.Duration, opts ...innerOperationOption) error
+
 	// delete removes an item from the cache. May return ErrCacheMiss (depends on concrete implementation)
 	delete(ctx context.Context, key string) error
 
@@ -37,6 +41,10 @@ type innerCache interface {
 	// opts determines if will apply `noReply`, which is only supported by memcached.
 	deleteMany(ctx context.Context, keys []string, opts ...innerOperationOption) error
 
+	// deleteManyWithResult deletes multiple items from the cache, and returns whether each key existed.
+	// The returned slice has same length with keys.
+	deleteManyWithResult(ctx context.Context, keys []string) ([]bool, error)
+
 	// increment increments a real number, and returns error if the value is not real
 	// `initNonExistKey` in opts determines if will create value with 0 before perform operation if key not exists
 	// `initNonExistKey` by default will be applied
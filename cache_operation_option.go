@@ -35,6 +35,18 @@ type cacheOperationOptions struct {
 	expirationMap            map[string]time.Duration // used to specify a key's hard expiration, with the highest priority in Set/Load(Many)
 	hardExpirationMultiLayer []time.Duration          // used to specify a layer's hard expiration, with the medium priority between `expireMap` (high) and `expire` variable (low), applicable to Set/Load(Many) in MultiLayerCache only
 	softExpirationMultiLayer []time.Duration          // used to specify a layer's soft expiration, with the higher priority than `softExpiration`, applicable to Set/Load(Many) in MultiLayerCache only
+	deletedKeys              map[string]bool          // used to receive whether the keys existed, applicable to DeleteMany only
+	tags                     []string                 // tags of to-cache data, applicable to Set/SetMany/Load(Many)
+	tagVersions              []int64                  // versions of tags in the shared cache to be stored with the data, resolved internally
+	version                  int64                    // version of to-cache data, generated on encoding if not set, resolved internally
+	computeTimeMs            int64                    // time in milliseconds the DataLoader took to load to-cache data, resolved internally
+	earlyRecomputationBeta   float64                  // used to refresh data before hard expiration probabilistically, applicable to Load(Many)
+	staleIfError             time.Duration            // grace window to keep data past hard expiration and serve it if DataLoader fails, applicable to Load(Many)
+	staleKeys                map[string]bool          // used to receive the keys served with stale data, applicable to Load(Many) only
+	staleResultMap           map[string]loadResult    // the data kept in the grace window of stale-if-error, resolved internally
+	negativeCacheTTL         time.Duration            // expiration of the tombstones cached for the keys DataLoader returns nil for, applicable to Load(Many)
+	tombstone                bool                     // whether to-cache data is a tombstone, resolved internally
+	batchName                string                   // name of the batch coalescing DataLoader calls, applicable to Load(Many)
 }
 
 var operationOptionsPool = &sync.Pool{
@@ -69,6 +81,18 @@ func (p *cacheOperationOptions) reset() {
 	p.expirationMap = nil
 	p.softExpirationMultiLayer = nil
 	p.hardExpirationMultiLayer = nil
+	p.deletedKeys = nil
+	p.tags = nil
+	p.tagVersions = nil
+	p.version = 0
+	p.computeTimeMs = 0
+	p.earlyRecomputationBeta = 0
+	p.staleIfError = 0
+	p.staleKeys = nil
+	p.staleResultMap = nil
+	p.negativeCacheTTL = 0
+	p.tombstone = false
+	p.batchName = ""
 }
 
 func newCacheOperationOptions() *cacheOperationOptions {
@@ -127,6 +151,63 @@ func WithSoftExpirationMultiLayer(softExpirationMultiLayer []time.Duration) Oper
 	}
 }
 
+// WithEarlyRecomputation enables the probabilistic early recomputation (XFetch) for Load(Many).
+// The time DataLoader takes to load the data is recorded along with the data, and each read refreshes the data
+// asynchronously before its hard expiration with a probability growing as the hard expiration approaches,
+// so the refreshes of hot keys are spread across goroutines and instances without distributed lock.
+// beta scales the earliness, 1 is the usual choice, and a larger value refreshes earlier. It is disabled if beta is not positive.
+// It works along with soft expiration and any StampedeMitigationStrategy.
+func WithEarlyRecomputation(beta float64) OperationOption {
+	return func(option *cacheOperationOptions) {
+		option.earlyRecomputationBeta = beta
+	}
+}
+
+// WithStaleIfError keeps the data loaded by Load(Many) in the cache for maxStale past its hard expiration,
+// and serves the data kept in the grace window if DataLoader fails or times out on reloading it.
+// The data past hard expiration is treated as missing otherwise, e.g. by Get(Many) or the Load(Many) without stale-if-error.
+// It has the higher priority than StaleIfErrorMillis of ManufacturerConfig, and the keys served with stale data
+// can be received by WithStaleKeys.
+func WithStaleIfError(maxStale time.Duration) OperationOption {
+	return func(option *cacheOperationOptions) {
+		option.staleIfError = maxStale
+	}
+}
+
+// WithStaleKeys receives the keys served with the data past hard expiration by Load(Many), refer to WithStaleIfError.
+// For each key served with stale data, staleKeys[key] is set to true.
+func WithStaleKeys(staleKeys map[string]bool) OperationOption {
+	return func(option *cacheOperationOptions) {
+		option.staleKeys = staleKeys
+	}
+}
+
+// WithNegativeCaching caches a tombstone for ttl if DataLoader returns nil for a key without error,
+// so that the key is served as missing by Load(Many) without calling DataLoader until the tombstone expires.
+// The tombstone is treated as missing by Get(Many) as well, overwritten by Set and Add, and removed by Delete.
+// It has the higher priority than NegativeCacheConfig of ManufacturerConfig.
+// The expiration of the tombstone is rounded up to whole seconds, so it may live up to 1 second longer than ttl.
+//
+// The tombstone is marked in the header of bytes protocol, so it is not cached if encoding is disabled,
+// and it can not be decoded by the versions of this library without negative caching.
+func WithNegativeCaching(ttl time.Duration) OperationOption {
+	return func(option *cacheOperationOptions) {
+		option.negativeCacheTTL = ttl
+	}
+}
+
+// WithBatching coalesces the concurrent DataLoader calls of Load(Many) with the same batch name and the same DataLoader function
+// into one call with the union of their keys, refer to BatchConfig of ManufacturerConfig for the window and the max size of a batch.
+// The DataLoaders are compared by function, so the closures of the same function literal are batched together
+// and only the DataLoader of the first load of a batch is called, they MUST be equivalent if sharing the batch name.
+// The DataLoader is called with the values and the deadline of the first load's ctx but without its cancellation,
+// while each load waits for the batch until its own ctx is done. Each load caches the data of its own keys with its own expiration and options.
+func WithBatching(name string) OperationOption {
+	return func(option *cacheOperationOptions) {
+		option.batchName = name
+	}
+}
+
 // WithOnErrExpiration sets on-error expiration for current cache data.
 // If data loader return an error with full length result, the result will be cached with on-error expiration.
 // The on-error expiration will take effect for Load(Many).
@@ -186,3 +267,28 @@ func WithWaitRistretto() OperationOption {
 		option.waitRistretto = true
 	}
 }
+
+// WithDeletedKeys receives whether each key existed before being deleted by DeleteMany.
+// For each key, deletedKeys[key] is set to true if the key existed, or false if the key did not exist and was not in deletedKeys.
+// For MultiLayerCache and MigrationCache, a key is reported as existed if it existed in any of the underlying caches.
+//
+// The reply of each key is required, so `NoReply` flag is ignored when it is applied.
+func WithDeletedKeys(deletedKeys map[string]bool) OperationOption {
+	return func(option *cacheOperationOptions) {
+		option.deletedKeys = deletedKeys
+	}
+}
+
+// WithTags tags the to-cache data, so that the data can be invalidated by InvalidateTags of any of the tags.
+// Applicable to Set/SetMany/Load/LoadMany.
+//
+// For in-memory cache, the keys of each tag are indexed in memory. Keys are not removed from the index once overwritten
+// without the tag, so InvalidateTags may delete such keys as well.
+// For shared caches (e.g. redis, memcached), the versions of tags are stored in the cache along with the tagged data,
+// and tagged data with any tag invalidated afterwards is treated as missing on read, at the cost of reading tag versions
+// on each read of tagged data. The versions of tags are stored without expiration.
+func WithTags(tags ...string) OperationOption {
+	return func(option *cacheOperationOptions) {
+		option.tags = tags
+	}
+}
@@ -3,12 +3,11 @@ package cache
 import (
 	"context"
 	"go-eCache/internal/xcontext"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
-
-	"go-eCache/internal/utils"
 )
 
 // cacheType's value should map with cache.Type's
@@ -17,6 +16,8 @@ type cacheType int
 const (
 	redis cacheType = iota + 1
 	inMemory
+	_ // multilayer is not backed by cacheWrapper
+	memcached
 )
 
 // cacheWrapperInner is a immutable struct
@@ -26,11 +27,15 @@ type cacheWrapperInner struct {
 	cacheType           cacheType
 	cache               innerCache
 	cacheHostName       string
+	keyHandler          keyHandler
 	codecHandler        codecHandler
 	encodingHandler     encodingHandler
 	manufacturerHandler manufacturerHandler
 	defaultExpiration   time.Duration
 	maxExpiration       time.Duration
+	observationConfig   observationConfig
+	hotKeyHandler       *hotKeyHandler
+	hotKeyConfig        HotKeyConfig // the config of hotKeyHandler, the handler is kept by updateConfig if it is unchanged
 	isDisabled          bool
 	isClosed            uint32
 }
@@ -52,6 +57,8 @@ func (s cacheType) String() string {
 		return "redis"
 	case inMemory:
 		return "inmemory"
+	case memcached:
+		return "memcached"
 	}
 	return "unknown"
 }
@@ -81,13 +88,33 @@ func newCacheWrapperInner(name string, config Config) (*cacheWrapperInner, error
 	}
 
 	switch config.Type {
+	case Redis:
+		redisConfig := config.Redis
+		if err = fillCacheWrapperInnerFieldsWithRedisConfig(redisConfig, newInner); err != nil {
+			return nil, err
+		}
+		newInner.cache, err = newRedisCache(redisConfig)
+		if err != nil {
+			return nil, err
+		}
 	case InMemory:
 		inMemoryConfig := config.InMemory
+		if err = fillCacheWrapperInnerFieldsWithInMemConfig(inMemoryConfig, newInner); err != nil {
+			return nil, err
+		}
 		newInner.cache, err = newInMemoryCache(inMemoryConfig)
 		if err != nil {
 			return nil, err
 		}
-		fillCacheWrapperInnerFieldsWithInMemConfig(inMemoryConfig, newInner)
+	case Memcached:
+		memcachedConfig := config.Memcached
+		if err = fillCacheWrapperInnerFieldsWithMemcachedConfig(memcachedConfig, newInner); err != nil {
+			return nil, err
+		}
+		newInner.cache, err = newMemcachedCache(memcachedConfig)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		return nil, errorConfigTypeNotSupported
 	}
@@ -95,27 +122,76 @@ func newCacheWrapperInner(name string, config Config) (*cacheWrapperInner, error
 	return newInner, nil
 }
 
-func fillCacheWrapperInnerFieldsWithInMemConfig(config InMemoryCacheConfig, newInner *cacheWrapperInner) {
+func fillCacheWrapperInnerFieldsWithInMemConfig(config InMemoryCacheConfig, newInner *cacheWrapperInner) error {
 	newInner.cacheType = inMemory
 	newInner.defaultExpiration = config.defaultExpiration()
 	newInner.maxExpiration = time.Duration(config.MaxExpirationSecs) * time.Second
+	newInner.keyHandler = newKeyHandler(config.KeyConfig, 0)
 	newInner.codecHandler = newCodecHandler(config.CodecConfig)
 	newInner.manufacturerHandler = newManufacturerHandler(config.ManufacturerConfig)
-	var err error
+	newInner.isDisabled = config.DisableConfig.Disable
+	newInner.observationConfig = newObservationConfig(config.ObservationConfig)
+	return newInner.fillHotKeyHandler(config.HotKeyConfig, false)
+}
+
+func fillCacheWrapperInnerFieldsWithRedisConfig(config RedisConfig, newInner *cacheWrapperInner) error {
+	newInner.cacheType = redis
+	newInner.cacheHostName = config.Address
+	newInner.defaultExpiration = config.defaultExpiration()
+	newInner.maxExpiration = time.Duration(config.MaxExpirationSecs) * time.Second
+	newInner.keyHandler = newKeyHandler(config.KeyConfig, 0)
+	newInner.codecHandler = newCodecHandler(config.CodecConfig)
+	newInner.encodingHandler = newEncodingHandler(config.EncodingConfig)
+	newInner.manufacturerHandler = newManufacturerHandler(config.ManufacturerConfig)
+	newInner.isDisabled = config.DisableConfig.Disable
+	newInner.observationConfig = newObservationConfig(config.ObservationConfig)
+	return newInner.fillHotKeyHandler(config.HotKeyConfig, true)
+}
+
+func fillCacheWrapperInnerFieldsWithMemcachedConfig(config MemcachedConfig, newInner *cacheWrapperInner) error {
+	newInner.cacheType = memcached
+	newInner.cacheHostName = config.hostName()
+	newInner.defaultExpiration = config.defaultExpiration()
+	newInner.maxExpiration = time.Duration(config.MaxExpirationSecs) * time.Second
+	newInner.keyHandler = newKeyHandler(config.KeyConfig, memcachedMaxKeyLength)
+	newInner.codecHandler = newCodecHandler(config.CodecConfig)
+	newInner.encodingHandler = newEncodingHandler(config.EncodingConfig)
+	newInner.manufacturerHandler = newManufacturerHandler(config.ManufacturerConfig)
+	newInner.isDisabled = config.DisableConfig.Disable
+	newInner.observationConfig = newObservationConfig(config.ObservationConfig)
+	return newInner.fillHotKeyHandler(config.HotKeyConfig, true)
+}
+
+// fillHotKeyHandler creates the hotKeyHandler from config, the existing handler is kept if config is unchanged,
+// so that the detected hot keys and the local items are not lost by updating the other configs.
+func (c *cacheWrapperInner) fillHotKeyHandler(config HotKeyConfig, isRemote bool) error {
+	if c.hotKeyHandler != nil && reflect.DeepEqual(c.hotKeyConfig, config) {
+		return nil
+	}
+
+	hotKeyHandler, err := newHotKeyHandler(config, isRemote)
 	if err != nil {
-		// will only return an error if provided hotkey config is invalid which should not be possible.
-		panic(err)
+		return err
 	}
+	c.hotKeyHandler = hotKeyHandler
+	c.hotKeyConfig = config
+	return nil
+}
+
+func (c *cacheWrapper) get(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) error {
+	_, err := c.getWithMeta(ctx, cmdGet, key, receiver, opts...)
+	return err
 }
 
-func (c *cacheWrapper) get(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) (err error) {
+// getWithMeta gets the value of key and its meta, the value is only set to receiver if command is not cmdTTL
+func (c *cacheWrapper) getWithMeta(ctx context.Context, command string, key string, receiver interface{}, opts ...OperationOption) (meta ItemMeta, err error) {
 	inner := c.loadCacheWrapperInner()
 
 	if inner.isCacheClosed() {
-		return ErrCacheClosed
+		return meta, ErrCacheClosed
 	}
-	if c.isDisabled(ctx) {
-		return ErrCacheMiss
+	if inner.isReadSkipped(ctx) {
+		return meta, ErrCacheMiss
 	}
 
 	option := newCacheOperationOptions()
@@ -129,17 +205,17 @@ func (c *cacheWrapper) get(ctx context.Context, key string, receiver interface{}
 	stats := &RequestStats{
 		CacheName:      inner.name,
 		CacheType:      inner.cacheType.String(),
-		CacheOperation: cmdGet,
+		CacheOperation: command,
 		TotalKeyCount:  1,
 		RequestSize:    len(fixedKey),
 		hostName:       inner.cacheHostName,
 	}
 
-	requestStatsDecorator(ctx, stats, func() error {
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
 		stats.req = key
 
 		var value interface{}
-		value, err = inner.cache.get(ctx, fixedKey)
+		value, err = inner.getFromCache(ctx, fixedKey)
 		if err != nil {
 			return err
 		}
@@ -148,10 +224,22 @@ func (c *cacheWrapper) get(ctx context.Context, key string, receiver interface{}
 		stats.SuccessKeyCount = 1
 
 		var data interface{}
-		data, _, err = inner.decode(value, option.skipEncodeDecode)
+		var header metaHeader
+		data, header, err = inner.decode(value, option.skipEncodeDecode)
 		if err != nil {
 			return err
 		}
+		now := time.Now()
+		if header.Tombstone || inner.getStaleByTags(ctx, []metaHeader{header})[0] || isHardExpired(now, header) {
+			stats.SuccessKeyCount = 0
+			err = ErrCacheMiss
+			return err
+		}
+
+		meta = genItemMeta(header, stats.ResponseSize, now)
+		if command == cmdTTL {
+			return nil
+		}
 
 		err = inner.setCacheDataToReceiver(data, receiver, option)
 		stats.resp = receiver
@@ -159,7 +247,7 @@ func (c *cacheWrapper) get(ctx context.Context, key string, receiver interface{}
 		return err
 	})
 
-	return err
+	return meta, err
 }
 
 func (c *cacheWrapper) getMany(ctx context.Context, receiverMap map[string]interface{}, opts ...OperationOption) (err error) {
@@ -177,7 +265,7 @@ func (c *cacheWrapper) getMany(ctx context.Context, receiverMap map[string]inter
 		opt(option)
 	}
 
-	if c.isDisabled(ctx) {
+	if inner.isReadSkipped(ctx) {
 		for k := range receiverMap {
 			handleMissingKey(option.nonExistKeyStrategy, receiverMap, k)
 		}
@@ -199,7 +287,7 @@ func (c *cacheWrapper) getMany(ctx context.Context, receiverMap map[string]inter
 		hostName:       inner.cacheHostName,
 	}
 
-	requestStatsDecorator(ctx, stats, func() error {
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
 		err = c.getManyInner(ctx, keys, fixedKeys, receiverMap, stats, option)
 		return err
 	})
@@ -208,12 +296,12 @@ func (c *cacheWrapper) getMany(ctx context.Context, receiverMap map[string]inter
 }
 
 func (c *cacheWrapper) getManyInner(ctx context.Context, originalKeys []string, fixedKeys []string, receiverMap map[string]interface{}, stats *RequestStats, option *cacheOperationOptions) (err error) {
-	stats.req = fixedKeys
+	stats.req = originalKeys
 
 	inner := c.loadCacheWrapperInner()
 
 	var values []interface{}
-	values, err = inner.cache.getMany(ctx, fixedKeys...)
+	values, err = inner.getManyFromCache(ctx, fixedKeys)
 	if err != nil {
 		return
 	}
@@ -230,20 +318,37 @@ func (c *cacheWrapper) getManyInner(ctx context.Context, originalKeys []string,
 	stats.SuccessKeyCount = successKeyCount
 	stats.ResponseSize = responseSize
 
+	dataList := make([]interface{}, len(values))
+	headers := make([]metaHeader, len(values))
 	for idx := range values {
-		originalKey := originalKeys[idx]
 		if values[idx] == nil {
-			handleMissingKey(option.nonExistKeyStrategy, receiverMap, originalKey)
-		} else {
-			var data interface{}
-			data, _, err = inner.decode(values[idx], option.skipEncodeDecode)
-			if err != nil {
-				return
-			}
+			continue
+		}
+		dataList[idx], headers[idx], err = inner.decode(values[idx], option.skipEncodeDecode)
+		if err != nil {
+			return
+		}
+	}
+	// the data is stale if any of its tags is invalidated, or it is past hard expiration and kept only for stale-if-error,
+	// and the tombstone is treated as stale data as well
+	stale := inner.getStaleByTags(ctx, headers)
+	now := time.Now()
+	for idx := range stale {
+		stale[idx] = stale[idx] || isHardExpired(now, headers[idx]) || headers[idx].Tombstone
+	}
 
-			if err = inner.setCacheDataToReceiver(data, receiverMap[originalKey], option); err != nil {
-				return
+	for idx := range values {
+		originalKey := originalKeys[idx]
+		if values[idx] == nil || stale[idx] {
+			if stale[idx] {
+				stats.SuccessKeyCount--
 			}
+			handleMissingKey(option.nonExistKeyStrategy, receiverMap, originalKey)
+			continue
+		}
+
+		if err = inner.setCacheDataToReceiver(dataList[idx], receiverMap[originalKey], option); err != nil {
+			return
 		}
 	}
 	stats.resp = receiverMap
@@ -287,14 +392,27 @@ func (c *cacheWrapper) loadMany(ctx context.Context, loader DataLoader, receiver
 	if len(receiverMap) == 0 {
 		return nil
 	}
+	if option.staleIfError == 0 {
+		option.staleIfError = inner.manufacturerHandler.staleIfError
+	}
+	if option.negativeCacheTTL == 0 {
+		option.negativeCacheTTL = inner.manufacturerHandler.negativeCacheTTL
+	}
 
 	keys := make([]string, 0, len(receiverMap))
 	for key := range receiverMap {
 		keys = append(keys, key)
 	}
-	missingKeys, toUpdateKeys, successKeyResultMap, getManyErr := getManyForLoad(ctx, inner, keys, receiverMap, inner.codecHandler, *option)
-	if getManyErr != nil {
-		return getManyErr
+	var missingKeys, toUpdateKeys []string
+	var successKeyResultMap map[string]loadResult
+	if inner.isReadSkipped(ctx) {
+		missingKeys = keys
+	} else {
+		var getManyErr error
+		missingKeys, toUpdateKeys, successKeyResultMap, option.staleResultMap, getManyErr = getManyForLoad(ctx, inner, keys, receiverMap, inner.codecHandler, *option)
+		if getManyErr != nil {
+			return getManyErr
+		}
 	}
 
 	if len(successKeyResultMap) > 0 {
@@ -307,7 +425,7 @@ func (c *cacheWrapper) loadMany(ctx context.Context, loader DataLoader, receiver
 		}
 	}
 
-	if len(toUpdateKeys) > 0 {
+	if len(toUpdateKeys) > 0 && !inner.isWriteSkipped(ctx) {
 		//for _, key := range toUpdateKeys {
 		//	fmt.Printf("updateKey:%v\n", key)
 		//}
@@ -330,7 +448,7 @@ func (c *cacheWrapper) loadHandleToUpdateKeys(ctx context.Context, toUpdateKeys
 	// copy current reference of manufacturerHandler
 	// so if users choose to update config for manufacturer policy,
 	// old requests still can proceed with old group lock
-	curManufacturerHandler := inner.manufacturerHandler
+	curManufacturerHandler := inner.manufacturerHandlerForCtx(ctx)
 
 	// waitingInProcessSignalCallsMap will be loaded back to cache by another go-routine/instance, so we can ignore them in this go-routine.
 	toHandleKeys, _ := curManufacturerHandler.add(ctx, toUpdateKeys)
@@ -347,8 +465,11 @@ func (c *cacheWrapper) loadHandleToUpdateKeys(ctx context.Context, toUpdateKeys
 					}
 				}()
 			}
-			loadResultMap := loadHandleKeys(detachCtx, inner, toHandleKeys, receiverMap, loader, expire, curManufacturerHandler, inner.codecHandler, option)
-			curManufacturerHandler.complete(ctx, genToCompleteResultMap(loadResultMap))
+			detachCtx, span := startBackgroundRefreshSpan(detachCtx, inner, toHandleKeys)
+			loadResultMap := curManufacturerHandler.handle(detachCtx, toHandleKeys, func() map[string]loadResult {
+				return loadHandleKeys(detachCtx, inner, toHandleKeys, receiverMap, loader, expire, curManufacturerHandler, inner.codecHandler, option)
+			})
+			span.Finish(getFirstLoadResultErr(loadResultMap))
 		}()
 	}
 }
@@ -359,24 +480,29 @@ func (c *cacheWrapper) loadHandleMissingKeys(ctx context.Context, missingKeys []
 	// copy current reference of manufacturerHandler
 	// so if users choose to update config for manufacturer policy,
 	// old requests still can proceed with old group lock
-	curManufacturerHandler := inner.manufacturerHandler
+	curManufacturerHandler := inner.manufacturerHandlerForCtx(ctx)
 
 	toHandleKeys, waitingInProcessSignalCallsMap := curManufacturerHandler.add(ctx, missingKeys)
 
 	if len(toHandleKeys) > 0 {
-		loadResultMap := loadHandleKeys(ctx, inner, toHandleKeys, receiverMap, loader, expire, curManufacturerHandler, inner.codecHandler, option)
-		curManufacturerHandler.complete(ctx, genToCompleteResultMap(loadResultMap))
+		loadResultMap := curManufacturerHandler.handle(ctx, toHandleKeys, func() map[string]loadResult {
+			return loadHandleKeys(ctx, inner, toHandleKeys, receiverMap, loader, expire, curManufacturerHandler, inner.codecHandler, option)
+		})
 		err := setLoadResultsToReceiverMap(loadResultMap, receiverMap, inner.codecHandler, option)
 		if err != nil {
 			return err
 		}
 	}
 	for key, call := range waitingInProcessSignalCallsMap {
-		val, _ := curManufacturerHandler.wait(call)
+		val, waitErr := curManufacturerHandler.wait(ctx, call)
 		result, ok := val.(loadResult)
 		if !ok {
-			continue
+			if waitErr == nil {
+				continue
+			}
+			result = loadResult{err: waitErr}
 		}
+		result = getStaleResultIfError(key, result, option.staleResultMap)
 		err := setLoadResultToReceiver(key, result, receiverMap, inner.codecHandler, option)
 		if err != nil {
 			return err
@@ -406,8 +532,14 @@ func (c *cacheWrapperInner) encode(val interface{}, option *cacheOperationOption
 		hardTimeoutTs = hardTimeoutForeverIndicator
 	}
 
+	version := option.version
+	if version == 0 {
+		version = genItemVersion()
+	}
+
 	if c.cacheType == inMemory {
-		return inMemoryEncode(val, withSoftTimeoutTs(softTimeoutTs), withHardTimeoutTs(hardTimeoutTs))
+		return inMemoryEncode(val, withSoftTimeoutTs(softTimeoutTs), withHardTimeoutTs(hardTimeoutTs), withTags(option.tags, nil),
+			withVersion(version), withComputeTimeMs(option.computeTimeMs), withTombstone(option.tombstone))
 	}
 
 	b, ok := val.([]byte)
@@ -415,7 +547,8 @@ func (c *cacheWrapperInner) encode(val interface{}, option *cacheOperationOption
 		return nil, cacheErr("data_from_input_is_not_bytes")
 	}
 
-	return c.encodingHandler.encode(b, withSoftTimeoutTs(softTimeoutTs), withHardTimeoutTs(hardTimeoutTs))
+	return c.encodingHandler.encode(b, withSoftTimeoutTs(softTimeoutTs), withHardTimeoutTs(hardTimeoutTs), withTags(option.tags, option.tagVersions),
+		withVersion(version), withComputeTimeMs(option.computeTimeMs), withTombstone(option.tombstone))
 }
 
 func (c *cacheWrapper) set(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) (err error) {
@@ -424,7 +557,7 @@ func (c *cacheWrapper) set(ctx context.Context, key string, value interface{}, e
 	if inner.isCacheClosed() {
 		return ErrCacheClosed
 	}
-	if c.isDisabled(ctx) {
+	if inner.isWriteSkipped(ctx) {
 		return nil
 	}
 
@@ -444,7 +577,7 @@ func (c *cacheWrapper) set(ctx context.Context, key string, value interface{}, e
 		hostName:       inner.cacheHostName,
 	}
 
-	requestStatsDecorator(ctx, stats, func() error {
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
 		stats.req = map[string]interface{}{key: value}
 
 		var data interface{}
@@ -456,13 +589,23 @@ func (c *cacheWrapper) set(ctx context.Context, key string, value interface{}, e
 		expire = inner.translateExpire(ctx, expire)
 		option.hardExpiration = expire
 
+		if err = inner.resolveTagVersions(ctx, option); err != nil {
+			return err
+		}
+
 		var encodedData interface{}
 		encodedData, err = inner.encode(data, option)
 		if err != nil {
 			return err
 		}
 
+		unlock := inner.lockTags(option.tags)
 		err = inner.cache.set(ctx, fixedKey, encodedData, expire, withWaitRistretto(option.waitRistretto))
+		inner.hotKeyHandler.invalidate(fixedKey)
+		if err == nil {
+			inner.indexTags(option.tags, fixedKey)
+		}
+		unlock()
 		stats.RequestSize = len(fixedKey) + inner.getEncodedDataSize(encodedData)
 
 		return err
@@ -477,7 +620,7 @@ func (c *cacheWrapper) setMany(ctx context.Context, valueMap map[string]interfac
 	if inner.isCacheClosed() {
 		return ErrCacheClosed
 	}
-	if c.isDisabled(ctx) {
+	if inner.isWriteSkipped(ctx) {
 		return nil
 	}
 
@@ -499,7 +642,7 @@ func (c *cacheWrapper) setMany(ctx context.Context, valueMap map[string]interfac
 		hostName:       inner.cacheHostName,
 	}
 
-	requestStatsDecorator(ctx, stats, func() error {
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
 		expire = inner.translateExpire(ctx, expire)
 		err = c.setManyInner(ctx, valueMap, expire, stats, option)
 
@@ -523,6 +666,10 @@ func (c *cacheWrapper) setManyInner(ctx context.Context, valueMap map[string]int
 
 	fixedKeys, _ := inner.getFixedKeys(ctx, keys)
 
+	if err = inner.resolveTagVersions(ctx, option); err != nil {
+		return
+	}
+
 	expirationMap := make(map[string]time.Duration)
 	for idx, fixedKey := range fixedKeys {
 		originalKey := keys[idx]
@@ -557,7 +704,17 @@ func (c *cacheWrapper) setManyInner(ctx context.Context, valueMap map[string]int
 
 	stats.RequestSize = requestSize
 
-	return inner.cache.setMany(ctx, newValueMap, expire, withNoReply(option.noReply), withExpirationMap(expirationMap), withWaitRistretto(option.waitRistretto))
+	unlock := inner.lockTags(option.tags)
+	defer unlock()
+
+	err = inner.cache.setMany(ctx, newValueMap, expire, withNoReply(option.noReply), withExpirationMap(expirationMap), withWaitRistretto(option.waitRistretto))
+	for fixedKey := range newValueMap {
+		inner.hotKeyHandler.invalidate(fixedKey)
+	}
+	if err == nil {
+		inner.indexTags(option.tags, fixedKeys...)
+	}
+	return err
 }
 
 // nolint:predeclared
@@ -567,7 +724,7 @@ func (c *cacheWrapper) delete(ctx context.Context, key string, opts ...Operation
 	if inner.isCacheClosed() {
 		return ErrCacheClosed
 	}
-	if c.isDisabled(ctx) {
+	if inner.isWriteSkipped(ctx) {
 		return nil
 	}
 
@@ -588,9 +745,10 @@ func (c *cacheWrapper) delete(ctx context.Context, key string, opts ...Operation
 		hostName:       inner.cacheHostName,
 	}
 
-	requestStatsDecorator(ctx, stats, func() error {
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
 		stats.req = key
 		err = inner.cache.delete(ctx, fixedKey)
+		inner.hotKeyHandler.invalidate(fixedKey)
 
 		return err
 	})
@@ -604,7 +762,7 @@ func (c *cacheWrapper) deleteMany(ctx context.Context, keys []string, opts ...Op
 	if inner.isCacheClosed() {
 		return ErrCacheClosed
 	}
-	if c.isDisabled(ctx) {
+	if inner.isWriteSkipped(ctx) {
 		return nil
 	}
 
@@ -621,15 +779,10 @@ func (c *cacheWrapper) deleteMany(ctx context.Context, keys []string, opts ...Op
 	return c.deleteManyInner(ctx, keys, inner, option)
 }
 
-// deleteManyInner deletes the cache items identified by the sharded keys.
-func (c *cacheWrapper) deleteManyInner(ctx context.Context, shardedKeys []string, inner *cacheWrapperInner, option *cacheOperationOptions) error {
+// deleteManyInner deletes the cache items identified by keys, and fills option.deletedKeys if applied.
+func (c *cacheWrapper) deleteManyInner(ctx context.Context, keys []string, inner *cacheWrapperInner, option *cacheOperationOptions) error {
 	var err error
-	fixedKeys := make([]string, len(shardedKeys))
-	requestSize := 0
-
-	for _, shardedKey := range shardedKeys {
-		requestSize += len(shardedKey)
-	}
+	fixedKeys, requestSize := inner.getFixedKeys(ctx, keys)
 
 	stats := &RequestStats{
 		CacheName:      inner.name,
@@ -640,23 +793,83 @@ func (c *cacheWrapper) deleteManyInner(ctx context.Context, shardedKeys []string
 		hostName:       inner.cacheHostName,
 	}
 
-	requestStatsDecorator(ctx, stats, func() error {
-		stats.req = fixedKeys
-		err = inner.cache.deleteMany(ctx, fixedKeys, withNoReply(option.noReply))
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
+		stats.req = keys
+		if option.deletedKeys == nil {
+			err = inner.cache.deleteMany(ctx, fixedKeys, withNoReply(option.noReply))
+			inner.hotKeyHandler.invalidate(fixedKeys...)
+			return err
+		}
 
-		return err
+		var existed []bool
+		existed, err = inner.cache.deleteManyWithResult(ctx, fixedKeys)
+		inner.hotKeyHandler.invalidate(fixedKeys...)
+		if err != nil {
+			return err
+		}
+		for idx, key := range keys {
+			if existed[idx] {
+				stats.SuccessKeyCount++
+				option.deletedKeys[key] = true
+			} else if _, ok := option.deletedKeys[key]; !ok {
+				option.deletedKeys[key] = false
+			}
+		}
+		stats.resp = option.deletedKeys
+
+		return nil
 	})
 
 	return err
 }
 
+// deleteMatching deletes the keys satisfying match from the in-memory cache by its key index, and returns the number of deleted keys.
+// Keys are matched without the prefix of KeyConfig, and pattern is only used for stats.
+func (c *cacheWrapper) deleteMatching(ctx context.Context, command string, pattern string, match func(key string) bool) (count int, err error) {
+	inner := c.loadCacheWrapperInner()
+
+	if inner.isCacheClosed() {
+		return 0, ErrCacheClosed
+	}
+	if inner.isWriteSkipped(ctx) {
+		return 0, nil
+	}
+
+	inMemCache, ok := inner.cache.(*inMemoryCacheInner)
+	if !ok {
+		return 0, errorConfigTypeNotSupported
+	}
+
+	stats := &RequestStats{
+		CacheName:      inner.name,
+		CacheType:      inner.cacheType.String(),
+		CacheOperation: command,
+		RequestSize:    len(pattern),
+		hostName:       inner.cacheHostName,
+	}
+
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
+		stats.req = pattern
+		count, err = inMemCache.deleteMatching(ctx, func(fixedKey string) bool {
+			key, ok := inner.keyHandler.trimPrefix(fixedKey)
+			return ok && match(key)
+		})
+		stats.TotalKeyCount = count
+		stats.SuccessKeyCount = count
+
+		return err
+	})
+
+	return count, err
+}
+
 func (c *cacheWrapper) add(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) (err error) {
 	inner := c.loadCacheWrapperInner()
 	if inner.isCacheClosed() {
 		return ErrCacheClosed
 	}
-	if c.isDisabled(ctx) {
-		return nil
+	if inner.isWriteSkipped(ctx) {
+		return ErrNotStored
 	}
 	return c.addOrReplaceInner(ctx, key, value, expire, cmdAdd, opts)
 }
@@ -667,7 +880,7 @@ func (c *cacheWrapper) replace(ctx context.Context, key string, value interface{
 	if inner.isCacheClosed() {
 		return ErrCacheClosed
 	}
-	if c.isDisabled(ctx) {
+	if inner.isWriteSkipped(ctx) {
 		return ErrNotStored
 	}
 	return c.addOrReplaceInner(ctx, key, value, expire, cmdReplace, opts)
@@ -691,7 +904,7 @@ func (c *cacheWrapper) addOrReplaceInner(ctx context.Context, key string, value
 		hostName:       inner.cacheHostName,
 	}
 
-	requestStatsDecorator(ctx, stats, func() error {
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
 		stats.req = map[string]interface{}{key: value}
 
 		var data interface{}
@@ -718,10 +931,15 @@ func (c *cacheWrapper) addOrReplaceInner(ctx context.Context, key string, value
 			err = inner.cache.replace(ctx, fixedKey, encodedData, expire)
 		case cmdAdd:
 			err = inner.cache.add(ctx, fixedKey, encodedData, expire)
+			if err == ErrNotStored && !option.skipEncodeDecode && inner.isTombstoneSupported() {
+				// the tombstone or the data past hard expiration is regarded as absent, thus can be overwritten
+				err = inner.cache.compareAndSwap(ctx, fixedKey, inner.isAbsentData, encodedData, expire)
+			}
 		default:
 			// Logic error
 			panic(cacheErr("unknown_operation"))
 		}
+		inner.hotKeyHandler.invalidate(fixedKey)
 
 		return err
 	})
@@ -743,7 +961,7 @@ func (c *cacheWrapper) incrDecrInner(ctx context.Context, key string, delta uint
 	if inner.isCacheClosed() {
 		return 0, ErrCacheClosed
 	}
-	if c.isDisabled(ctx) {
+	if inner.isWriteSkipped(ctx) {
 		return 0, ErrCacheMiss
 	}
 
@@ -763,7 +981,7 @@ func (c *cacheWrapper) incrDecrInner(ctx context.Context, key string, delta uint
 		hostName:       inner.cacheHostName,
 	}
 
-	requestStatsDecorator(ctx, stats, func() error {
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
 		stats.req = map[string]interface{}{key: delta}
 
 		switch command {
@@ -775,6 +993,7 @@ func (c *cacheWrapper) incrDecrInner(ctx context.Context, key string, delta uint
 			// Logic error
 			panic(cacheErr("unknown_operation"))
 		}
+		inner.hotKeyHandler.invalidate(fixedKey)
 
 		return err
 	})
@@ -788,7 +1007,7 @@ func (c *cacheWrapper) expire(ctx context.Context, key string, expire time.Durat
 	if inner.isCacheClosed() {
 		return ErrCacheClosed
 	}
-	if c.isDisabled(ctx) {
+	if inner.isWriteSkipped(ctx) {
 		return ErrCacheMiss
 	}
 
@@ -798,19 +1017,21 @@ func (c *cacheWrapper) expire(ctx context.Context, key string, expire time.Durat
 		opt(option)
 	}
 
+	fixedKey := inner.getFixedKey(ctx, key)
+
 	stats := &RequestStats{
 		CacheName:      inner.name,
 		CacheType:      inner.cacheType.String(),
 		CacheOperation: cmdExpire,
-		RequestSize:    len(key),
+		RequestSize:    len(fixedKey),
 		hostName:       inner.cacheHostName,
 	}
 
-	requestStatsDecorator(ctx, stats, func() error {
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
 		expire = inner.translateExpire(ctx, expire)
 		stats.req = map[string]interface{}{key: expire}
 
-		err = c.expireInner(ctx, key, expire, inner, option)
+		err = c.expireInner(ctx, fixedKey, expire, inner, option)
 		return err
 	})
 	return err
@@ -820,32 +1041,50 @@ func (c *cacheWrapper) expireInner(ctx context.Context, fixedKey string, expire
 	if option.skipEncodeDecode {
 		err = inner.cache.expire(ctx, fixedKey, expire)
 	} else {
-		var result interface{}
-		result, err = inner.cache.get(ctx, fixedKey)
-		if err != nil {
-			return err
+		for attempt := 0; attempt < expireMaxAttempts; attempt++ {
+			if err = inner.expireOnce(ctx, fixedKey, expire, option); err != ErrNotStored {
+				break
+			}
 		}
+	}
+	inner.hotKeyHandler.invalidate(fixedKey)
 
-		var decodedData interface{}
-		var header metaHeader
-		decodedData, header, err = inner.decode(result, false)
-		if err != nil {
-			return err
-		}
+	return err
+}
 
-		option.hardExpiration = expire
-		option.softTimeoutTs = header.SoftTimeoutTs
+// expireOnce re-encodes the cached data with expire, the data is only updated if it is not modified since read.
+// Returns ErrNotStored if the data is modified concurrently.
+func (c *cacheWrapperInner) expireOnce(ctx context.Context, fixedKey string, expire time.Duration, option *cacheOperationOptions) error {
+	result, err := c.cache.get(ctx, fixedKey)
+	if err != nil {
+		return err
+	}
 
-		var encodedData interface{}
-		encodedData, err = inner.encode(decodedData, option)
-		if err != nil {
-			return err
-		}
+	decodedData, header, err := c.decode(result, false)
+	if err != nil {
+		return err
+	}
+	if header.Tombstone || isHardExpired(time.Now(), header) {
+		return ErrCacheMiss
+	}
+
+	option.hardExpiration = expire
+	option.softTimeoutTs = header.SoftTimeoutTs
+	option.tags = header.Tags
+	option.tagVersions = header.TagVersions
+	option.version = header.Version
+	option.computeTimeMs = header.ComputeTimeMs
 
-		err = inner.cache.set(ctx, fixedKey, encodedData, expire)
+	encodedData, err := c.encode(decodedData, option)
+	if err != nil {
+		return err
 	}
 
-	return err
+	if header.Version == 0 {
+		// the data without version can only be updated unconditionally
+		return c.cache.set(ctx, fixedKey, encodedData, expire)
+	}
+	return c.cache.compareAndSwap(ctx, fixedKey, c.genVersionMatcher(header.Version), encodedData, expire)
 }
 
 func (c *cacheWrapper) flush(ctx context.Context) (err error) {
@@ -854,7 +1093,7 @@ func (c *cacheWrapper) flush(ctx context.Context) (err error) {
 	if inner.isCacheClosed() {
 		return ErrCacheClosed
 	}
-	if c.isDisabled(ctx) {
+	if inner.isWriteSkipped(ctx) {
 		return nil
 	}
 
@@ -865,8 +1104,9 @@ func (c *cacheWrapper) flush(ctx context.Context) (err error) {
 		hostName:       inner.cacheHostName,
 	}
 
-	requestStatsDecorator(ctx, stats, func() error {
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
 		err = inner.cache.flush(ctx)
+		inner.hotKeyHandler.invalidateAll()
 
 		return err
 	})
@@ -891,7 +1131,7 @@ func (c *cacheWrapper) ping(ctx context.Context) (err error) {
 		hostName:       inner.cacheHostName,
 	}
 
-	requestStatsDecorator(ctx, stats, func() error {
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
 		err = inner.cache.ping(ctx)
 
 		return err
@@ -933,12 +1173,24 @@ func (c *cacheWrapper) updateConfig(config Config) error {
 	var err error
 
 	switch config.Type {
+	case Redis:
+		redisConfig := config.Redis
+		err = updateRedisCacheWrapperInner(newInner, redisConfig)
+		if err != nil {
+			return err
+		}
 	case InMemory:
 		inMemConfig := config.InMemory
 		err = updateInMemCacheWrapperInner(newInner, inMemConfig)
 		if err != nil {
 			return err
 		}
+	case Memcached:
+		memcachedConfig := config.Memcached
+		err = updateMemcachedCacheWrapperInner(newInner, memcachedConfig)
+		if err != nil {
+			return err
+		}
 	default:
 		return errorConfigTypeNotSupported
 	}
@@ -947,14 +1199,36 @@ func (c *cacheWrapper) updateConfig(config Config) error {
 	return nil
 }
 
-func updateInMemCacheWrapperInner(inner *cacheWrapperInner, config InMemoryCacheConfig) error {
-	if err := inner.cache.(*inMemoryCacheInner).updateConfig(config); err != nil {
+func updateRedisCacheWrapperInner(inner *cacheWrapperInner, config RedisConfig) error {
+	redisCache, ok := inner.cache.(*redisCacheInner)
+	if !ok {
+		return errorConfigTypeNotSupported
+	}
+	if err := fillCacheWrapperInnerFieldsWithRedisConfig(config, inner); err != nil {
 		return err
 	}
 
-	fillCacheWrapperInnerFieldsWithInMemConfig(config, inner)
+	return redisCache.updateConfig(config)
+}
 
-	return nil
+func updateMemcachedCacheWrapperInner(inner *cacheWrapperInner, config MemcachedConfig) error {
+	memcachedCache, ok := inner.cache.(*memcachedCacheInner)
+	if !ok {
+		return errorConfigTypeNotSupported
+	}
+	if err := fillCacheWrapperInnerFieldsWithMemcachedConfig(config, inner); err != nil {
+		return err
+	}
+
+	return memcachedCache.updateConfig(config)
+}
+
+func updateInMemCacheWrapperInner(inner *cacheWrapperInner, config InMemoryCacheConfig) error {
+	if err := fillCacheWrapperInnerFieldsWithInMemConfig(config, inner); err != nil {
+		return err
+	}
+
+	return inner.cache.(*inMemoryCacheInner).updateConfig(config)
 }
 
 func (c *cacheWrapperInner) getFixedKey(ctx context.Context, key string) string {
@@ -962,14 +1236,13 @@ func (c *cacheWrapperInner) getFixedKey(ctx context.Context, key string) string
 	return fixedKeys[0]
 }
 
-// getFixedKeys returns the fixed-to-originalKeys map, slice of fixed keys, total length of all keys
+// getFixedKeys returns the slice of fixed keys transformed by keyHandler in the order of keys, total length of all fixed keys
 func (c *cacheWrapperInner) getFixedKeys(ctx context.Context, keys []string) (fixedKeys []string, totalKeysLength int) {
 	newKeys := make([]string, len(keys))
 	totalNewKeysLength := 0
 
 	for i := range keys {
-		//TODO  可以对key进行额外处理
-		newKey := keys[i]
+		newKey := c.keyHandler.fixKey(keys[i])
 		newKeys[i] = newKey
 		totalNewKeysLength += len(newKey)
 	}
@@ -986,9 +1259,14 @@ func genCacheWrapperInnerCopy(oldInner *cacheWrapperInner) *cacheWrapperInner {
 	newInner.cacheHostName = oldInner.cacheHostName
 	newInner.defaultExpiration = oldInner.defaultExpiration
 	newInner.maxExpiration = oldInner.maxExpiration
+	newInner.keyHandler = oldInner.keyHandler
 	newInner.codecHandler = oldInner.codecHandler
 	newInner.encodingHandler = oldInner.encodingHandler
 	newInner.manufacturerHandler = oldInner.manufacturerHandler
+	newInner.observationConfig = oldInner.observationConfig
+	newInner.isDisabled = oldInner.isDisabled
+	newInner.hotKeyHandler = oldInner.hotKeyHandler
+	newInner.hotKeyConfig = oldInner.hotKeyConfig
 
 	return newInner
 }
@@ -1016,7 +1294,7 @@ func (c *cacheWrapperInner) setCacheDataToReceiver(data interface{}, receiver in
 	}
 
 	if c.cacheType == inMemory && option.skipCodec {
-		return utils.SetValue(data, receiver)
+		return setSkipCodecData(data, receiver)
 	}
 
 	b, ok := data.([]byte)
@@ -1030,7 +1308,7 @@ func (c *cacheWrapperInner) setCacheDataToReceiver(data interface{}, receiver in
 // convertValueToCacheData converts value to to-cache data
 func (c *cacheWrapperInner) convertValueToCacheData(value interface{}, option *cacheOperationOptions) (data interface{}, err error) {
 	if c.cacheType == inMemory && option.skipCodec {
-		return utils.GetValue(value), nil
+		return getSkipCodecData(value), nil
 	}
 
 	bytesData, err := c.codecHandler.marshal(value, option.codecType, option.customCodec)
@@ -1071,7 +1349,27 @@ func (c *cacheWrapperInner) isDisabledCtxOrConfig(ctx context.Context) bool {
 		return true
 	}
 
-	return false
+	return isCacheBypassCtx(ctx)
+}
+
+// manufacturerHandlerForCtx returns the manufacturerHandler applied to the operation with ctx
+func (c *cacheWrapperInner) manufacturerHandlerForCtx(ctx context.Context) manufacturerHandler {
+	h := c.manufacturerHandler.forCtx(ctx)
+	if c.isDisabled && h.strategy == AcrossInstanceSignal {
+		// dlocks can not be acquired from the disabled cache, thus keys are only synchronized within the process
+		h.strategy = InProcessSignal
+	}
+	return h
+}
+
+// isReadSkipped returns if the cached data should not be read, i.e. the cache is disabled or the data is to be refreshed
+func (c *cacheWrapperInner) isReadSkipped(ctx context.Context) bool {
+	return c.isDisabledCtxOrConfig(ctx) || isForceRefreshCtx(ctx)
+}
+
+// isWriteSkipped returns if the cache should not be modified, i.e. the cache is disabled or read-only
+func (c *cacheWrapperInner) isWriteSkipped(ctx context.Context) bool {
+	return c.isDisabledCtxOrConfig(ctx) || isReadOnlyCtx(ctx)
 }
 
 func (c *cacheWrapper) isDisabled(ctx context.Context) bool {
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// expireMaxAttempts is the max number of attempts of Expire, which is retried if the key is modified concurrently
+const expireMaxAttempts = 3
+
+// itemVersionSeq is the sequence generating the versions of data.
+// It starts from a random number, so that the versions generated by different instances hardly collide.
+var itemVersionSeq = initItemVersionSeq()
+
+func initItemVersionSeq() uint64 {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return uint64(time.Now().UnixNano())
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// genItemVersion generates a positive version for the data to be written
+func genItemVersion() int64 {
+	for {
+		if version := int64(atomic.AddUint64(&itemVersionSeq, 1) & math.MaxInt64); version != 0 {
+			return version
+		}
+	}
+}
+
+func (c *cacheWrapper) compareAndSwap(ctx context.Context, key string, value interface{}, version int64, expire time.Duration, opts ...OperationOption) (err error) {
+	inner := c.loadCacheWrapperInner()
+
+	if inner.isCacheClosed() {
+		return ErrCacheClosed
+	}
+	if inner.isWriteSkipped(ctx) {
+		return ErrNotStored
+	}
+
+	option := newCacheOperationOptions()
+	defer recycleCacheOperationOptions(option)
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	if option.skipEncodeDecode || (inner.encodingHandler.disableEncoding && inner.cacheType != inMemory) {
+		return errItemVersionNotSupported
+	}
+
+	fixedKey := inner.getFixedKey(ctx, key)
+
+	stats := &RequestStats{
+		CacheName:      inner.name,
+		CacheType:      inner.cacheType.String(),
+		CacheOperation: cmdCompareAndSwap,
+		TotalKeyCount:  1,
+		hostName:       inner.cacheHostName,
+	}
+
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
+		stats.req = map[string]interface{}{key: value}
+
+		var data interface{}
+		data, err = inner.convertValueToCacheData(value, option)
+		if err != nil {
+			return err
+		}
+
+		expire = inner.translateExpire(ctx, expire)
+		option.hardExpiration = expire
+
+		if err = inner.resolveTagVersions(ctx, option); err != nil {
+			return err
+		}
+
+		var encodedData interface{}
+		encodedData, err = inner.encode(data, option)
+		if err != nil {
+			return err
+		}
+
+		unlock := inner.lockTags(option.tags)
+		err = inner.cache.compareAndSwap(ctx, fixedKey, inner.genVersionMatcher(version), encodedData, expire)
+		inner.hotKeyHandler.invalidate(fixedKey)
+		if err == nil {
+			inner.indexTags(option.tags, fixedKey)
+		}
+		unlock()
+		stats.RequestSize = len(fixedKey) + inner.getEncodedDataSize(encodedData)
+
+		return err
+	})
+
+	return err
+}
+
+// genVersionMatcher returns a function matching the cached value with version, the data without version never matches
+func (c *cacheWrapperInner) genVersionMatcher(version int64) func(value interface{}) bool {
+	return func(value interface{}) bool {
+		_, header, err := c.decode(value, false)
+		return err == nil && header.Version != 0 && header.Version == version && !isHardExpired(time.Now(), header)
+	}
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func testCacheCompareAndSwap(t *testing.T, c Cache, opts ...OperationOption) {
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "key", "v1", time.Minute, opts...); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	var val string
+	version, err := c.GetWithVersion(ctx, "key", &val)
+	if err != nil || val != "v1" || version == 0 {
+		t.Fatalf("expect v1 with version, got: %v, version: %v, err: %v", val, version, err)
+	}
+
+	if err = c.CompareAndSwap(ctx, "key", "v2", version, time.Minute, opts...); err != nil {
+		t.Fatalf("compare and swap err: %v", err)
+	}
+	if err = c.CompareAndSwap(ctx, "key", "v3", version, time.Minute, opts...); err != ErrNotStored {
+		t.Fatalf("expect not stored with outdated version, got: %v", err)
+	}
+	newVersion, err := c.GetWithVersion(ctx, "key", &val)
+	if err != nil || val != "v2" || newVersion == version {
+		t.Fatalf("expect v2 with new version, got: %v, version: %v, err: %v", val, newVersion, err)
+	}
+
+	// the version is kept by Expire, while changed by other writes
+	if err = c.Expire(ctx, "key", 2*time.Minute, opts...); err != nil {
+		t.Fatalf("expire err: %v", err)
+	}
+	if version, err = c.GetWithVersion(ctx, "key", &val); err != nil || version != newVersion {
+		t.Fatalf("expect version kept by expire, got: %v, err: %v", version, err)
+	}
+	if err = c.Set(ctx, "key", "v4", time.Minute, opts...); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	if err = c.CompareAndSwap(ctx, "key", "v5", version, time.Minute, opts...); err != ErrNotStored {
+		t.Fatalf("expect not stored after overwritten, got: %v", err)
+	}
+
+	if err = c.CompareAndSwap(ctx, "not_exist", "v", version, time.Minute, opts...); err != ErrNotStored {
+		t.Fatalf("expect not stored for non-exist key, got: %v", err)
+	}
+}
+
+func TestRedisCacheCompareAndSwap(t *testing.T) {
+	testCacheCompareAndSwap(t, newTestRedisCache(t))
+}
+
+func TestMemcachedCacheCompareAndSwap(t *testing.T) {
+	c, _ := newTestMemcachedCache(t)
+	testCacheCompareAndSwap(t, c)
+}
+
+func TestInMemoryCacheCompareAndSwap(t *testing.T) {
+	testCacheCompareAndSwap(t, newTestInMemoryCache(t), WithWaitRistretto())
+}
+
+func TestMultiLayerCacheCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	c, local, _ := newTestMultiLayerCache(t)
+	testCacheCompareAndSwap(t, c, WithWaitRistretto())
+
+	// the outer layers are overwritten once the last layer is swapped
+	var val string
+	version, err := c.GetWithVersion(ctx, "key", &val)
+	if err != nil {
+		t.Fatalf("get with version err: %v", err)
+	}
+	if err = c.CompareAndSwap(ctx, "key", "v6", version, time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("compare and swap err: %v", err)
+	}
+	if err = local.Get(ctx, "key", &val); err != nil || val != "v6" {
+		t.Fatalf("expect v6 in the outer layer, got: %v, err: %v", val, err)
+	}
+}
+
+func TestCompareAndSwapEncodingDisabled(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{Address: server.addr(), EncodingConfig: EncodingConfig{DisableEncoding: true}})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	if err = c.CompareAndSwap(ctx, "key", "v", 1, time.Minute); err != errItemVersionNotSupported {
+		t.Fatalf("expect item version not supported, got: %v", err)
+	}
+}
@@ -12,6 +12,12 @@ const (
 
 	// MultiLayer is cache type multilayer
 	MultiLayer Type = 3
+
+	// Memcached is cache type memcached
+	Memcached Type = 4
+
+	// Migration is cache type migration
+	Migration Type = 5
 )
 
 // Config defines the configuration for a cache. It is used when initializing or updating cache through manager.
@@ -20,21 +26,27 @@ const (
 type Config struct {
 	Type Type `yaml:"type" json:"type"`
 
-	//Redis    RedisConfig         `yaml:"redis" json:"redis"`
-	InMemory InMemoryCacheConfig `yaml:"in_memory" json:"in_memory"`
+	Redis     RedisConfig         `yaml:"redis" json:"redis"`
+	InMemory  InMemoryCacheConfig `yaml:"in_memory" json:"in_memory"`
+	Memcached MemcachedConfig     `yaml:"memcached" json:"memcached"`
 
-	//MultiLayer MultiLayerConfig `yaml:"multilayer" json:"multilayer"`
+	MultiLayer MultiLayerConfig `yaml:"multilayer" json:"multilayer"`
+	Migration  MigrationConfig  `yaml:"migration" json:"migration"`
 }
 
 // Validate checks if this Config is valid.
 func (c Config) Validate() error {
 	switch c.Type {
-	//case Redis:
-	//	return c.Redis.Validate()
+	case Redis:
+		return c.Redis.Validate()
 	case InMemory:
 		return c.InMemory.Validate()
-	//case MultiLayer:
-	//	return c.MultiLayer.Validate()
+	case Memcached:
+		return c.Memcached.Validate()
+	case MultiLayer:
+		return c.MultiLayer.Validate()
+	case Migration:
+		return c.Migration.Validate()
 	default:
 		return errorConfigTypeNotSupported
 	}
@@ -43,12 +55,16 @@ func (c Config) Validate() error {
 // RawConfig returns the raw Config of the specific cache type e.g. RedisConfig.
 func (c Config) RawConfig() (interface{}, error) {
 	switch c.Type {
-	//case Redis:
-	//	return c.Redis, nil
+	case Redis:
+		return c.Redis, nil
 	case InMemory:
 		return c.InMemory, nil
-	//case MultiLayer:
-	//	return c.MultiLayer, nil
+	case Memcached:
+		return c.Memcached, nil
+	case MultiLayer:
+		return c.MultiLayer, nil
+	case Migration:
+		return c.Migration, nil
 	default:
 		return nil, errorConfigTypeNotSupported
 	}
@@ -56,7 +72,7 @@ func (c Config) RawConfig() (interface{}, error) {
 
 func (c Config) isSingle() bool {
 	switch c.Type {
-	case Redis, InMemory:
+	case Redis, InMemory, Memcached:
 		return true
 	default:
 		return false
@@ -65,7 +81,7 @@ func (c Config) isSingle() bool {
 
 func (c Config) isComposite() bool {
 	switch c.Type {
-	case MultiLayer:
+	case MultiLayer, Migration:
 		return true
 	default:
 		return false
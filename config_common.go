@@ -2,6 +2,8 @@ package cache
 
 import (
 	"fmt"
+	"strconv"
+
 	"go-eCache/internal/compression"
 )
 
@@ -11,6 +13,18 @@ const (
 
 	// defaultElapseThresholdForSlowLogMillis will apply when the observation config's ElapseThresholdMillis is 0
 	defaultElapseThresholdForSlowLogMillis = 100
+
+	// defaultMaxPayloadBytes will apply when the trace payload config's MaxPayloadBytes is 0
+	defaultMaxPayloadBytes = 512
+
+	// defaultHotKeyTopN will apply when the hot key config's TopN is 0
+	defaultHotKeyTopN = 10
+	// defaultHotKeyMinFrequency will apply when the hot key config's MinFrequency is 0
+	defaultHotKeyMinFrequency = 100
+	// defaultHotKeyWindowSecs will apply when the hot key config's WindowSecs is 0
+	defaultHotKeyWindowSecs = 10
+	// defaultHotKeyLocalCacheTTLMillis will apply when the hot key config's LocalCacheTTLMillis is 0
+	defaultHotKeyLocalCacheTTLMillis = 1000
 )
 
 const (
@@ -53,7 +67,169 @@ func (c EncodingConfig) Validate() error {
 	return nil
 }
 
-// DisableConfig defines whether current cache is disabled
+// ObservationConfig defines how the operations of a cache are observed
+type ObservationConfig struct {
+	// StatsCollectors are invoked in order with the RequestStats of each finished cache operation.
+	// Collectors are called synchronously, so they should be fast and must not block.
+	StatsCollectors []StatsCollector `yaml:"-" json:"-"`
+
+	// EnablePrometheusMetrics reports the stats to the built-in Prometheus collector, which is exposed by PrometheusHandler.
+	// Default value is false.
+	EnablePrometheusMetrics bool `yaml:"enable_prometheus_metrics" json:"enable_prometheus_metrics"`
+
+	// Logger is used to write slow operation logs and warnings e.g. dlock loss.
+	// Default to no logging, nothing is logged (slow operation logs included) unless Logger is set.
+	Logger Logger `yaml:"-" json:"-"`
+
+	// SlowOperationLogConfig defines the behavior of slow operation logs
+	SlowOperationLogConfig SlowOperationLogConfig `yaml:"slow_operation_log_config" json:"slow_operation_log_config"`
+
+	// Tracer is used to start spans for cache operations, DataLoader calls, dlock acquisition and background refresh.
+	// Default to no tracing, refer to TracerAdapter for backing it by tracing APIs e.g. OpenTelemetry.
+	Tracer Tracer `yaml:"-" json:"-"`
+
+	// TracePayloadConfig defines whether the request and response of operations are captured into spans
+	TracePayloadConfig TracePayloadConfig `yaml:"trace_payload_config" json:"trace_payload_config"`
+}
+
+// Validate checks if config is valid
+func (c ObservationConfig) Validate() error {
+	if c.SlowOperationLogConfig.ElapseThresholdMillis < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_elapse_threshold_millis: %v", c.SlowOperationLogConfig.ElapseThresholdMillis))
+	}
+	if c.TracePayloadConfig.MaxPayloadBytes < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_max_payload_bytes: %v", c.TracePayloadConfig.MaxPayloadBytes))
+	}
+	return nil
+}
+
+// SlowOperationLogConfig defines the config of slow operation logs,
+// which contains the keys, the request and response sizes and the error of the operation.
+type SlowOperationLogConfig struct {
+	// Disable disables slow operation logs. Default value is false.
+	Disable bool `yaml:"disable" json:"disable"`
+
+	// ElapseThresholdMillis is the minimum elapsed time for an operation to be logged as slow.
+	// Default value is 100 ms.
+	ElapseThresholdMillis int64 `yaml:"elapse_threshold_millis" json:"elapse_threshold_millis"`
+}
+
+// TracePayloadConfig defines the capture of the request and response of operations into spans.
+// Payloads may contain sensitive data, so the capture is opt-in.
+type TracePayloadConfig struct {
+	// Enable enables the payload capture. Default value is false.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// MaxPayloadBytes is the max bytes of each captured payload, longer payloads are truncated.
+	// Default value is 512.
+	MaxPayloadBytes int `yaml:"max_payload_bytes" json:"max_payload_bytes"`
+}
+
+// HotKeyConfig defines the detection of hot keys, i.e. the most frequently read keys of a cache.
+//
+// For remote caches (i.e. Redis and Memcached), detected hot keys are also served from local memory with a short TTL,
+// which reduces the load of the hottest keys on the remote cache. Local values are invalidated by the writes through
+// this cache instance, but the writes through other instances are only visible after LocalCacheTTLMillis.
+type HotKeyConfig struct {
+	// Enable enables hot key detection. Default value is false.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// Patterns are the regex patterns of the keys to detect, keys matching none of them are ignored.
+	// Default value is empty, means all keys are detected.
+	Patterns []string `yaml:"patterns" json:"patterns"`
+
+	// TopN is the max number of hot keys. Default value is 10.
+	TopN int `yaml:"top_n" json:"top_n"`
+
+	// MinFrequency is the minimum estimated read count for a key to be hot. Default value is 100.
+	MinFrequency int `yaml:"min_frequency" json:"min_frequency"`
+
+	// WindowSecs is the period to halve the read counts, so that keys no longer read cool down. Default value is 10.
+	WindowSecs int `yaml:"window_secs" json:"window_secs"`
+
+	// DisableLocalCache disables serving hot keys of remote caches from local memory. Default value is false.
+	DisableLocalCache bool `yaml:"disable_local_cache" json:"disable_local_cache"`
+
+	// LocalCacheTTLMillis is the TTL of the hot keys served from local memory. Default value is 1000 ms.
+	LocalCacheTTLMillis int64 `yaml:"local_cache_ttl_millis" json:"local_cache_ttl_millis"`
+}
+
+// Validate checks if config is valid
+func (c HotKeyConfig) Validate() error {
+	if c.TopN < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_top_n: %v", c.TopN))
+	}
+	if c.MinFrequency < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_min_frequency: %v", c.MinFrequency))
+	}
+	if c.WindowSecs < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_window_secs: %v", c.WindowSecs))
+	}
+	if c.LocalCacheTTLMillis < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_local_cache_ttl_millis: %v", c.LocalCacheTTLMillis))
+	}
+	if _, err := compileHotKeyPatterns(c.Patterns); err != nil {
+		return err
+	}
+	return nil
+}
+
+// KeyConfig defines how the keys of cache operations are transformed before being sent to the cache.
+// The transformation is transparent to users, i.e. the original keys are used in receiver maps and stats.
+type KeyConfig struct {
+	// Namespace is prepended to keys in the format "<Namespace>:<key>", so that caches can share a backend.
+	// Default value is empty, means no namespace.
+	Namespace string `yaml:"namespace" json:"namespace"`
+
+	// Version is the schema version of keys, prepended to keys in the format "v<Version>:<key>" after the namespace.
+	// Bumping it by UpdateConfig invalidates all the cached data logically, the data of old versions expire by themselves.
+	// Default value is 0, means no version segment.
+	Version int `yaml:"version" json:"version"`
+
+	// MaxKeyLength is the max length of keys sent to the cache, longer keys are replaced by their SHA-256 hash after the prefix.
+	// Default value is 250 for Memcached (i.e. the key length limit of memcached), and 0 for others, means no limit.
+	MaxKeyLength int `yaml:"max_key_length" json:"max_key_length"`
+}
+
+// Validate checks if config is valid
+func (c KeyConfig) Validate() error {
+	if c.Version < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_version: %v", c.Version))
+	}
+	if c.MaxKeyLength < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_max_key_length: %v", c.MaxKeyLength))
+	}
+	if c.MaxKeyLength > 0 && c.MaxKeyLength < len(c.keyPrefix())+hashedKeyLength {
+		// the hashed keys must not exceed the limit
+		return cacheErr(fmt.Sprintf("invalid_config_max_key_length_too_small: %v", c.MaxKeyLength))
+	}
+	return nil
+}
+
+// keyPrefix returns the prefix of the namespace and version segments
+func (c KeyConfig) keyPrefix() string {
+	var prefix string
+	if c.Namespace != "" {
+		prefix += c.Namespace + ":"
+	}
+	if c.Version != 0 {
+		prefix += "v" + strconv.Itoa(c.Version) + ":"
+	}
+	return prefix
+}
+
+// DisableConfig defines whether current cache is disabled.
+// A cache can be disabled or enabled at runtime by UpdateConfig, e.g. to switch off a misbehaving backend during an incident.
+//
+// The operations of a disabled cache never access the backend:
+//   - Get, GetWithMeta, GetWithVersion, TTL, Increment, Decrement and Expire return ErrCacheMiss
+//   - GetMany treats all keys as missing and returns nil
+//   - Set, SetMany, Delete, DeleteMany, InvalidateTags and Flush are skipped and return nil
+//   - Add, Replace and CompareAndSwap return ErrNotStored
+//   - Load and LoadMany call the DataLoader, the loaded data are returned but not cached,
+//     and AcrossInstanceSignal falls back to InProcessSignal since no dlock can be acquired
+//   - Ping returns nil
+//   - InMemoryCache.DeleteByPrefix and DeleteMatching delete nothing and return 0
 type DisableConfig struct {
 	// Disable is used to disable current cache
 	Disable bool `yaml:"disable" json:"disable"`
@@ -67,6 +243,34 @@ type ManufacturerConfig struct {
 
 	// AcrossInstanceSignalConfig is the extra config for the strategy `AcrossInstanceSignal` of the `CacheStampedeMitigation`
 	AcrossInstanceSignalConfig AcrossInstanceSignalConfig `yaml:"across_instance_signal_config" json:"across_instance_signal_config"`
+
+	// StaleIfErrorMillis is the default grace window of stale-if-error for Load(Many), refer to WithStaleIfError.
+	// Default as 0, which disables stale-if-error unless WithStaleIfError is applied.
+	StaleIfErrorMillis int64 `yaml:"stale_if_error_millis" json:"stale_if_error_millis"`
+
+	// NegativeCacheConfig defines the default caching of the keys that DataLoader returns nil for, refer to WithNegativeCaching
+	NegativeCacheConfig NegativeCacheConfig `yaml:"negative_cache_config" json:"negative_cache_config"`
+
+	// BatchConfig defines how the concurrent DataLoader calls are batched, refer to WithBatching
+	BatchConfig BatchConfig `yaml:"batch_config" json:"batch_config"`
+}
+
+// BatchConfig defines how the concurrent DataLoader calls of Load(Many) with the same batch name are batched
+type BatchConfig struct {
+	// WindowMillis is the time collecting the keys of concurrent calls into a batch, starting from the first call of the batch.
+	// Default as 1 ms.
+	WindowMillis int64 `yaml:"window_millis" json:"window_millis"`
+
+	// MaxBatchSize is the max number of keys of a batch, the batch is dispatched once it is reached regardless of WindowMillis.
+	// Default as 100.
+	MaxBatchSize int `yaml:"max_batch_size" json:"max_batch_size"`
+}
+
+// NegativeCacheConfig defines how the non-existence of keys is cached by Load(Many)
+type NegativeCacheConfig struct {
+	// TTLMillis is the default expiration of the tombstone cached for a key that DataLoader returns nil for,
+	// which is rounded up to whole seconds. Default as 0, which disables negative caching unless WithNegativeCaching is applied.
+	TTLMillis int64 `yaml:"ttl_millis" json:"ttl_millis"`
 }
 
 type AcrossInstanceSignalConfig struct {
@@ -92,6 +296,15 @@ func (c *ManufacturerConfig) Validate(cacheType Type) error {
 			c.CacheStampedeMitigation != AcrossInstanceSignal {
 			return cacheErr(fmt.Sprintf("manufacturer_config_strategy_invalid: %v", c.CacheStampedeMitigation))
 		}
+		if c.StaleIfErrorMillis < 0 {
+			return cacheErr(fmt.Sprintf("manufacturer_config_stale_if_error_invalid: %v", c.StaleIfErrorMillis))
+		}
+		if c.NegativeCacheConfig.TTLMillis < 0 {
+			return cacheErr(fmt.Sprintf("manufacturer_config_negative_cache_ttl_invalid: %v", c.NegativeCacheConfig.TTLMillis))
+		}
+		if c.BatchConfig.WindowMillis < 0 || c.BatchConfig.MaxBatchSize < 0 {
+			return cacheErr(fmt.Sprintf("manufacturer_config_batch_config_invalid: %+v", c.BatchConfig))
+		}
 		return c.validateAcrossInstanceSignalConfig(cacheType)
 	}
 	return nil
@@ -3,6 +3,12 @@ package cache
 const (
 	// cmdGet constant val of Get
 	cmdGet = "Get"
+	// cmdGetWithMeta constant val of GetWithMeta
+	cmdGetWithMeta = "GetWithMeta"
+	// cmdGetWithVersion constant val of GetWithVersion
+	cmdGetWithVersion = "GetWithVersion"
+	// cmdTTL constant val of TTL
+	cmdTTL = "TTL"
 	// cmdGetMany constant val of GetMany
 	cmdGetMany = "GetMany"
 	// cmdSet constant val of Set
@@ -13,10 +19,18 @@ const (
 	cmdAdd = "Add"
 	// cmdReplace constant val of Replace
 	cmdReplace = "Replace"
+	// cmdCompareAndSwap constant val of CompareAndSwap
+	cmdCompareAndSwap = "CompareAndSwap"
 	// cmdDelete constant val of Delete
 	cmdDelete = "Delete"
 	// cmdDeleteMany constant val of DeleteMany
 	cmdDeleteMany = "DeleteMany"
+	// cmdDeleteByPrefix constant val of DeleteByPrefix
+	cmdDeleteByPrefix = "DeleteByPrefix"
+	// cmdDeleteMatching constant val of DeleteMatching
+	cmdDeleteMatching = "DeleteMatching"
+	// cmdInvalidateTags constant val of InvalidateTags
+	cmdInvalidateTags = "InvalidateTags"
 	// cmdIncrement constant val of Increment
 	cmdIncrement = "Increment"
 	// cmdDecrement constant val of Decrement
@@ -27,4 +41,6 @@ const (
 	cmdPing = "Ping"
 	// cmdExpire constant val of Expire
 	cmdExpire = "Expire"
+	// cmdCompare constant val of Compare, reported by MigrationCache in ShadowCompare mode
+	cmdCompare = "Compare"
 )
@@ -27,6 +27,11 @@ func addDlockPrefix(key string) string {
 	return dlockPrefix + key
 }
 
+// getDlockKey returns the key of the dlock of key, which is transformed in the same way as the key
+func getDlockKey(ctx context.Context, inner *cacheWrapperInner, key string) string {
+	return inner.getFixedKey(ctx, addDlockPrefix(key))
+}
+
 // randDlockValue is the randome unique 32-bit value for the distributed lock
 // nolint:gosec
 func randDlockValue() []byte {
@@ -58,10 +63,26 @@ func reclassifyToHandleKeys(ctx context.Context, inner *cacheWrapperInner, toHan
 }
 
 // acquireDlock tries to acquire the dlock [For Lock-Holders and Lock-Listeners].
-func acquireDlock(ctx context.Context, inner *cacheWrapperInner, keys []string, value []byte, expire time.Duration) ([]bool, error) {
-	isAcquire := make([]bool, len(keys))
+func acquireDlock(ctx context.Context, inner *cacheWrapperInner, keys []string, value []byte, expire time.Duration) (isAcquire []bool, err error) {
+	ctx, span := inner.observationConfig.startSpan(ctx, spanNameAcquireDlock)
+	defer func() {
+		var acquiredKeyCount int
+		for _, acquired := range isAcquire {
+			if acquired {
+				acquiredKeyCount++
+			}
+		}
+		span.SetAttributes(
+			SpanAttribute{Key: "cache.name", Value: inner.name},
+			SpanAttribute{Key: "cache.total_key_count", Value: len(keys)},
+			SpanAttribute{Key: "cache.acquired_key_count", Value: acquiredKeyCount},
+		)
+		span.Finish(err)
+	}()
+
+	isAcquire = make([]bool, len(keys))
 	for idx, key := range keys {
-		err := inner.cache.add(ctx, addDlockPrefix(key), value, expire)
+		err := inner.cache.add(ctx, getDlockKey(ctx, inner, key), value, expire)
 		if err != nil && err != ErrNotStored {
 			// the acquired keys will be released later, no need to release them here
 			// we can return error directly
@@ -82,7 +103,7 @@ func releaseDlock(ctx context.Context, inner *cacheWrapperInner, loadResultMap m
 		curDlockVal, _ := getDlock(ctx, inner, key)
 
 		if bytes.Equal(curDlockVal, value) {
-			_ = inner.cache.delete(ctx, addDlockPrefix(key))
+			_ = inner.cache.delete(ctx, getDlockKey(ctx, inner, key))
 		}
 	}
 }
@@ -96,14 +117,13 @@ func extendDlock(ctx context.Context, inner *cacheWrapperInner, keys []string, v
 			continue
 		}
 		if bytes.Equal(curDlockVal, value) {
-			err := inner.cache.expire(ctx, addDlockPrefix(key), expire)
+			err := inner.cache.expire(ctx, getDlockKey(ctx, inner, key), expire)
 			if err != nil {
-				//TODO 添加日志
-
+				inner.observationConfig.errorf(ctx, "failed to extend dlock: cache_name=%v, key=%v, err=%v", inner.name, key, err)
 			}
 		} else {
 			// this log may appear many times, since lock extension will continue even if it has failed before
-			//TODO 添加日志
+			inner.observationConfig.warnf(ctx, "dlock is no longer held, skip extending: cache_name=%v, key=%v", inner.name, key)
 		}
 	}
 }
@@ -126,7 +146,7 @@ func getLostDlockKeys(ctx context.Context, inner *cacheWrapperInner, keys []stri
 
 // getDlock gets value from dlock's keys [For Lock-Holder and Lock-Listeners].
 func getDlock(ctx context.Context, inner *cacheWrapperInner, key string) (byteData []byte, err error) {
-	data, err := inner.cache.get(ctx, addDlockPrefix(key))
+	data, err := inner.cache.get(ctx, getDlockKey(ctx, inner, key))
 	if err != nil {
 		return nil, err
 	}
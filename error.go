@@ -56,16 +56,45 @@ var (
 	// errNilReceiver means the receiver(s) from Get/GetMany/Load/LoadMany is nil
 	errNilReceiver = cacheErr("receiver_is_nil")
 
+	// errTypedValueMismatch means that the data stored in cache is not of the type of TypedCache
+	errTypedValueMismatch = cacheErr("typed_value_type_mismatch")
+
 	// errHotKeyRegexpCompile means that the hot key regex patterns are invalid
 	errHotKeyRegexpCompile = cacheErr("hotkey_regexp_compile_failed")
 
 	errInMemoryConfigCacheTypeNotSupported = cacheErr("inmemory_config_cache_type_not_supported")
 
+	// errInMemoryKeyIndexNotEnabled means that DeleteByPrefix/DeleteMatching is called without InMemoryCacheConfig.EnableKeyIndex
+	errInMemoryKeyIndexNotEnabled = cacheErr("inmemory_key_index_not_enabled")
+
+	// errInMemoryKeyIndexNotUpdatable means that InMemoryCacheConfig.EnableKeyIndex is changed by UpdateConfig
+	errInMemoryKeyIndexNotUpdatable = cacheErr("inmemory_key_index_not_updatable")
+
+	// errInMemoryWriteDropped means that a write of a read-modify-write operation is dropped by the in-memory cache,
+	// e.g. due to contention of the buffer or rejection by the admission policy of Ristretto
+	errInMemoryWriteDropped = cacheErr("inmemory_write_dropped")
+
+	// errInvalidTagVersion means that the version of a tag stored in the cache is not an integer
+	errInvalidTagVersion = cacheErr("invalid_tag_version")
+
+	// errItemVersionNotSupported means that the data is not versioned since encoding is disabled or skipped, refer to CompareAndSwap
+	errItemVersionNotSupported = cacheErr("item_version_not_supported")
+
+	// errKeyPatternCompile means that the key regex pattern of DeleteMatching is invalid
+	errKeyPatternCompile = cacheErr("key_pattern_compile_failed")
+
 	// errContextTimeout means that cache operation is suspended due to context timeout, but not all timeout error will return this error
 	errContextTimeout = cacheErr("cache_context_timeout_err")
 
+	// errLoadNotCompleted means that the loading of a key is abandoned without result (e.g. due to panic),
+	// the goroutines waiting for the key within the same process receive this error
+	errLoadNotCompleted = cacheErr("load_not_completed")
+
 	// errDlockLoss means that cache value in waiting instances is filled with nil data due to dlock loss when using the AcrossInstanceSignal strategy
 	errDlockLoss = cacheErr("cache_value_fill_in_nil_due_to_dlock_loss")
+
+	// errMemcachedMalformedKey means that the key is too long or contains whitespace or control characters which memcached does not accept
+	errMemcachedMalformedKey = cacheErr("memcached_malformed_key")
 )
 
 func isTimeoutError(err error) bool {
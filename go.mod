@@ -8,6 +8,7 @@ require (
 	github.com/golang/snappy v0.0.4
 	github.com/json-iterator/go v1.1.12
 	github.com/pkg/errors v0.9.1
+	google.golang.org/protobuf v1.26.0
 )
 
 require (
@@ -17,5 +18,4 @@ require (
 	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	golang.org/x/sys v0.0.0-20221010170243-090e33056c14 // indirect
-	google.golang.org/protobuf v1.26.0 // indirect
 )
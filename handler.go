@@ -2,6 +2,9 @@ package cache
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"time"
 
 	"go-eCache/codec"
@@ -9,6 +12,54 @@ import (
 	"go-eCache/internal/group"
 )
 
+/**** keyHandler ****/
+
+// hashedKeyMarker separates the prefix and the hash of a hashed key
+const hashedKeyMarker = "#"
+
+// hashedKeyLength is the length of a hashed key excluding the prefix, i.e. the marker and the hex-encoded SHA-256 hash
+const hashedKeyLength = len(hashedKeyMarker) + sha256.Size*2
+
+// keyHandler transforms the keys of cache operations into the keys sent to the cache (i.e. fixed keys)
+type keyHandler struct {
+	prefix       string // namespace and version segments
+	maxKeyLength int    // keys longer than it are hashed, 0 means no limit
+}
+
+func newKeyHandler(config KeyConfig, defaultMaxKeyLength int) keyHandler {
+	maxKeyLength := config.MaxKeyLength
+	if maxKeyLength == 0 {
+		maxKeyLength = defaultMaxKeyLength
+	}
+	return keyHandler{
+		prefix:       config.keyPrefix(),
+		maxKeyLength: maxKeyLength,
+	}
+}
+
+// fixKey prepends the prefix to key, and hashes it if it is longer than maxKeyLength
+func (h keyHandler) fixKey(key string) string {
+	if h.prefix == "" && (h.maxKeyLength == 0 || len(key) <= h.maxKeyLength) {
+		return key
+	}
+
+	fixedKey := h.prefix + key
+	if h.maxKeyLength > 0 && len(fixedKey) > h.maxKeyLength {
+		sum := sha256.Sum256([]byte(key))
+		fixedKey = h.prefix + hashedKeyMarker + hex.EncodeToString(sum[:])
+	}
+	return fixedKey
+}
+
+// trimPrefix returns the key of fixedKey without the prefix, false if fixedKey does not have the prefix.
+// The key returned for a hashed key is the marker and the hash.
+func (h keyHandler) trimPrefix(fixedKey string) (string, bool) {
+	if !strings.HasPrefix(fixedKey, h.prefix) {
+		return "", false
+	}
+	return fixedKey[len(h.prefix):], true
+}
+
 /**** encodingHandler ****/
 
 type encodingHandler struct {
@@ -51,7 +102,11 @@ func (h encodingHandler) encode(byt []byte, opts ...bytesProtocolOption) ([]byte
 	return bytesEncode(byt,
 		algo,
 		withSoftTimeoutTs(option.softTimeoutTs),
-		withHardTimeoutTs(option.hardTimeoutTs))
+		withHardTimeoutTs(option.hardTimeoutTs),
+		withTags(option.tags, option.tagVersions),
+		withVersion(option.version),
+		withComputeTimeMs(option.computeTimeMs),
+		withTombstone(option.tombstone))
 }
 
 func (h encodingHandler) decode(byt []byte) ([]byte, metaHeader, error) {
@@ -111,6 +166,9 @@ type manufacturerHandler struct {
 	strategy                   StampedeMitigationStrategy
 	group                      *group.Group // sync results within the same process (instance) if strategy is InProcessSignal or AcrossInstanceSignal.
 	acrossInstanceSignalConfig acrossInstanceSignalConfig
+	staleIfError               time.Duration  // the default grace window of stale-if-error for Load(Many)
+	negativeCacheTTL           time.Duration  // the default expiration of tombstones for Load(Many)
+	batcher                    *loaderBatcher // batches DataLoader calls of Load(Many) with batch names, nil if batching is not applicable
 }
 
 type acrossInstanceSignalConfig struct {
@@ -120,7 +178,13 @@ type acrossInstanceSignalConfig struct {
 
 func newManufacturerHandler(config ManufacturerConfig) manufacturerHandler {
 	strategy := config.CacheStampedeMitigation
-	curManufacturerHandler := manufacturerHandler{strategy: strategy, group: group.NewGroup()}
+	curManufacturerHandler := manufacturerHandler{
+		strategy:         strategy,
+		group:            group.NewGroup(),
+		staleIfError:     time.Duration(config.StaleIfErrorMillis) * time.Millisecond,
+		negativeCacheTTL: time.Duration(config.NegativeCacheConfig.TTLMillis) * time.Millisecond,
+		batcher:          newLoaderBatcher(config.BatchConfig),
+	}
 	if strategy == AcrossInstanceSignal {
 		if config.AcrossInstanceSignalConfig.RetryIntervalMillis == 0 {
 			config.AcrossInstanceSignalConfig.RetryIntervalMillis = defaultDlockRetryIntervalMillis
@@ -136,6 +200,15 @@ func newManufacturerHandler(config ManufacturerConfig) manufacturerHandler {
 	return curManufacturerHandler
 }
 
+// forCtx returns the handler applied to the operation with ctx.
+// The isolated operations (refer to isIsolatedCtx) are not synchronized with others, thus neither protection nor batching is applied.
+func (h manufacturerHandler) forCtx(ctx context.Context) manufacturerHandler {
+	if isIsolatedCtx(ctx) {
+		return manufacturerHandler{strategy: NoProtection}
+	}
+	return h
+}
+
 // add splits the keys into 2 groups: toHandleKeys and waitingInProcessSignalCallsMap, based on the stampede mitigation strategy.
 //
 // toHandleKeys: list of keys that needs to be handled by this go-routine.
@@ -147,40 +220,57 @@ func (h manufacturerHandler) add(ctx context.Context, keys []string) (toHandleKe
 	case NoProtection:
 		toHandleKeys = append(toHandleKeys, keys...)
 	case InProcessSignal, AcrossInstanceSignal:
-		// check whether current request is from shadow traffic
+		// shadow traffic is not synchronized with others, refer to forCtx
 		waitingInProcessSignalCallsMap, toHandleKeys = h.group.AddCalls(keys)
 
 	}
 	return toHandleKeys, waitingInProcessSignalCallsMap
 }
 
-// complete synchronizes the values in valueMap to the ManufacturerHandler's waiting group.
+// handle loads toHandleKeys by load, and completes them by complete.
+// toHandleKeys are completed even if load panics, so that the goroutines waiting for them are never blocked forever.
+func (h manufacturerHandler) handle(ctx context.Context, toHandleKeys []string, load func() map[string]loadResult) (loadResultMap map[string]loadResult) {
+	defer func() {
+		h.complete(ctx, toHandleKeys, loadResultMap)
+	}()
+
+	return load()
+}
+
+// complete synchronizes the load results of toHandleKeys to the ManufacturerHandler's waiting group.
+// The keys absent in loadResultMap are completed with errLoadNotCompleted.
 //
 // If the strategy is `NoProtection`, it will do nothing.
-func (h manufacturerHandler) complete(ctx context.Context, valueMap map[string]interface{}) {
+func (h manufacturerHandler) complete(ctx context.Context, toHandleKeys []string, loadResultMap map[string]loadResult) {
 	switch h.strategy {
 	case NoProtection:
 	case InProcessSignal, AcrossInstanceSignal:
-		h.group.CompleteCalls(valueMap)
+		h.group.CompleteCalls(genToCompleteResultMap(toHandleKeys, loadResultMap))
 
 	}
 }
 
-func genToCompleteResultMap(loadResultMap map[string]loadResult) map[string]interface{} {
-	toCompleteResultMap := make(map[string]interface{}, len(loadResultMap))
-	for key, result := range loadResultMap {
-		toCompleteResultMap[key] = result
+func genToCompleteResultMap(toHandleKeys []string, loadResultMap map[string]loadResult) map[string]group.Result {
+	toCompleteResultMap := make(map[string]group.Result, len(toHandleKeys))
+	for _, key := range toHandleKeys {
+		result, ok := loadResultMap[key]
+		if !ok {
+			toCompleteResultMap[key] = group.Result{Err: errLoadNotCompleted}
+			continue
+		}
+		toCompleteResultMap[key] = group.Result{Val: result, Err: result.err}
 	}
 	return toCompleteResultMap
 }
 
-// wait pends on waiting values, and returns the value and error of the call.
+// wait pends on waiting values until ctx is done, and returns the value and error of the call.
+// The value is the loadResult of the key if the call is completed with a result, even if the loading failed.
 //
 // If the strategy is `NoProtection`, it will do nothing.
-func (h manufacturerHandler) wait(call *group.Call) (interface{}, error) {
+func (h manufacturerHandler) wait(ctx context.Context, call *group.Call) (interface{}, error) {
 	switch h.strategy {
 	case InProcessSignal, AcrossInstanceSignal:
-		return h.group.WaitCall(call)
+		return h.group.WaitCallContext(ctx, call)
 	}
 	return nil, nil
 }
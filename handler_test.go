@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestInProcessSignalCache(t *testing.T) *InMemoryCache {
+	c, err := NewInMemoryCache("test_inmemory", InMemoryCacheConfig{
+		CacheType:          Ristretto,
+		ManufacturerConfig: ManufacturerConfig{CacheStampedeMitigation: InProcessSignal},
+	})
+	if err != nil {
+		t.Fatalf("new in-memory cache err: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+
+	return c
+}
+
+// startBlockingLoad starts a Load of key whose loader blocks until release is closed, and then returns loaded.
+// It returns after the loader is called, so that the later Loads of key wait for it.
+func startBlockingLoad(t *testing.T, c Cache, key string, release chan struct{}, loaded func() ([]interface{}, error)) <-chan error {
+	started := make(chan struct{})
+	errCh := make(chan error, 1)
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		close(started)
+		<-release
+		return loaded()
+	}
+	go func() {
+		var val string
+		errCh <- c.Load(context.Background(), loader, key, &val, time.Minute)
+	}()
+	<-started
+
+	return errCh
+}
+
+func TestInProcessSignalWaiterTimeout(t *testing.T) {
+	c := newTestInProcessSignalCache(t)
+	release := make(chan struct{})
+	errCh := startBlockingLoad(t, c, "key", release, func() ([]interface{}, error) {
+		return []interface{}{"val"}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var val string
+	if err := c.Load(ctx, nil, "key", &val, time.Minute); err != context.DeadlineExceeded {
+		t.Fatalf("expect the waiter to time out, got: %v", err)
+	}
+
+	close(release)
+	if err := <-errCh; err != nil {
+		t.Fatalf("expect the loading goroutine unaffected, got: %v", err)
+	}
+}
+
+func TestInProcessSignalLoaderError(t *testing.T) {
+	c := newTestInProcessSignalCache(t)
+	loaderErr := errors.New("loader err")
+	release := make(chan struct{})
+	errCh := startBlockingLoad(t, c, "key", release, func() ([]interface{}, error) {
+		return nil, loaderErr
+	})
+
+	var wg sync.WaitGroup
+	waiterErrs := make([]error, 3)
+	for i := range waiterErrs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var val string
+			waiterErrs[i] = c.Load(context.Background(), nil, "key", &val, time.Minute)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if err := <-errCh; err != loaderErr {
+		t.Fatalf("expect loader err, got: %v", err)
+	}
+	for _, err := range waiterErrs {
+		if err != loaderErr {
+			t.Fatalf("expect loader err propagated to the waiter, got: %v", err)
+		}
+	}
+}
+
+func TestInProcessSignalLoaderPanic(t *testing.T) {
+	c := newTestInProcessSignalCache(t)
+	release := make(chan struct{})
+	errCh := startBlockingLoad(t, c, "key", release, func() ([]interface{}, error) {
+		panic("loader panic")
+	})
+
+	waiterErrCh := make(chan error, 1)
+	go func() {
+		var val string
+		waiterErrCh <- c.Load(context.Background(), nil, "key", &val, time.Minute)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if err := <-errCh; err != errDataLoaderPanic {
+		t.Fatalf("expect data loader panic, got: %v", err)
+	}
+	select {
+	case err := <-waiterErrCh:
+		if err != errDataLoaderPanic {
+			t.Fatalf("expect data loader panic propagated to the waiter, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expect the waiter released after the loader panicked")
+	}
+}
@@ -0,0 +1,329 @@
+package cache
+
+import (
+	"context"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// hotKeySketchDepth is the number of rows of the count-min sketch
+	hotKeySketchDepth = 4
+	// hotKeySketchWidth is the number of counters in each row of the count-min sketch, must be a power of 2
+	hotKeySketchWidth = 1 << 12
+)
+
+// HotKey is a key detected as hot
+type HotKey struct {
+	Key       string // the key sent to the cache, i.e. transformed by KeyConfig
+	Frequency uint64 // the estimated access count of the key, which is halved every window
+}
+
+// hotKeyHandler detects hot keys by their read frequency, and serves detected hot keys of remote caches from local memory.
+// A nil *hotKeyHandler means hot key detection is disabled.
+//
+// Read frequencies are estimated by a count-min sketch, whose counters are halved every window,
+// so that keys no longer accessed cool down. The topN keys whose frequencies reach minFrequency are hot keys.
+type hotKeyHandler struct {
+	patterns     []*regexp.Regexp
+	topN         int
+	minFrequency uint32
+	window       time.Duration
+	localTTL     time.Duration // 0 means hot keys are not served from local memory
+
+	mu            sync.Mutex
+	sketch        [hotKeySketchDepth][hotKeySketchWidth]uint32
+	hotKeys       map[string]uint32 // hot keys and their estimated frequencies
+	localItems    map[string]hotKeyLocalItem
+	lastDecayTime time.Time
+}
+
+type hotKeyLocalItem struct {
+	value    interface{}
+	expireAt time.Time
+}
+
+// newHotKeyHandler creates the handler from config, local serving is only applied if isRemote
+func newHotKeyHandler(config HotKeyConfig, isRemote bool) (*hotKeyHandler, error) {
+	if !config.Enable {
+		return nil, nil
+	}
+
+	patterns, err := compileHotKeyPatterns(config.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &hotKeyHandler{
+		patterns:      patterns,
+		topN:          config.TopN,
+		minFrequency:  uint32(config.MinFrequency),
+		window:        time.Duration(config.WindowSecs) * time.Second,
+		hotKeys:       make(map[string]uint32),
+		localItems:    make(map[string]hotKeyLocalItem),
+		lastDecayTime: time.Now(),
+	}
+	if h.topN == 0 {
+		h.topN = defaultHotKeyTopN
+	}
+	if h.minFrequency == 0 {
+		h.minFrequency = defaultHotKeyMinFrequency
+	}
+	if h.window == 0 {
+		h.window = defaultHotKeyWindowSecs * time.Second
+	}
+	if isRemote && !config.DisableLocalCache {
+		h.localTTL = time.Duration(config.LocalCacheTTLMillis) * time.Millisecond
+		if h.localTTL == 0 {
+			h.localTTL = defaultHotKeyLocalCacheTTLMillis * time.Millisecond
+		}
+	}
+
+	return h, nil
+}
+
+func compileHotKeyPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errHotKeyRegexpCompile
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// access records a read of key, and returns the locally served value of key if key is hot
+func (h *hotKeyHandler) access(key string) (value interface{}, found bool, isHot bool) {
+	if !h.matchPatterns(key) {
+		return nil, false, false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.decayIfNeeded(now)
+	isHot = h.record(key)
+	if !isHot || h.localTTL == 0 {
+		return nil, false, isHot
+	}
+
+	item, ok := h.localItems[key]
+	if !ok {
+		return nil, false, true
+	}
+	if now.After(item.expireAt) {
+		delete(h.localItems, key)
+		return nil, false, true
+	}
+	return item.value, true, true
+}
+
+// setLocal stores the value of the hot key to be served locally
+func (h *hotKeyHandler) setLocal(key string, value interface{}) {
+	if h.localTTL == 0 || value == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.hotKeys[key]; ok {
+		h.localItems[key] = hotKeyLocalItem{value: value, expireAt: time.Now().Add(h.localTTL)}
+	}
+}
+
+// invalidate removes the locally served values of keys, it should be called once keys are modified
+func (h *hotKeyHandler) invalidate(keys ...string) {
+	if h == nil || h.localTTL == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, key := range keys {
+		delete(h.localItems, key)
+	}
+}
+
+// invalidateAll removes all the locally served values
+func (h *hotKeyHandler) invalidateAll() {
+	if h == nil || h.localTTL == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.localItems = make(map[string]hotKeyLocalItem)
+}
+
+// getHotKeys returns the hot keys in descending order of frequency
+func (h *hotKeyHandler) getHotKeys() []HotKey {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.decayIfNeeded(time.Now())
+	hotKeys := make([]HotKey, 0, len(h.hotKeys))
+	for key, frequency := range h.hotKeys {
+		hotKeys = append(hotKeys, HotKey{Key: key, Frequency: uint64(frequency)})
+	}
+	sort.Slice(hotKeys, func(i, j int) bool {
+		if hotKeys[i].Frequency != hotKeys[j].Frequency {
+			return hotKeys[i].Frequency > hotKeys[j].Frequency
+		}
+		return hotKeys[i].Key < hotKeys[j].Key
+	})
+	return hotKeys
+}
+
+func (h *hotKeyHandler) matchPatterns(key string) bool {
+	if len(h.patterns) == 0 {
+		return true
+	}
+	for _, pattern := range h.patterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// record increments the counters of key in the sketch and updates the hot keys, returns if key is hot.
+// It must be called with h.mu held.
+func (h *hotKeyHandler) record(key string) bool {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+	sum := hash.Sum64()
+	h1, h2 := uint32(sum), uint32(sum>>32)|1
+
+	frequency := ^uint32(0)
+	for row := 0; row < hotKeySketchDepth; row++ {
+		counter := &h.sketch[row][(h1+uint32(row)*h2)&(hotKeySketchWidth-1)]
+		if *counter < ^uint32(0) {
+			*counter++
+		}
+		if *counter < frequency {
+			frequency = *counter
+		}
+	}
+
+	if _, ok := h.hotKeys[key]; ok {
+		h.hotKeys[key] = frequency
+		return true
+	}
+	if frequency < h.minFrequency {
+		return false
+	}
+	if len(h.hotKeys) < h.topN {
+		h.hotKeys[key] = frequency
+		return true
+	}
+
+	// replace the coldest hot key if key is hotter
+	coldestKey, coldestFrequency := "", ^uint32(0)
+	for hotKey, hotFrequency := range h.hotKeys {
+		if hotFrequency < coldestFrequency {
+			coldestKey, coldestFrequency = hotKey, hotFrequency
+		}
+	}
+	if frequency <= coldestFrequency {
+		return false
+	}
+	delete(h.hotKeys, coldestKey)
+	delete(h.localItems, coldestKey)
+	h.hotKeys[key] = frequency
+	return true
+}
+
+// decayIfNeeded halves all the counters once a window is passed, hot keys below minFrequency after decay are removed.
+// It must be called with h.mu held.
+func (h *hotKeyHandler) decayIfNeeded(now time.Time) {
+	if now.Sub(h.lastDecayTime) < h.window {
+		return
+	}
+	h.lastDecayTime = now
+
+	for row := range h.sketch {
+		for idx := range h.sketch[row] {
+			h.sketch[row][idx] >>= 1
+		}
+	}
+	for key, frequency := range h.hotKeys {
+		frequency >>= 1
+		if frequency < h.minFrequency {
+			delete(h.hotKeys, key)
+			delete(h.localItems, key)
+			continue
+		}
+		h.hotKeys[key] = frequency
+	}
+}
+
+// getFromCache gets the value of key from the inner cache, hot keys are detected and served locally if enabled
+func (c *cacheWrapperInner) getFromCache(ctx context.Context, key string) (interface{}, error) {
+	h := c.hotKeyHandler
+	if h == nil {
+		return c.cache.get(ctx, key)
+	}
+
+	value, found, isHot := h.access(key)
+	if found {
+		return value, nil
+	}
+	value, err := c.cache.get(ctx, key)
+	if err == nil && isHot {
+		h.setLocal(key, value)
+	}
+	return value, err
+}
+
+// getManyFromCache gets the values of keys from the inner cache, hot keys are detected and served locally if enabled
+func (c *cacheWrapperInner) getManyFromCache(ctx context.Context, keys []string) ([]interface{}, error) {
+	h := c.hotKeyHandler
+	if h == nil {
+		return c.cache.getMany(ctx, keys...)
+	}
+
+	values := make([]interface{}, len(keys))
+	var remoteKeys []string
+	var remoteIdxList []int
+	var hotIdxList []int
+	for idx, key := range keys {
+		value, found, isHot := h.access(key)
+		if found {
+			values[idx] = value
+			continue
+		}
+		if isHot {
+			hotIdxList = append(hotIdxList, idx)
+		}
+		remoteKeys = append(remoteKeys, key)
+		remoteIdxList = append(remoteIdxList, idx)
+	}
+	if len(remoteKeys) == 0 {
+		return values, nil
+	}
+
+	remoteValues, err := c.cache.getMany(ctx, remoteKeys...)
+	if err != nil {
+		return nil, err
+	}
+	for i, idx := range remoteIdxList {
+		values[idx] = remoteValues[i]
+	}
+	for _, idx := range hotIdxList {
+		h.setLocal(keys[idx], values[idx])
+	}
+	return values, nil
+}
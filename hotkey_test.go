@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHotKeyDetection(t *testing.T) {
+	h, err := newHotKeyHandler(HotKeyConfig{Enable: true, Patterns: []string{"^user:"}, TopN: 2, MinFrequency: 3}, false)
+	if err != nil {
+		t.Fatalf("new hot key handler err: %v", err)
+	}
+
+	for key, count := range map[string]int{"user:a": 5, "user:b": 4, "user:c": 3, "user:d": 2, "order:a": 10} {
+		for i := 0; i < count; i++ {
+			h.access(key)
+		}
+	}
+
+	hotKeys := h.getHotKeys()
+	if len(hotKeys) != 2 || hotKeys[0] != (HotKey{Key: "user:a", Frequency: 5}) || hotKeys[1] != (HotKey{Key: "user:b", Frequency: 4}) {
+		t.Fatalf("expect user:a and user:b as hot keys, got: %v", hotKeys)
+	}
+
+	// a key hotter than the coldest hot key replaces it
+	for i := 0; i < 3; i++ {
+		h.access("user:c")
+	}
+	hotKeys = h.getHotKeys()
+	if len(hotKeys) != 2 || hotKeys[0].Key != "user:c" || hotKeys[1].Key != "user:a" {
+		t.Fatalf("expect user:c and user:a as hot keys, got: %v", hotKeys)
+	}
+
+	// counters are halved after a window
+	h.lastDecayTime = time.Now().Add(-h.window)
+	hotKeys = h.getHotKeys()
+	if len(hotKeys) != 1 || hotKeys[0] != (HotKey{Key: "user:c", Frequency: 3}) {
+		t.Fatalf("expect only user:c kept after decay, got: %v", hotKeys)
+	}
+
+	var disabled *hotKeyHandler
+	if disabled.getHotKeys() != nil {
+		t.Fatalf("expect no hot keys when disabled")
+	}
+
+	if err := (HotKeyConfig{Patterns: []string{"("}}).Validate(); err != errHotKeyRegexpCompile {
+		t.Fatalf("expect regexp compile error, got: %v", err)
+	}
+}
+
+func TestRedisCacheHotKeyLocalServing(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{
+		Address:      server.addr(),
+		HotKeyConfig: HotKeyConfig{Enable: true, MinFrequency: 2, LocalCacheTTLMillis: 60000},
+	})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	if err := c.Set(ctx, "k", "v1", time.Minute); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	var val string
+	for i := 0; i < 3; i++ {
+		if err := c.Get(ctx, "k", &val); err != nil || val != "v1" {
+			t.Fatalf("expect v1, got: %v, err: %v", val, err)
+		}
+	}
+	if hotKeys := c.HotKeys(); len(hotKeys) != 1 || hotKeys[0].Key != "k" {
+		t.Fatalf("expect k as hot key, got: %v", hotKeys)
+	}
+
+	// the hot key is served locally even if it is removed from redis by others
+	server.mu.Lock()
+	delete(server.data, "k")
+	server.mu.Unlock()
+	if err := c.Get(ctx, "k", &val); err != nil || val != "v1" {
+		t.Fatalf("expect v1 served locally, got: %v, err: %v", val, err)
+	}
+	receiverMap := map[string]interface{}{"k": new(string)}
+	if err := c.GetMany(ctx, receiverMap); err != nil || *receiverMap["k"].(*string) != "v1" {
+		t.Fatalf("expect v1 served locally by GetMany, got: %v, err: %v", receiverMap, err)
+	}
+
+	// writes through the cache invalidate the local value
+	if err := c.Set(ctx, "k", "v2", time.Minute); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	if err := c.Get(ctx, "k", &val); err != nil || val != "v2" {
+		t.Fatalf("expect v2 after set, got: %v, err: %v", val, err)
+	}
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Fatalf("delete err: %v", err)
+	}
+	if err := c.Get(ctx, "k", &val); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss after delete, got: %v", err)
+	}
+}
+
+func TestRedisCacheHotKeysKeptByUpdateConfig(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	config := RedisConfig{
+		Address:      server.addr(),
+		HotKeyConfig: HotKeyConfig{Enable: true, MinFrequency: 2},
+	}
+	c, err := NewRedisCache("test_redis", config)
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	var val string
+	for i := 0; i < 3; i++ {
+		_ = c.Get(ctx, "k", &val)
+	}
+	if hotKeys := c.HotKeys(); len(hotKeys) != 1 || hotKeys[0].Key != "k" {
+		t.Fatalf("expect k as hot key, got: %v", hotKeys)
+	}
+
+	// the hot keys are kept if the hot key config is unchanged
+	config.MaxExpirationSecs = 3600
+	if err = c.UpdateConfig(config); err != nil {
+		t.Fatalf("update config err: %v", err)
+	}
+	if hotKeys := c.HotKeys(); len(hotKeys) != 1 || hotKeys[0].Key != "k" {
+		t.Fatalf("expect k kept as hot key, got: %v", hotKeys)
+	}
+
+	config.HotKeyConfig.MinFrequency = 10
+	if err = c.UpdateConfig(config); err != nil {
+		t.Fatalf("update config err: %v", err)
+	}
+	if hotKeys := c.HotKeys(); len(hotKeys) != 0 {
+		t.Fatalf("expect hot keys reset by the changed hot key config, got: %v", hotKeys)
+	}
+}
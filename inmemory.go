@@ -2,10 +2,14 @@ package cache
 
 import (
 	"context"
+	"regexp"
+	"strings"
 	"sync/atomic"
 	"time"
 )
 
+var _ Cache = (*InMemoryCache)(nil)
+
 // InMemoryCache implements Cache interface
 type InMemoryCache struct {
 	inner *cacheWrapper
@@ -25,6 +29,22 @@ func (c *InMemoryCache) Get(ctx context.Context, key string, receiver interface{
 	return c.inner.get(ctx, key, receiver, opts...)
 }
 
+// GetWithMeta (refer to GetWithMeta of Cache interface)
+func (c *InMemoryCache) GetWithMeta(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) (ItemMeta, error) {
+	return c.inner.getWithMeta(ctx, cmdGetWithMeta, key, receiver, opts...)
+}
+
+// GetWithVersion (refer to GetWithVersion of Cache interface)
+func (c *InMemoryCache) GetWithVersion(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) (int64, error) {
+	meta, err := c.inner.getWithMeta(ctx, cmdGetWithVersion, key, receiver, opts...)
+	return meta.Version, err
+}
+
+// TTL (refer to TTL of Cache interface)
+func (c *InMemoryCache) TTL(ctx context.Context, key string, opts ...OperationOption) (ItemMeta, error) {
+	return c.inner.getWithMeta(ctx, cmdTTL, key, nil, opts...)
+}
+
 // GetMany (refer to GetMany of Cache interface)
 func (c *InMemoryCache) GetMany(ctx context.Context, receiverMap map[string]interface{}, opts ...OperationOption) error {
 	return c.inner.getMany(ctx, receiverMap, opts...)
@@ -40,6 +60,11 @@ func (c *InMemoryCache) SetMany(ctx context.Context, valueMap map[string]interfa
 	return c.inner.setMany(ctx, valueMap, expire, opts...)
 }
 
+// CompareAndSwap (refer to CompareAndSwap of Cache interface)
+func (c *InMemoryCache) CompareAndSwap(ctx context.Context, key string, value interface{}, version int64, expire time.Duration, opts ...OperationOption) error {
+	return c.inner.compareAndSwap(ctx, key, value, version, expire, opts...)
+}
+
 // Delete (refer to Delete of Cache interface)
 func (c *InMemoryCache) Delete(ctx context.Context, key string, opts ...OperationOption) error {
 	return c.inner.delete(ctx, key, opts...)
@@ -50,6 +75,56 @@ func (c *InMemoryCache) DeleteMany(ctx context.Context, keys []string, opts ...O
 	return c.inner.deleteMany(ctx, keys, opts...)
 }
 
+// DeleteByPrefix deletes all the keys with prefix, and returns the number of deleted keys.
+// It requires EnableKeyIndex of InMemoryCacheConfig. The prefix of KeyConfig is applied, i.e. only the keys of current namespace
+// and version are deleted, while keys hashed due to MaxKeyLength of KeyConfig are not matched by their original keys.
+func (c *InMemoryCache) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	return c.inner.deleteMatching(ctx, cmdDeleteByPrefix, prefix, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// DeleteMatching deletes all the keys matching the regular expression pattern, and returns the number of deleted keys.
+// It requires EnableKeyIndex of InMemoryCacheConfig, and keys are matched in the same way as DeleteByPrefix.
+// Both DeleteByPrefix and DeleteMatching scan all the indexed keys, which should be taken into account for large caches.
+func (c *InMemoryCache) DeleteMatching(ctx context.Context, pattern string) (int, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return 0, errKeyPatternCompile
+	}
+	return c.inner.deleteMatching(ctx, cmdDeleteMatching, pattern, re.MatchString)
+}
+
+// InvalidateTags (refer to InvalidateTags of Cache interface)
+func (c *InMemoryCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	return c.inner.invalidateTags(ctx, tags)
+}
+
+// Add (refer to Add of Cache interface)
+func (c *InMemoryCache) Add(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error {
+	return c.inner.add(ctx, key, value, expire, opts...)
+}
+
+// Replace (refer to Replace of Cache interface)
+func (c *InMemoryCache) Replace(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error {
+	return c.inner.replace(ctx, key, value, expire, opts...)
+}
+
+// Increment (refer to Increment of Cache interface)
+func (c *InMemoryCache) Increment(ctx context.Context, key string, delta uint64, opts ...OperationOption) (int64, error) {
+	return c.inner.increment(ctx, key, delta, opts...)
+}
+
+// Decrement (refer to Decrement of Cache interface)
+func (c *InMemoryCache) Decrement(ctx context.Context, key string, delta uint64, opts ...OperationOption) (int64, error) {
+	return c.inner.decrement(ctx, key, delta, opts...)
+}
+
+// Expire (refer to Expire of Cache interface)
+func (c *InMemoryCache) Expire(ctx context.Context, key string, expire time.Duration, opts ...OperationOption) error {
+	return c.inner.expire(ctx, key, expire, opts...)
+}
+
 // Load (refer to Load of Cache interface)
 func (c *InMemoryCache) Load(ctx context.Context, loader DataLoader, key string, receiver interface{}, expire time.Duration, opts ...OperationOption) error {
 	return c.inner.load(ctx, loader, key, receiver, expire, opts...)
@@ -75,6 +150,11 @@ func (c *InMemoryCache) Close(ctx context.Context) error {
 	return c.inner.close()
 }
 
+// HotKeys returns the detected hot keys in descending order of frequency, nil if hot key detection is disabled
+func (c *InMemoryCache) HotKeys() []HotKey {
+	return c.loadInner().hotKeyHandler.getHotKeys()
+}
+
 // UpdateConfig updates current in-memory cache based on config
 func (c *InMemoryCache) UpdateConfig(config InMemoryCacheConfig) error {
 	return c.inner.updateConfig(Config{
@@ -74,6 +74,22 @@ type InMemoryCacheConfig struct {
 	// ManufacturerConfig defines the behavior for `Load` and `LoadMany`
 	ManufacturerConfig ManufacturerConfig `yaml:"manufacturer_config" json:"manufacturer_config"`
 
+	// KeyConfig defines how keys are transformed before being sent to the cache e.g. namespacing
+	KeyConfig KeyConfig `yaml:"key_config" json:"key_config"`
+
+	// DisableConfig defines whether the cache is disabled, refer to DisableConfig for the behavior of operations
+	DisableConfig DisableConfig `yaml:"disable_config" json:"disable_config"`
+
+	// ObservationConfig defines how the operations of the cache are observed e.g. stats collection
+	ObservationConfig ObservationConfig `yaml:"observation_config" json:"observation_config"`
+
+	// HotKeyConfig defines the detection of hot keys, which are already in local memory thus not served otherwise
+	HotKeyConfig HotKeyConfig `yaml:"hot_key_config" json:"hot_key_config"`
+
+	// EnableKeyIndex defines whether the written keys are indexed, which is required by DeleteByPrefix and DeleteMatching.
+	// The index costs extra memory and a lock per write, and it can not be changed by UpdateConfig.
+	EnableKeyIndex bool `yaml:"enable_key_index" json:"enable_key_index"`
+
 	// RistrettoCacheConfig defines config for ristretto inmemory cache
 	// Only take effect when CacheType is Ristretto
 	RistrettoCacheConfig RistrettoCacheConfig `yaml:"ristretto_cache_config" json:"ristretto_cache_config"`
@@ -93,6 +109,15 @@ func (c InMemoryCacheConfig) Validate() error {
 	if err := c.RistrettoCacheConfig.Validate(); err != nil {
 		return err
 	}
+	if err := c.KeyConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.ObservationConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.HotKeyConfig.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -3,18 +3,28 @@ package cache
 import (
 	"context"
 	"go-eCache/internal/client/inmemory"
+	"hash/fnv"
+	"math"
 	"reflect"
 	"runtime"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
 )
 
+// inMemoryLockStripes is the number of locks guarding the read-modify-write operations of in-memory cache,
+// keys are spread over the locks by hash, so that operations on different keys rarely contend.
+const inMemoryLockStripes = 256
+
 // nolint:predeclared
 type inMemoryCacheClient interface {
 	Get(key string) (interface{}, bool)
+	GetWithTTL(key string) (interface{}, time.Duration, bool)
+	Contains(key string) bool
 	GetMany(keys ...string) []interface{}
-	Set(key string, value interface{}, expire time.Duration)
+	Set(key string, value interface{}, expire time.Duration) bool
 	SetMany(valueMap map[string]interface{}, expire time.Duration, expirationMap map[string]time.Duration)
 	Delete(key string) bool
 	DeleteMany(keys ...string)
@@ -27,8 +37,20 @@ type closableInMemoryCacheClient interface {
 }
 
 // inMemoryCacheInner is a wrapper of real in memory cache which impl innerCache Interface
+//
+// Add, replace, increment, decrement and expire are read-modify-write operations over the real in memory cache,
+// they are serialized per key by lock striping, and wait for the write to be applied before releasing the lock.
+// Set and delete do not take the lock, so they may interleave with these operations as in other cache backends.
+//
+// If keyIndex is enabled, the written keys are indexed, so that they can be deleted by prefix or pattern.
+// The keys written with tags are indexed by tagIndex, so that they can be deleted by tags.
+// The tags are locked while writing and indexing the tagged data, and while invalidating them, refer to inMemoryTagIndex.lock.
+// Keys are removed from the index before being deleted, so that a key concurrently set is never missing in the index.
 type inMemoryCacheInner struct {
 	cacheImpl unsafe.Pointer // of type *inMemoryCacheClient
+	locks     [inMemoryLockStripes]sync.Mutex
+	keyIndex  *inMemoryKeyIndex
+	tagIndex  *inMemoryTagIndex
 }
 
 func (c *inMemoryCacheInner) get(ctx context.Context, key string) (interface{}, error) {
@@ -61,6 +83,7 @@ func (c *inMemoryCacheInner) set(ctx context.Context, key string, value interfac
 	}
 
 	impl.Set(key, value, expire)
+	c.keyIndex.add(impl, key)
 
 	if options.waitRistretto {
 		impl.Wait()
@@ -77,6 +100,11 @@ func (c *inMemoryCacheInner) setMany(ctx context.Context, valueMap map[string]in
 
 	impl := c.loadInnerInMemoryCache()
 	impl.SetMany(valueMap, expire, options.expirationMap)
+	if c.keyIndex != nil {
+		for key := range valueMap {
+			c.keyIndex.add(impl, key)
+		}
+	}
 
 	if options.waitRistretto {
 		impl.Wait()
@@ -86,22 +114,46 @@ func (c *inMemoryCacheInner) setMany(ctx context.Context, valueMap map[string]in
 }
 
 func (c *inMemoryCacheInner) add(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...innerOperationOption) error {
-	return nil
-	//impl := c.loadInnerInMemoryCache()
-	//
-	//return impl.add(key, value, expire, opts...)
+	mu := c.lockKey(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	impl := c.loadInnerInMemoryCache()
+	if _, found := impl.Get(key); found {
+		return ErrNotStored
+	}
+	return c.setAndWait(impl, key, value, expire)
 }
 
 func (c *inMemoryCacheInner) replace(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...innerOperationOption) error {
-	return nil
-	//impl := c.loadInnerInMemoryCache()
-	//
-	//return impl.replace(key, value, expire, opts...)
+	mu := c.lockKey(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	impl := c.loadInnerInMemoryCache()
+	if _, found := impl.Get(key); !found {
+		return ErrNotStored
+	}
+	return c.setAndWait(impl, key, value, expire)
+}
+
+func (c *inMemoryCacheInner) compareAndSwap(ctx context.Context, key string, match func(value interface{}) bool, value interface{}, expire time.Duration, opts ...innerOperationOption) error {
+	mu := c.lockKey(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	impl := c.loadInnerInMemoryCache()
+	cur, found := impl.Get(key)
+	if !found || !match(cur) {
+		return ErrNotStored
+	}
+	return c.setAndWait(impl, key, value, expire)
 }
 
 // nolint:predeclared
 func (c *inMemoryCacheInner) delete(ctx context.Context, key string) error {
 	impl := c.loadInnerInMemoryCache()
+	c.keyIndex.remove(key)
 	ok := impl.Delete(key)
 
 	if !ok {
@@ -113,36 +165,149 @@ func (c *inMemoryCacheInner) delete(ctx context.Context, key string) error {
 func (c *inMemoryCacheInner) deleteMany(ctx context.Context, keys []string, opts ...innerOperationOption) error {
 	impl := c.loadInnerInMemoryCache()
 
+	c.keyIndex.remove(keys...)
 	impl.DeleteMany(keys...)
 
 	return nil
 }
 
+func (c *inMemoryCacheInner) deleteManyWithResult(ctx context.Context, keys []string) ([]bool, error) {
+	impl := c.loadInnerInMemoryCache()
+
+	c.keyIndex.remove(keys...)
+	existed := make([]bool, len(keys))
+	for idx, key := range keys {
+		existed[idx] = impl.Delete(key)
+	}
+
+	return existed, nil
+}
+
 func (c *inMemoryCacheInner) increment(ctx context.Context, key string, delta uint64, opts ...innerOperationOption) (int64, error) {
-	return 0, nil
-	//impl := c.loadInnerInMemoryCache()
-	//
-	//return impl.increment(key, delta, opts...)
+	if delta > math.MaxInt64 {
+		return 0, cacheErr("inmemory_delta_overflow")
+	}
+	return c.incrDecr(key, int64(delta), opts...)
 }
 
 func (c *inMemoryCacheInner) decrement(ctx context.Context, key string, delta uint64, opts ...innerOperationOption) (int64, error) {
-	return 0, nil
-	//impl := c.loadInnerInMemoryCache()
-	//
-	//return impl.decrement(key, delta, opts...)
+	if delta > math.MaxInt64 {
+		return 0, cacheErr("inmemory_delta_overflow")
+	}
+	return c.incrDecr(key, -int64(delta), opts...)
 }
 
+// incrDecr adds delta to the integer value of key, the remaining TTL of key is kept.
+// Same as Redis, a non-exist key is created with 0 before the operation if `initNonExistKey` is true,
+// and the result can be negative.
+func (c *inMemoryCacheInner) incrDecr(key string, delta int64, opts ...innerOperationOption) (int64, error) {
+	options := newInnerCacheOperationOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	mu := c.lockKey(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	impl := c.loadInnerInMemoryCache()
+	val, ttl, found := impl.GetWithTTL(key)
+	if !found {
+		if !options.initNonExistKey {
+			return 0, ErrCacheMiss
+		}
+		// new counter is stored as raw bytes as in other cache backends, it can be read with WithSkipEncoding
+		val = []byte("0")
+	}
+
+	num, err := parseInMemoryCounter(val)
+	if err != nil {
+		return 0, err
+	}
+	if (delta > 0 && num > math.MaxInt64-delta) || (delta < 0 && num < math.MinInt64-delta) {
+		return 0, cacheErr("inmemory_counter_overflow")
+	}
+	num += delta
+
+	// the counter is given a new version as any other write, so that the versions read before are no longer matched
+	if err = c.setAndWait(impl, key, renewInMemoryItemVersion(formatInMemoryCounter(val, num)), ttl); err != nil {
+		return 0, err
+	}
+
+	return num, nil
+}
+
+// expire resets the TTL of key, the header is kept if the value is an inMemoryItem, with the hard timeout updated
 func (c *inMemoryCacheInner) expire(ctx context.Context, key string, expire time.Duration, opts ...innerOperationOption) error {
-	return nil
-	//impl := c.loadInnerInMemoryCache()
-	//
-	//return impl.expire(key, expire, opts...)
+	mu := c.lockKey(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	impl := c.loadInnerInMemoryCache()
+	val, found := impl.Get(key)
+	if !found {
+		return ErrCacheMiss
+	}
+
+	if item, ok := val.(inMemoryItem); ok {
+		switch {
+		case expire > 0:
+			item.Header.HardTimeoutTs = time.Now().Add(expire).Unix()
+		case expire == NoExpiration:
+			item.Header.HardTimeoutTs = hardTimeoutForeverIndicator
+		default:
+			item.Header.HardTimeoutTs = 0
+		}
+		val = item
+	}
+	return c.setAndWait(impl, key, val, expire)
 }
 
 func (c *inMemoryCacheInner) ping(ctx context.Context) error {
 	return nil
 }
 
+// deleteMatching deletes the indexed keys satisfying match, and returns the number of keys existed
+func (c *inMemoryCacheInner) deleteMatching(ctx context.Context, match func(key string) bool) (int, error) {
+	if c.keyIndex == nil {
+		return 0, errInMemoryKeyIndexNotEnabled
+	}
+
+	existed, err := c.deleteManyWithResult(ctx, c.keyIndex.match(match))
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, ok := range existed {
+		if ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// indexTags indexes keys by each of tags
+func (c *inMemoryCacheInner) indexTags(tags []string, keys ...string) {
+	impl := c.loadInnerInMemoryCache()
+	for _, tag := range tags {
+		c.tagIndex.add(impl, tag, keys...)
+	}
+}
+
+// lockTags locks tags for writing and indexing the data tagged by them, and returns the function to unlock
+func (c *inMemoryCacheInner) lockTags(tags []string) (unlock func()) {
+	return c.tagIndex.lock(tags, false)
+}
+
+// invalidateTags deletes the keys indexed by tags
+func (c *inMemoryCacheInner) invalidateTags(ctx context.Context, tags []string) error {
+	unlock := c.tagIndex.lock(tags, true)
+	defer unlock()
+
+	return c.deleteMany(ctx, c.tagIndex.take(tags...))
+}
+
 // nolint:predeclared
 func (c *inMemoryCacheInner) close() error {
 	curImpl := c.loadInnerInMemoryCache()
@@ -156,6 +321,8 @@ func (c *inMemoryCacheInner) close() error {
 func (c *inMemoryCacheInner) flush(ctx context.Context) error {
 	impl := c.loadInnerInMemoryCache()
 
+	c.keyIndex.clear()
+	c.tagIndex.clear()
 	impl.Flush()
 
 	return nil
@@ -165,6 +332,94 @@ func (c *inMemoryCacheInner) rawClient() interface{} {
 	return nil
 }
 
+// lockKey returns the lock guarding key
+func (c *inMemoryCacheInner) lockKey(key string) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &c.locks[h.Sum32()%inMemoryLockStripes]
+}
+
+// setAndWait sets value and waits until it is visible, so that the following operation holding the same lock can see it.
+// Returns errInMemoryWriteDropped if the write is dropped or rejected by the admission policy of the real in memory cache.
+func (c *inMemoryCacheInner) setAndWait(impl inMemoryCacheClient, key string, value interface{}, expire time.Duration) error {
+	if expire == NoExpiration {
+		expire = 0
+	}
+	if !impl.Set(key, value, expire) {
+		return errInMemoryWriteDropped
+	}
+	c.keyIndex.add(impl, key)
+	impl.Wait()
+
+	if !impl.Contains(key) {
+		return errInMemoryWriteDropped
+	}
+	return nil
+}
+
+// parseInMemoryCounter parses the integer value of a counter, which can be wrapped by an inMemoryItem,
+// in bytes or string (e.g. set by codec or created by incrDecr) or of integer kinds (e.g. set with WithSkipCodec).
+func parseInMemoryCounter(val interface{}) (int64, error) {
+	switch v := val.(type) {
+	case inMemoryItem:
+		return parseInMemoryCounter(v.Val)
+	case []byte:
+		return parseInMemoryCounterString(string(v))
+	case string:
+		return parseInMemoryCounterString(v)
+	}
+
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, cacheErr("inmemory_counter_overflow")
+		}
+		return int64(rv.Uint()), nil
+	default:
+		return 0, cacheErr("inmemory_value_is_not_integer")
+	}
+}
+
+func parseInMemoryCounterString(s string) (int64, error) {
+	num, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, cacheErr("inmemory_value_is_not_integer")
+	}
+	return num, nil
+}
+
+// formatInMemoryCounter formats num in the same form as the original value val
+func formatInMemoryCounter(val interface{}, num int64) interface{} {
+	switch v := val.(type) {
+	case inMemoryItem:
+		v.Val = formatInMemoryCounter(v.Val, num)
+		return v
+	case []byte:
+		return []byte(strconv.FormatInt(num, 10))
+	case string:
+		return strconv.FormatInt(num, 10)
+	}
+
+	// fall back to int64 if num can not be kept in the original integer kind, e.g. a negative result of an unsigned counter
+	converted := reflect.ValueOf(num).Convert(reflect.TypeOf(val)).Interface()
+	if n, err := parseInMemoryCounter(converted); err != nil || n != num {
+		return num
+	}
+	return converted
+}
+
+// renewInMemoryItemVersion generates a new version for val if it is a versioned inMemoryItem
+func renewInMemoryItemVersion(val interface{}) interface{} {
+	if item, ok := val.(inMemoryItem); ok && item.Header.Version != 0 {
+		item.Header.Version = genItemVersion()
+		return item
+	}
+	return val
+}
+
 func (c *inMemoryCacheInner) loadInnerInMemoryCache() inMemoryCacheClient {
 	inner := *(*inMemoryCacheClient)(atomic.LoadPointer(&c.cacheImpl))
 
@@ -172,13 +427,19 @@ func (c *inMemoryCacheInner) loadInnerInMemoryCache() inMemoryCacheClient {
 }
 
 func (c *inMemoryCacheInner) updateConfig(newConfig InMemoryCacheConfig) error {
+	if newConfig.EnableKeyIndex != (c.keyIndex != nil) {
+		return errInMemoryKeyIndexNotUpdatable
+	}
+
+	// compare with the defaulted config as the current implementation is created with, so that the cached data is kept
+	// if only the other configs e.g. DisableConfig are updated
+	ristrettoConfig := newConfig.RistrettoCacheConfig
+	ristrettoConfig.setDefaultValue()
 
-	if needCreateNewRistrettoCache(c, newConfig.RistrettoCacheConfig) {
+	if needCreateNewRistrettoCache(c, ristrettoConfig) {
 		return replaceCurImplWithNewRistrettoCache(c, newConfig)
 	}
-	return updateCurImplRistrettoMaxCost(c, newConfig.RistrettoCacheConfig.Capacity)
-
-	return nil
+	return updateCurImplRistrettoMaxCost(c, ristrettoConfig.Capacity)
 }
 
 func updateCurImplRistrettoMaxCost(c *inMemoryCacheInner, newCapacity int64) error {
@@ -209,6 +470,8 @@ func replaceCurImplWithNewRistrettoCache(c *inMemoryCacheInner, cfg InMemoryCach
 	}
 
 	atomic.StorePointer(&c.cacheImpl, unsafe.Pointer(&newImpl))
+	c.keyIndex.clear()
+	c.tagIndex.clear()
 
 	return nil
 }
@@ -239,6 +502,10 @@ func newInMemoryCache(config InMemoryCacheConfig) (c *inMemoryCacheInner, err er
 
 	inner := &inMemoryCacheInner{
 		cacheImpl: unsafe.Pointer(&impl),
+		tagIndex:  newInMemoryTagIndex(),
+	}
+	if config.EnableKeyIndex {
+		inner.keyIndex = newInMemoryKeyIndex()
 	}
 
 	runtime.SetFinalizer(inner, stopInMemoryCache)
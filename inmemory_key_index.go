@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// inMemoryKeyIndexMinPruneSize is the min number of keys of a shard to prune it
+	inMemoryKeyIndexMinPruneSize = 1024
+	// inMemoryKeyIndexPruneGracePeriod is the period after the last write of a key, within which the key is not pruned
+	// even if it is not found, as the writes to ristretto are applied asynchronously
+	inMemoryKeyIndexPruneGracePeriod = time.Second
+)
+
+// inMemoryKeyIndex indexes the keys written to the in-memory cache, so that keys can be looked up by prefix or pattern.
+// A nil *inMemoryKeyIndex means the key index is disabled.
+//
+// Keys expired or evicted by the in-memory cache are not removed from the index immediately,
+// instead a shard of the index is pruned once its size doubles since the last pruning.
+type inMemoryKeyIndex struct {
+	shards [inMemoryLockStripes]inMemoryKeyIndexShard
+}
+
+type inMemoryKeyIndexShard struct {
+	mu        sync.Mutex
+	keys      map[string]int64 // indexed keys and their last write time in unix nanoseconds
+	pruneSize int              // the shard is pruned once the number of keys reaches pruneSize
+}
+
+func newInMemoryKeyIndex() *inMemoryKeyIndex {
+	idx := &inMemoryKeyIndex{}
+	for i := range idx.shards {
+		idx.shards[i].keys = make(map[string]int64)
+		idx.shards[i].pruneSize = inMemoryKeyIndexMinPruneSize
+	}
+	return idx
+}
+
+// add indexes keys, impl is used to check the existence of keys when pruning
+func (idx *inMemoryKeyIndex) add(impl inMemoryCacheClient, keys ...string) {
+	if idx == nil {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	for _, key := range keys {
+		shard := idx.shard(key)
+		shard.mu.Lock()
+		shard.keys[key] = now
+		if len(shard.keys) >= shard.pruneSize {
+			shard.prune(impl, now)
+		}
+		shard.mu.Unlock()
+	}
+}
+
+// remove removes keys from the index
+func (idx *inMemoryKeyIndex) remove(keys ...string) {
+	if idx == nil {
+		return
+	}
+
+	for _, key := range keys {
+		shard := idx.shard(key)
+		shard.mu.Lock()
+		delete(shard.keys, key)
+		shard.mu.Unlock()
+	}
+}
+
+// clear removes all keys from the index
+func (idx *inMemoryKeyIndex) clear() {
+	if idx == nil {
+		return
+	}
+
+	for i := range idx.shards {
+		shard := &idx.shards[i]
+		shard.mu.Lock()
+		shard.keys = make(map[string]int64)
+		shard.pruneSize = inMemoryKeyIndexMinPruneSize
+		shard.mu.Unlock()
+	}
+}
+
+// match returns the indexed keys satisfying match
+func (idx *inMemoryKeyIndex) match(match func(key string) bool) []string {
+	var keys []string
+	for i := range idx.shards {
+		shard := &idx.shards[i]
+		shard.mu.Lock()
+		for key := range shard.keys {
+			if match(key) {
+				keys = append(keys, key)
+			}
+		}
+		shard.mu.Unlock()
+	}
+	return keys
+}
+
+func (idx *inMemoryKeyIndex) shard(key string) *inMemoryKeyIndexShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &idx.shards[h.Sum32()%inMemoryLockStripes]
+}
+
+// prune removes the keys no longer in the in-memory cache. It must be called with s.mu held.
+func (s *inMemoryKeyIndexShard) prune(impl inMemoryCacheClient, now int64) {
+	for key, writeTime := range s.keys {
+		if now-writeTime > int64(inMemoryKeyIndexPruneGracePeriod) && !impl.Contains(key) {
+			delete(s.keys, key)
+		}
+	}
+
+	s.pruneSize = 2 * len(s.keys)
+	if s.pruneSize < inMemoryKeyIndexMinPruneSize {
+		s.pruneSize = inMemoryKeyIndexMinPruneSize
+	}
+}
+
+// inMemoryTagIndex indexes the keys of tags written to the in-memory cache, so that the keys of tags can be invalidated.
+// Keys expired or evicted are pruned lazily in the same way as inMemoryKeyIndex.
+type inMemoryTagIndex struct {
+	shards [inMemoryLockStripes]inMemoryTagIndexShard
+}
+
+type inMemoryTagIndexShard struct {
+	tagMu     sync.RWMutex // held shared by the writes of tagged data and exclusively by invalidation, refer to lock
+	mu        sync.Mutex
+	tags      map[string]map[string]int64 // indexed tags and their keys with the last write time in unix nanoseconds
+	size      int                         // the total number of keys of all tags
+	pruneSize int                         // the shard is pruned once size reaches pruneSize
+}
+
+func newInMemoryTagIndex() *inMemoryTagIndex {
+	idx := &inMemoryTagIndex{}
+	for i := range idx.shards {
+		idx.shards[i].tags = make(map[string]map[string]int64)
+		idx.shards[i].pruneSize = inMemoryKeyIndexMinPruneSize
+	}
+	return idx
+}
+
+// add indexes keys by tag, impl is used to check the existence of keys when pruning
+func (idx *inMemoryTagIndex) add(impl inMemoryCacheClient, tag string, keys ...string) {
+	now := time.Now().UnixNano()
+	shard := idx.shard(tag)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	tagKeys, ok := shard.tags[tag]
+	if !ok {
+		tagKeys = make(map[string]int64, len(keys))
+		shard.tags[tag] = tagKeys
+	}
+	for _, key := range keys {
+		if _, ok := tagKeys[key]; !ok {
+			shard.size++
+		}
+		tagKeys[key] = now
+	}
+	if shard.size >= shard.pruneSize {
+		shard.prune(impl, now)
+	}
+}
+
+// lock locks the shards of tags, and returns the function to unlock them.
+// The writes of tagged data hold the lock shared until the keys are indexed,
+// and invalidation holds it exclusively until the keys taken are deleted,
+// so that the data written concurrently with invalidation are either deleted by it or written after it.
+func (idx *inMemoryTagIndex) lock(tags []string, exclusive bool) (unlock func()) {
+	// the shards are locked in order and each only once to avoid deadlocks
+	var shards []int
+	seen := make(map[int]struct{}, len(tags))
+	for _, tag := range tags {
+		i := idx.shardIndex(tag)
+		if _, ok := seen[i]; !ok {
+			seen[i] = struct{}{}
+			shards = append(shards, i)
+		}
+	}
+	sort.Ints(shards)
+
+	for _, i := range shards {
+		if exclusive {
+			idx.shards[i].tagMu.Lock()
+		} else {
+			idx.shards[i].tagMu.RLock()
+		}
+	}
+	return func() {
+		for _, i := range shards {
+			if exclusive {
+				idx.shards[i].tagMu.Unlock()
+			} else {
+				idx.shards[i].tagMu.RUnlock()
+			}
+		}
+	}
+}
+
+// take removes tags from the index, and returns the keys of them
+func (idx *inMemoryTagIndex) take(tags ...string) []string {
+	var keys []string
+	for _, tag := range tags {
+		shard := idx.shard(tag)
+		shard.mu.Lock()
+		for key := range shard.tags[tag] {
+			keys = append(keys, key)
+		}
+		shard.size -= len(shard.tags[tag])
+		delete(shard.tags, tag)
+		shard.mu.Unlock()
+	}
+	return keys
+}
+
+// clear removes all tags from the index
+func (idx *inMemoryTagIndex) clear() {
+	for i := range idx.shards {
+		shard := &idx.shards[i]
+		shard.mu.Lock()
+		shard.tags = make(map[string]map[string]int64)
+		shard.size = 0
+		shard.pruneSize = inMemoryKeyIndexMinPruneSize
+		shard.mu.Unlock()
+	}
+}
+
+func (idx *inMemoryTagIndex) shard(tag string) *inMemoryTagIndexShard {
+	return &idx.shards[idx.shardIndex(tag)]
+}
+
+func (idx *inMemoryTagIndex) shardIndex(tag string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(tag))
+	return int(h.Sum32() % inMemoryLockStripes)
+}
+
+// prune removes the keys no longer in the in-memory cache and the tags without keys. It must be called with s.mu held.
+func (s *inMemoryTagIndexShard) prune(impl inMemoryCacheClient, now int64) {
+	for tag, tagKeys := range s.tags {
+		for key, writeTime := range tagKeys {
+			if now-writeTime > int64(inMemoryKeyIndexPruneGracePeriod) && !impl.Contains(key) {
+				delete(tagKeys, key)
+				s.size--
+			}
+		}
+		if len(tagKeys) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+
+	s.pruneSize = 2 * s.size
+	if s.pruneSize < inMemoryKeyIndexMinPruneSize {
+		s.pruneSize = inMemoryKeyIndexMinPruneSize
+	}
+}
@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func newTestInMemoryCache(t *testing.T) *InMemoryCache {
+	c, err := NewInMemoryCache("test_inmemory", InMemoryCacheConfig{CacheType: Ristretto})
+	if err != nil {
+		t.Fatalf("new in-memory cache err: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+
+	return c
+}
+
+func TestInMemoryCacheConditionalOperations(t *testing.T) {
+	ctx := context.Background()
+	c := newTestInMemoryCache(t)
+
+	if err := c.Replace(ctx, "k", "v1", time.Minute); err != ErrNotStored {
+		t.Fatalf("expect not stored on replacing non-exist key, got: %v", err)
+	}
+	if err := c.Add(ctx, "k", "v1", time.Minute); err != nil {
+		t.Fatalf("add err: %v", err)
+	}
+	if err := c.Add(ctx, "k", "v2", time.Minute); err != ErrNotStored {
+		t.Fatalf("expect not stored on adding exist key, got: %v", err)
+	}
+	if err := c.Replace(ctx, "k", "v3", time.Minute); err != nil {
+		t.Fatalf("replace err: %v", err)
+	}
+	var val string
+	if err := c.Get(ctx, "k", &val); err != nil || val != "v3" {
+		t.Fatalf("unexpected value %v, err: %v", val, err)
+	}
+
+	if err := c.Expire(ctx, "k", time.Hour, WithSkipEncoding()); err != nil {
+		t.Fatalf("expire err: %v", err)
+	}
+	if header := getLayerHeader(t, c, "k"); header.HardTimeoutTs-time.Now().Unix() < 3590 {
+		t.Fatalf("expect hard timeout updated in header, got: %+v", header)
+	}
+	if err := c.Expire(ctx, "k", 50*time.Millisecond, WithSkipEncoding()); err != nil {
+		t.Fatalf("expire err: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := c.Get(ctx, "k", &val); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss after expiration, got: %v", err)
+	}
+	if err := c.Expire(ctx, "k", time.Minute, WithSkipEncoding()); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss on expiring non-exist key, got: %v", err)
+	}
+}
+
+func TestInMemoryCacheCounter(t *testing.T) {
+	ctx := context.Background()
+	c := newTestInMemoryCache(t)
+
+	if _, err := c.Increment(ctx, "counter", 1, WithInitNonExistKey(false)); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss on incrementing non-exist key, got: %v", err)
+	}
+	if n, err := c.Increment(ctx, "counter", 5); err != nil || n != 5 {
+		t.Fatalf("unexpected increment result %v, err: %v", n, err)
+	}
+	if n, err := c.Decrement(ctx, "counter", 2); err != nil || n != 3 {
+		t.Fatalf("unexpected decrement result %v, err: %v", n, err)
+	}
+	var counter int64
+	if err := c.Get(ctx, "counter", &counter, WithSkipEncoding()); err != nil || counter != 3 {
+		t.Fatalf("unexpected counter %v, err: %v", counter, err)
+	}
+
+	// counter set through codec keeps its header
+	if err := c.Set(ctx, "set_counter", 10, time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	version, err := c.GetWithVersion(ctx, "set_counter", &counter)
+	if err != nil || version == 0 {
+		t.Fatalf("expect counter with version, got: %v, err: %v", version, err)
+	}
+	if n, err := c.Increment(ctx, "set_counter", 1); err != nil || n != 11 {
+		t.Fatalf("unexpected increment result %v, err: %v", n, err)
+	}
+	if err := c.Get(ctx, "set_counter", &counter); err != nil || counter != 11 {
+		t.Fatalf("unexpected counter %v, err: %v", counter, err)
+	}
+	if err := c.CompareAndSwap(ctx, "set_counter", 0, version, time.Minute); err != ErrNotStored {
+		t.Fatalf("expect not stored with the version before increment, got: %v", err)
+	}
+
+	if err := c.Set(ctx, "not_counter", "v", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	if _, err := c.Increment(ctx, "not_counter", 1); err == nil {
+		t.Fatalf("expect error on incrementing non-integer value")
+	}
+
+	var wg sync.WaitGroup
+	added := make(chan struct{}, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Increment(ctx, "concurrent_counter", 1); err != nil {
+				t.Errorf("increment err: %v", err)
+			}
+			if err := c.Add(ctx, "concurrent_add", "v", time.Minute); err == nil {
+				added <- struct{}{}
+			}
+		}()
+	}
+	wg.Wait()
+	if len(added) != 1 {
+		t.Fatalf("expect exactly one add to succeed, got %v", len(added))
+	}
+	if n, err := c.Increment(ctx, "concurrent_counter", 0); err != nil || n != 20 {
+		t.Fatalf("unexpected counter %v, err: %v", n, err)
+	}
+}
+
+// droppingInMemoryClient drops all writes, as Ristretto may do due to contention of the buffer
+type droppingInMemoryClient struct {
+	inMemoryCacheClient
+}
+
+func (c droppingInMemoryClient) Set(key string, value interface{}, expire time.Duration) bool {
+	return false
+}
+
+func TestInMemoryCacheWriteDropped(t *testing.T) {
+	ctx := context.Background()
+	inner, err := newInMemoryCache(InMemoryCacheConfig{CacheType: Ristretto})
+	if err != nil {
+		t.Fatalf("new in-memory cache err: %v", err)
+	}
+	if err = inner.set(ctx, "k", []byte("1"), time.Minute, withWaitRistretto(true)); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+
+	impl := inner.loadInnerInMemoryCache()
+	defer impl.(closableInMemoryCacheClient).Close()
+	var droppingImpl inMemoryCacheClient = droppingInMemoryClient{impl}
+	atomic.StorePointer(&inner.cacheImpl, unsafe.Pointer(&droppingImpl))
+
+	if err = inner.add(ctx, "not_exist", []byte("v"), time.Minute); err != errInMemoryWriteDropped {
+		t.Fatalf("expect write dropped on add, got: %v", err)
+	}
+	if err = inner.replace(ctx, "k", []byte("v"), time.Minute); err != errInMemoryWriteDropped {
+		t.Fatalf("expect write dropped on replace, got: %v", err)
+	}
+	if _, err = inner.increment(ctx, "k", 1); err != errInMemoryWriteDropped {
+		t.Fatalf("expect write dropped on increment, got: %v", err)
+	}
+	if err = inner.expire(ctx, "k", time.Hour); err != errInMemoryWriteDropped {
+		t.Fatalf("expect write dropped on expire, got: %v", err)
+	}
+}
+
+func TestInMemoryCacheDisable(t *testing.T) {
+	ctx := context.Background()
+	c := newTestInMemoryCache(t)
+
+	if err := c.Set(ctx, "k", "v", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	if err := c.UpdateConfig(InMemoryCacheConfig{CacheType: Ristretto, DisableConfig: DisableConfig{Disable: true}}); err != nil {
+		t.Fatalf("update config err: %v", err)
+	}
+
+	var val string
+	if err := c.Get(ctx, "k", &val); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss when disabled, got: %v", err)
+	}
+	if err := c.Set(ctx, "k", "v2", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("expect set skipped when disabled, got: %v", err)
+	}
+	if err := c.Add(ctx, "k2", "v2", time.Minute); err != ErrNotStored {
+		t.Fatalf("expect not stored on add when disabled, got: %v", err)
+	}
+	if err := c.Replace(ctx, "k", "v2", time.Minute); err != ErrNotStored {
+		t.Fatalf("expect not stored on replace when disabled, got: %v", err)
+	}
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return []interface{}{"loaded"}, nil
+	}
+	if err := c.Load(ctx, loader, "k", &val, time.Minute, WithWaitRistretto()); err != nil || val != "loaded" {
+		t.Fatalf("expect load falling through to loader when disabled, got: %v, err: %v", val, err)
+	}
+
+	if err := c.UpdateConfig(InMemoryCacheConfig{CacheType: Ristretto}); err != nil {
+		t.Fatalf("update config err: %v", err)
+	}
+	if err := c.Get(ctx, "k", &val); err != nil || val != "v" {
+		t.Fatalf("expect data kept after re-enabled, got: %v, err: %v", val, err)
+	}
+}
+
+func TestInMemoryCacheDeleteByPrefix(t *testing.T) {
+	ctx := context.Background()
+	config := InMemoryCacheConfig{CacheType: Ristretto, EnableKeyIndex: true, KeyConfig: KeyConfig{Namespace: "ns"}}
+	c, err := NewInMemoryCache("test_inmemory", config)
+	if err != nil {
+		t.Fatalf("new in-memory cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	valueMap := map[string]interface{}{"user:1": 1, "user:2": 2, "order:1": 1, "order:x": 0}
+	if err := c.SetMany(ctx, valueMap, time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set many err: %v", err)
+	}
+	if n, err := c.DeleteByPrefix(ctx, "user:"); err != nil || n != 2 {
+		t.Fatalf("expect 2 keys deleted by prefix, got: %v, err: %v", n, err)
+	}
+	if n, err := c.DeleteMatching(ctx, `^order:\d+$`); err != nil || n != 1 {
+		t.Fatalf("expect 1 key deleted by pattern, got: %v, err: %v", n, err)
+	}
+	var val int
+	for key, expectedErr := range map[string]error{"user:1": ErrCacheMiss, "user:2": ErrCacheMiss, "order:1": ErrCacheMiss, "order:x": nil} {
+		if err := c.Get(ctx, key, &val); err != expectedErr {
+			t.Fatalf("expect %v on getting %v, got: %v", expectedErr, key, err)
+		}
+	}
+
+	// deleted keys are removed from the index
+	if n, err := c.DeleteByPrefix(ctx, "user:"); err != nil || n != 0 {
+		t.Fatalf("expect no key deleted again, got: %v, err: %v", n, err)
+	}
+	if _, err := c.DeleteMatching(ctx, "("); err != errKeyPatternCompile {
+		t.Fatalf("expect pattern compile error, got: %v", err)
+	}
+	config.EnableKeyIndex = false
+	if err := c.UpdateConfig(config); err != errInMemoryKeyIndexNotUpdatable {
+		t.Fatalf("expect key index not updatable, got: %v", err)
+	}
+	if _, err := newTestInMemoryCache(t).DeleteByPrefix(ctx, "user:"); err != errInMemoryKeyIndexNotEnabled {
+		t.Fatalf("expect key index not enabled, got: %v", err)
+	}
+}
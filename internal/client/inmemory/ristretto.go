@@ -49,6 +49,27 @@ func (c *RistrettoCache) Get(key string) (interface{}, bool) {
 	return c.inner.Get(key)
 }
 
+// GetWithTTL retrieves an item from the cache with its remaining time to live, 0 means the item never expires.
+// Returns true if the cache key exists, returns false otherwise
+func (c *RistrettoCache) GetWithTTL(key string) (interface{}, time.Duration, bool) {
+	val, found := c.inner.Get(key)
+	if !found {
+		return nil, 0, false
+	}
+	ttl, found := c.inner.GetTTL(key)
+	if !found {
+		return nil, 0, false
+	}
+
+	return val, ttl, true
+}
+
+// Contains returns true if the cache key exists, without counting as an access of the key
+func (c *RistrettoCache) Contains(key string) bool {
+	_, found := c.inner.GetTTL(key)
+	return found
+}
+
 // GetMany retrieves multiple items from the cache.
 // If a key does not exist, a `nil` will be returned.
 func (c *RistrettoCache) GetMany(keys ...string) []interface{} {
@@ -64,19 +85,21 @@ func (c *RistrettoCache) GetMany(keys ...string) []interface{} {
 	return res
 }
 
-func (c *RistrettoCache) setInner(key string, value interface{}, expire time.Duration) {
+func (c *RistrettoCache) setInner(key string, value interface{}, expire time.Duration) bool {
 	cost := int64(0) // because we use the CostFunc, and ristretto only call CostFunc if cost of item is 0.
 
 	// 0 make ristretto cache no expire, but -1 (NoExpiration) will do no-op
 	if expire <= 0 {
 		expire = 0
 	}
-	_ = c.inner.SetWithTTL(key, value, cost, expire)
+	return c.inner.SetWithTTL(key, value, cost, expire)
 }
 
 // Set sets an item to the cache, replacing any existing item.
-func (c *RistrettoCache) Set(key string, value interface{}, expire time.Duration) {
-	c.setInner(key, value, expire)
+// Returns false if the item is dropped (e.g. due to contention of the buffer), returns true otherwise,
+// though the item can still be rejected by the admission policy after it is buffered.
+func (c *RistrettoCache) Set(key string, value interface{}, expire time.Duration) bool {
+	return c.setInner(key, value, expire)
 }
 
 // SetMany sets multiple items to the cache, replacing any existing items.
@@ -0,0 +1,661 @@
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCacheMiss means that a Get failed because the item wasn't present.
+	ErrCacheMiss = errors.New("cache:memcached: cache miss")
+
+	// ErrNotStored means that a conditional write operation (i.e. Add) failed because the condition was not satisfied.
+	ErrNotStored = errors.New("cache:memcached: item not stored")
+
+	// ErrCASConflict means that a CompareAndSwap call failed due to the cached value being modified between the Get and the CompareAndSwap.
+	ErrCASConflict = errors.New("cache:memcached: compare-and-swap conflict")
+
+	// ErrClientClosed means that the client has been closed
+	ErrClientClosed = errors.New("cache:memcached: client closed")
+
+	// ErrNoServers is returned when no servers are configured
+	ErrNoServers = errors.New("cache:memcached: no servers configured")
+
+	// ErrMalformedKey is returned when an invalid key is used.
+	// Keys must be at maximum 250 bytes long and not contain whitespace or control characters.
+	ErrMalformedKey = errors.New("cache:memcached: key is too long or contains invalid characters")
+)
+
+// maxKeyLength is the maximum length of a key accepted by memcached
+const maxKeyLength = 250
+
+var (
+	crlf            = []byte("\r\n")
+	resultStored    = []byte("STORED\r\n")
+	resultNotStored = []byte("NOT_STORED\r\n")
+	resultExists    = []byte("EXISTS\r\n")
+	resultNotFound  = []byte("NOT_FOUND\r\n")
+	resultDeleted   = []byte("DELETED\r\n")
+	resultTouched   = []byte("TOUCHED\r\n")
+	resultOK        = []byte("OK\r\n")
+	resultEnd       = []byte("END\r\n")
+	prefixValue     = []byte("VALUE ")
+	prefixVersion   = []byte("VERSION ")
+)
+
+// Item is an item to be got or stored in a memcached server
+type Item struct {
+	Key   string
+	Value []byte
+	Flags uint32
+
+	// Expiration is the cache expiration time in seconds, zero means no expiration.
+	// Relative time is up to 30 days, larger value is treated as an absolute unix timestamp.
+	Expiration int32
+
+	// CasID is the compare-and-swap token, populated by Get
+	CasID uint64
+}
+
+// Config defines config used to build a memcached Client
+type Config struct {
+	// Addresses is the list of `host:port` of memcached servers, keys are distributed by crc32 hash
+	Addresses []string
+	// MaxIdle is the maximum number of idle connections kept for each server
+	MaxIdle int
+	// DialTimeout is the timeout for establishing new connections
+	DialTimeout time.Duration
+	// Timeout is the read/write timeout of a single request, the deadline of ctx takes effect if it is earlier
+	Timeout time.Duration
+}
+
+// Client is a memcached client which is safe for concurrent use
+type Client struct {
+	config Config
+
+	mu     sync.Mutex
+	idle   map[string][]*conn
+	closed bool
+}
+
+// NewClient creates a new Client, connections are dialed lazily
+func NewClient(config Config) *Client {
+	return &Client{
+		config: config,
+		idle:   make(map[string][]*conn),
+	}
+}
+
+type conn struct {
+	netConn net.Conn
+	rw      *bufio.ReadWriter
+	addr    string
+}
+
+// Addresses returns the addresses of servers
+func (c *Client) Addresses() []string {
+	return c.config.Addresses
+}
+
+func (c *Client) pickServer(key string) (string, error) {
+	switch len(c.config.Addresses) {
+	case 0:
+		return "", ErrNoServers
+	case 1:
+		return c.config.Addresses[0], nil
+	}
+	idx := crc32.ChecksumIEEE([]byte(key)) % uint32(len(c.config.Addresses))
+	return c.config.Addresses[idx], nil
+}
+
+func (c *Client) getConn(ctx context.Context, addr string) (*conn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClientClosed
+	}
+	if idle := c.idle[addr]; len(idle) > 0 {
+		cn := idle[len(idle)-1]
+		c.idle[addr] = idle[:len(idle)-1]
+		c.mu.Unlock()
+		return cn, nil
+	}
+	c.mu.Unlock()
+
+	dialer := net.Dialer{Timeout: c.config.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{
+		netConn: netConn,
+		rw:      bufio.NewReadWriter(bufio.NewReader(netConn), bufio.NewWriter(netConn)),
+		addr:    addr,
+	}, nil
+}
+
+func (c *Client) putConn(cn *conn, err error) {
+	if err != nil && !isResumableError(err) {
+		_ = cn.netConn.Close()
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || len(c.idle[cn.addr]) >= c.config.MaxIdle {
+		_ = cn.netConn.Close()
+		return
+	}
+	c.idle[cn.addr] = append(c.idle[cn.addr], cn)
+}
+
+// isResumableError reports whether the connection can be reused after err occurred
+func isResumableError(err error) bool {
+	switch err {
+	case ErrCacheMiss, ErrNotStored, ErrCASConflict, ErrMalformedKey:
+		return true
+	}
+	return false
+}
+
+// withConn gets a connection for addr, sets the deadline and runs f
+func (c *Client) withConn(ctx context.Context, addr string, f func(rw *bufio.ReadWriter) error) error {
+	cn, err := c.getConn(ctx, addr)
+	if err != nil {
+		return err
+	}
+
+	var deadline time.Time
+	if c.config.Timeout > 0 {
+		deadline = time.Now().Add(c.config.Timeout)
+	}
+	if ctxDeadline, ok := ctx.Deadline(); ok && (deadline.IsZero() || ctxDeadline.Before(deadline)) {
+		deadline = ctxDeadline
+	}
+	if err = cn.netConn.SetDeadline(deadline); err != nil {
+		c.putConn(cn, err)
+		return err
+	}
+
+	err = f(cn.rw)
+	c.putConn(cn, err)
+
+	return err
+}
+
+// Get gets items for keys, keys not cached are absent in the returned map
+func (c *Client) Get(ctx context.Context, keys ...string) (map[string]*Item, error) {
+	keysByAddr := make(map[string][]string)
+	for _, key := range keys {
+		if !legalKey(key) {
+			return nil, ErrMalformedKey
+		}
+		addr, err := c.pickServer(key)
+		if err != nil {
+			return nil, err
+		}
+		keysByAddr[addr] = append(keysByAddr[addr], key)
+	}
+
+	items := make(map[string]*Item, len(keys))
+	for addr, addrKeys := range keysByAddr {
+		err := c.withConn(ctx, addr, func(rw *bufio.ReadWriter) error {
+			if _, err := fmt.Fprintf(rw, "gets %s\r\n", strings.Join(addrKeys, " ")); err != nil {
+				return err
+			}
+			if err := rw.Flush(); err != nil {
+				return err
+			}
+			return parseGetResponse(rw.Reader, items)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+func parseGetResponse(r *bufio.Reader, items map[string]*Item) error {
+	for {
+		line, err := r.ReadSlice('\n')
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(line, resultEnd) {
+			return nil
+		}
+		if !bytes.HasPrefix(line, prefixValue) {
+			return fmt.Errorf("cache:memcached: unexpected line in get response: %q", line)
+		}
+
+		fields := strings.Fields(string(line[len(prefixValue):]))
+		if len(fields) < 3 {
+			return fmt.Errorf("cache:memcached: unexpected line in get response: %q", line)
+		}
+		item := &Item{Key: fields[0]}
+		flags, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			return err
+		}
+		item.Flags = uint32(flags)
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return err
+		}
+		if len(fields) > 3 {
+			if item.CasID, err = strconv.ParseUint(fields[3], 10, 64); err != nil {
+				return err
+			}
+		}
+
+		value := make([]byte, size+2)
+		if _, err = readFull(r, value); err != nil {
+			return err
+		}
+		if !bytes.HasSuffix(value, crlf) {
+			return fmt.Errorf("cache:memcached: corrupt get response")
+		}
+		item.Value = value[:size]
+		items[item.Key] = item
+	}
+}
+
+// Set writes the given item, unconditionally
+func (c *Client) Set(ctx context.Context, item *Item) error {
+	return c.store(ctx, "set", item)
+}
+
+// Add writes the given item, if no value already exists for its key. ErrNotStored is returned if that condition is not met.
+func (c *Client) Add(ctx context.Context, item *Item) error {
+	return c.store(ctx, "add", item)
+}
+
+// Replace writes the given item, but only if the server does already hold data for this key
+func (c *Client) Replace(ctx context.Context, item *Item) error {
+	return c.store(ctx, "replace", item)
+}
+
+// CompareAndSwap writes the given item that was previously returned by Get,
+// if the value was neither modified or evicted between the Get and the CompareAndSwap calls.
+// ErrCASConflict is returned if the value was modified in between, ErrNotStored is returned if the value was evicted in between.
+func (c *Client) CompareAndSwap(ctx context.Context, item *Item) error {
+	return c.store(ctx, "cas", item)
+}
+
+func (c *Client) store(ctx context.Context, verb string, item *Item) error {
+	if !legalKey(item.Key) {
+		return ErrMalformedKey
+	}
+	addr, err := c.pickServer(item.Key)
+	if err != nil {
+		return err
+	}
+
+	return c.withConn(ctx, addr, func(rw *bufio.ReadWriter) error {
+		if err := writeStorageCommand(rw, verb, item, false); err != nil {
+			return err
+		}
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		line, err := rw.ReadSlice('\n')
+		if err != nil {
+			return err
+		}
+		switch {
+		case bytes.Equal(line, resultStored):
+			return nil
+		case bytes.Equal(line, resultNotStored):
+			return ErrNotStored
+		case bytes.Equal(line, resultExists):
+			return ErrCASConflict
+		case bytes.Equal(line, resultNotFound):
+			return ErrNotStored
+		}
+		return fmt.Errorf("cache:memcached: unexpected response line from %q: %q", verb, line)
+	})
+}
+
+func writeStorageCommand(w *bufio.ReadWriter, verb string, item *Item, noReply bool) error {
+	var err error
+	if verb == "cas" {
+		_, err = fmt.Fprintf(w, "%s %s %d %d %d %d", verb, item.Key, item.Flags, item.Expiration, len(item.Value), item.CasID)
+	} else {
+		_, err = fmt.Fprintf(w, "%s %s %d %d %d", verb, item.Key, item.Flags, item.Expiration, len(item.Value))
+	}
+	if err != nil {
+		return err
+	}
+	if noReply {
+		if _, err = w.WriteString(" noreply"); err != nil {
+			return err
+		}
+	}
+	if _, err = w.Write(crlf); err != nil {
+		return err
+	}
+	if _, err = w.Write(item.Value); err != nil {
+		return err
+	}
+	_, err = w.Write(crlf)
+	return err
+}
+
+// SetMany writes multiple items unconditionally, commands are pipelined per server.
+// If noReply is true, the server will not send replies and failures are not reported.
+func (c *Client) SetMany(ctx context.Context, items []*Item, noReply bool) error {
+	itemsByAddr := make(map[string][]*Item)
+	for _, item := range items {
+		if !legalKey(item.Key) {
+			return ErrMalformedKey
+		}
+		addr, err := c.pickServer(item.Key)
+		if err != nil {
+			return err
+		}
+		itemsByAddr[addr] = append(itemsByAddr[addr], item)
+	}
+
+	for addr, addrItems := range itemsByAddr {
+		err := c.withConn(ctx, addr, func(rw *bufio.ReadWriter) error {
+			for _, item := range addrItems {
+				if err := writeStorageCommand(rw, "set", item, noReply); err != nil {
+					return err
+				}
+			}
+			if err := rw.Flush(); err != nil {
+				return err
+			}
+			if noReply {
+				return nil
+			}
+			var firstErr error
+			for range addrItems {
+				line, err := rw.ReadSlice('\n')
+				if err != nil {
+					return err
+				}
+				if !bytes.Equal(line, resultStored) && firstErr == nil {
+					firstErr = fmt.Errorf("cache:memcached: unexpected response line from set: %q", line)
+				}
+			}
+			return firstErr
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete deletes the item with the provided key, returns ErrCacheMiss if the item didn't already exist
+func (c *Client) Delete(ctx context.Context, key string) error {
+	if !legalKey(key) {
+		return ErrMalformedKey
+	}
+	addr, err := c.pickServer(key)
+	if err != nil {
+		return err
+	}
+
+	return c.withConn(ctx, addr, func(rw *bufio.ReadWriter) error {
+		if _, err := fmt.Fprintf(rw, "delete %s\r\n", key); err != nil {
+			return err
+		}
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		line, err := rw.ReadSlice('\n')
+		if err != nil {
+			return err
+		}
+		switch {
+		case bytes.Equal(line, resultDeleted):
+			return nil
+		case bytes.Equal(line, resultNotFound):
+			return ErrCacheMiss
+		}
+		return fmt.Errorf("cache:memcached: unexpected response line from delete: %q", line)
+	})
+}
+
+// DeleteMany deletes multiple items, commands are pipelined per server.
+// Items not existing are ignored. If noReply is true, the server will not send replies and failures are not reported.
+func (c *Client) DeleteMany(ctx context.Context, keys []string, noReply bool) error {
+	_, err := c.deleteMany(ctx, keys, noReply)
+	return err
+}
+
+// DeleteManyWithResult deletes multiple items, commands are pipelined per server.
+// The returned slice reports whether each key existed, and has same length with keys.
+func (c *Client) DeleteManyWithResult(ctx context.Context, keys []string) ([]bool, error) {
+	return c.deleteMany(ctx, keys, false)
+}
+
+// deleteMany deletes multiple items, and returns whether each key existed unless noReply is true
+func (c *Client) deleteMany(ctx context.Context, keys []string, noReply bool) ([]bool, error) {
+	idxListByAddr := make(map[string][]int)
+	for idx, key := range keys {
+		if !legalKey(key) {
+			return nil, ErrMalformedKey
+		}
+		addr, err := c.pickServer(key)
+		if err != nil {
+			return nil, err
+		}
+		idxListByAddr[addr] = append(idxListByAddr[addr], idx)
+	}
+
+	suffix := "\r\n"
+	if noReply {
+		suffix = " noreply\r\n"
+	}
+
+	existed := make([]bool, len(keys))
+	for addr, idxList := range idxListByAddr {
+		err := c.withConn(ctx, addr, func(rw *bufio.ReadWriter) error {
+			for _, idx := range idxList {
+				if _, err := fmt.Fprintf(rw, "delete %s%s", keys[idx], suffix); err != nil {
+					return err
+				}
+			}
+			if err := rw.Flush(); err != nil {
+				return err
+			}
+			if noReply {
+				return nil
+			}
+			var firstErr error
+			for _, idx := range idxList {
+				line, err := rw.ReadSlice('\n')
+				if err != nil {
+					return err
+				}
+				switch {
+				case bytes.Equal(line, resultDeleted):
+					existed[idx] = true
+				case bytes.Equal(line, resultNotFound):
+				default:
+					if firstErr == nil {
+						firstErr = fmt.Errorf("cache:memcached: unexpected response line from delete: %q", line)
+					}
+				}
+			}
+			return firstErr
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return existed, nil
+}
+
+// Increment atomically increments key by delta. The return value is the new value after being incremented or an error.
+// If the value didn't exist in memcached the error is ErrCacheMiss. The value in memcached must be an decimal number,
+// or an error will be returned. On 64-bit overflow, the new value wraps around.
+func (c *Client) Increment(ctx context.Context, key string, delta uint64) (uint64, error) {
+	return c.incrDecr(ctx, "incr", key, delta)
+}
+
+// Decrement atomically decrements key by delta. The return value is the new value after being decremented or an error.
+// If the value didn't exist in memcached the error is ErrCacheMiss. The value in memcached must be an decimal number,
+// or an error will be returned. On underflow, the new value is capped at zero and does not wrap around.
+func (c *Client) Decrement(ctx context.Context, key string, delta uint64) (uint64, error) {
+	return c.incrDecr(ctx, "decr", key, delta)
+}
+
+func (c *Client) incrDecr(ctx context.Context, verb, key string, delta uint64) (uint64, error) {
+	if !legalKey(key) {
+		return 0, ErrMalformedKey
+	}
+	addr, err := c.pickServer(key)
+	if err != nil {
+		return 0, err
+	}
+
+	var val uint64
+	err = c.withConn(ctx, addr, func(rw *bufio.ReadWriter) error {
+		if _, err := fmt.Fprintf(rw, "%s %s %d\r\n", verb, key, delta); err != nil {
+			return err
+		}
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		line, err := rw.ReadSlice('\n')
+		if err != nil {
+			return err
+		}
+		switch {
+		case bytes.Equal(line, resultNotFound):
+			return ErrCacheMiss
+		case bytes.HasPrefix(line, []byte("CLIENT_ERROR ")):
+			return fmt.Errorf("cache:memcached: %s", strings.TrimSpace(string(line)))
+		}
+		val, err = strconv.ParseUint(string(line[:len(line)-2]), 10, 64)
+		return err
+	})
+
+	return val, err
+}
+
+// Touch updates the expiry for the given key, returns ErrCacheMiss if the item didn't already exist
+func (c *Client) Touch(ctx context.Context, key string, expiration int32) error {
+	if !legalKey(key) {
+		return ErrMalformedKey
+	}
+	addr, err := c.pickServer(key)
+	if err != nil {
+		return err
+	}
+
+	return c.withConn(ctx, addr, func(rw *bufio.ReadWriter) error {
+		if _, err := fmt.Fprintf(rw, "touch %s %d\r\n", key, expiration); err != nil {
+			return err
+		}
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		line, err := rw.ReadSlice('\n')
+		if err != nil {
+			return err
+		}
+		switch {
+		case bytes.Equal(line, resultTouched):
+			return nil
+		case bytes.Equal(line, resultNotFound):
+			return ErrCacheMiss
+		}
+		return fmt.Errorf("cache:memcached: unexpected response line from touch: %q", line)
+	})
+}
+
+// FlushAll deletes all items in all servers
+func (c *Client) FlushAll(ctx context.Context) error {
+	return c.broadcast(ctx, "flush_all\r\n", resultOK)
+}
+
+// Ping checks all servers are accessible through the `version` command
+func (c *Client) Ping(ctx context.Context) error {
+	return c.broadcast(ctx, "version\r\n", prefixVersion)
+}
+
+func (c *Client) broadcast(ctx context.Context, command string, expectPrefix []byte) error {
+	if len(c.config.Addresses) == 0 {
+		return ErrNoServers
+	}
+	for _, addr := range c.config.Addresses {
+		err := c.withConn(ctx, addr, func(rw *bufio.ReadWriter) error {
+			if _, err := rw.WriteString(command); err != nil {
+				return err
+			}
+			if err := rw.Flush(); err != nil {
+				return err
+			}
+			line, err := rw.ReadSlice('\n')
+			if err != nil {
+				return err
+			}
+			if !bytes.HasPrefix(line, expectPrefix) {
+				return fmt.Errorf("cache:memcached: unexpected response line: %q", line)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes all idle connections, connections in use are closed when they are returned
+func (c *Client) Close() error {
+	c.mu.Lock()
+	idle := c.idle
+	c.idle = make(map[string][]*conn)
+	c.closed = true
+	c.mu.Unlock()
+
+	for _, conns := range idle {
+		for _, cn := range conns {
+			_ = cn.netConn.Close()
+		}
+	}
+	return nil
+}
+
+func legalKey(key string) bool {
+	if len(key) == 0 || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+func readFull(r *bufio.Reader, b []byte) (int, error) {
+	n := 0
+	for n < len(b) {
+		m, err := r.Read(b[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
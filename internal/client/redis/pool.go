@@ -0,0 +1,285 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned when getting a connection from a closed pool
+var ErrPoolClosed = errors.New("cache:redis: pool closed")
+
+// PoolConfig defines config used to build a redis connection Pool
+type PoolConfig struct {
+	// Address is the `host:port` of the redis server
+	Address string
+	// Password is sent through AUTH right after the connection is dialed if not empty
+	Password string
+	// DB is selected through SELECT right after the connection is dialed if not zero
+	DB int
+
+	// MaxIdle is the maximum number of idle connections kept in the pool
+	MaxIdle int
+	// MaxActive is the maximum number of connections allocated by the pool at a given time. Zero means no limit.
+	// When the limit is reached, getting a connection waits until one is returned or the context is done.
+	MaxActive int
+	// IdleTimeout closes connections after remaining idle for this duration. Zero means no timeout.
+	IdleTimeout time.Duration
+
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// Pool maintains a pool of connections to a single redis server
+type Pool struct {
+	config PoolConfig
+
+	mu     sync.Mutex
+	idle   []*Conn // used as a stack, the most recently used connection is on top
+	closed bool
+
+	slots chan struct{} // limits the number of active connections if MaxActive > 0
+}
+
+// NewPool creates a new Pool, connections are dialed lazily
+func NewPool(config PoolConfig) *Pool {
+	p := &Pool{config: config}
+	if config.MaxActive > 0 {
+		p.slots = make(chan struct{}, config.MaxActive)
+	}
+	return p
+}
+
+// Address returns the address of the redis server
+func (p *Pool) Address() string {
+	return p.config.Address
+}
+
+// Do sends a command to the server and returns the received reply.
+// Error replies are returned as error of type Error.
+func (p *Pool) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	conn, err := p.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.Do(ctx, args...)
+	p.Put(conn, isBroken(err))
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, e
+	}
+
+	return reply, nil
+}
+
+// Pipeline sends all commands in one round trip and returns the replies in the same order.
+// Error replies are kept in the returned slice as values of type Error.
+func (p *Pool) Pipeline(ctx context.Context, cmds [][]interface{}) ([]interface{}, error) {
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+
+	conn, err := p.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := conn.Pipeline(ctx, cmds)
+	p.Put(conn, isBroken(err))
+
+	return replies, err
+}
+
+// Get gets a connection from the pool, the connection must be returned through Put
+func (p *Pool) Get(ctx context.Context) (*Conn, error) {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	conn, err := p.getConn(ctx)
+	if err != nil {
+		p.releaseSlot()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (p *Pool) getConn(ctx context.Context) (*Conn, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+	for len(p.idle) > 0 {
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if p.config.IdleTimeout > 0 && time.Since(conn.lastUsed) > p.config.IdleTimeout {
+			_ = conn.netConn.Close()
+			continue
+		}
+		p.mu.Unlock()
+		return conn, nil
+	}
+	p.mu.Unlock()
+
+	return p.dial(ctx)
+}
+
+// Put returns a connection to the pool, broken connections are closed
+func (p *Pool) Put(conn *Conn, broken bool) {
+	defer p.releaseSlot()
+
+	p.mu.Lock()
+	if broken || p.closed || len(p.idle) >= p.config.MaxIdle {
+		p.mu.Unlock()
+		_ = conn.netConn.Close()
+		return
+	}
+	conn.lastUsed = time.Now()
+	p.idle = append(p.idle, conn)
+	p.mu.Unlock()
+}
+
+func (p *Pool) releaseSlot() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+// Close closes all idle connections, connections in use are closed when they are returned
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	for _, conn := range idle {
+		_ = conn.netConn.Close()
+	}
+	return nil
+}
+
+func (p *Pool) dial(ctx context.Context) (*Conn, error) {
+	dialer := net.Dialer{Timeout: p.config.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", p.config.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &Conn{
+		netConn:      netConn,
+		br:           bufio.NewReader(netConn),
+		bw:           bufio.NewWriter(netConn),
+		readTimeout:  p.config.ReadTimeout,
+		writeTimeout: p.config.WriteTimeout,
+	}
+
+	if p.config.Password != "" {
+		if _, err = conn.do(ctx, "AUTH", p.config.Password); err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+	}
+	if p.config.DB != 0 {
+		if _, err = conn.do(ctx, "SELECT", p.config.DB); err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// isBroken reports whether the connection should be discarded after err occurred
+func isBroken(err error) bool {
+	if err == nil {
+		return false
+	}
+	var replyErr Error
+	return !errors.As(err, &replyErr)
+}
+
+// Conn is a single connection to the redis server, it is not safe for concurrent use
+type Conn struct {
+	netConn      net.Conn
+	br           *bufio.Reader
+	bw           *bufio.Writer
+	lastUsed     time.Time
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+// Do sends a command and returns the reply, error replies are returned as values of type Error
+func (c *Conn) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	replies, err := c.Pipeline(ctx, [][]interface{}{args})
+	if err != nil {
+		return nil, err
+	}
+	return replies[0], nil
+}
+
+// do is similar like Do, but error replies are returned as error
+func (c *Conn) do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// Pipeline sends all commands before reading any reply
+func (c *Conn) Pipeline(ctx context.Context, cmds [][]interface{}) ([]interface{}, error) {
+	if err := c.netConn.SetWriteDeadline(c.deadline(ctx, c.writeTimeout)); err != nil {
+		return nil, err
+	}
+	for _, cmd := range cmds {
+		if err := writeCommand(c.bw, cmd); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.bw.Flush(); err != nil {
+		return nil, err
+	}
+
+	if err := c.netConn.SetReadDeadline(c.deadline(ctx, c.readTimeout)); err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, len(cmds))
+	for i := range cmds {
+		reply, err := readReply(c.br)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+
+	return replies, nil
+}
+
+// deadline returns the earlier one between the ctx deadline and the configured timeout
+func (c *Conn) deadline(ctx context.Context, timeout time.Duration) time.Time {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if ctxDeadline, ok := ctx.Deadline(); ok && (deadline.IsZero() || ctxDeadline.Before(deadline)) {
+		deadline = ctxDeadline
+	}
+	return deadline
+}
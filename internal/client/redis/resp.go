@@ -0,0 +1,221 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Error represents an error reply returned by the redis server
+type Error string
+
+// Error returns the error message replied by the server
+func (e Error) Error() string {
+	return string(e)
+}
+
+var (
+	// ErrNil is returned by the reply helpers when the server replies with a nil bulk string or nil array
+	ErrNil = errors.New("cache:redis: nil reply")
+
+	// errProtocol is returned when the reply does not follow RESP
+	errProtocol = errors.New("cache:redis: protocol error")
+)
+
+var crlf = []byte("\r\n")
+
+// writeCommand writes args as a RESP array of bulk strings
+func writeCommand(bw *bufio.Writer, args []interface{}) error {
+	if err := writeLen(bw, '*', len(args)); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		var b []byte
+		switch v := arg.(type) {
+		case []byte:
+			b = v
+		case string:
+			b = []byte(v)
+		case int:
+			b = strconv.AppendInt(nil, int64(v), 10)
+		case int64:
+			b = strconv.AppendInt(nil, v, 10)
+		case uint64:
+			b = strconv.AppendUint(nil, v, 10)
+		case float64:
+			b = strconv.AppendFloat(nil, v, 'g', -1, 64)
+		case nil:
+			b = nil
+		default:
+			b = []byte(fmt.Sprint(v))
+		}
+		if err := writeLen(bw, '$', len(b)); err != nil {
+			return err
+		}
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+		if _, err := bw.Write(crlf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeLen(bw *bufio.Writer, prefix byte, n int) error {
+	if err := bw.WriteByte(prefix); err != nil {
+		return err
+	}
+	if _, err := bw.WriteString(strconv.Itoa(n)); err != nil {
+		return err
+	}
+	_, err := bw.Write(crlf)
+	return err
+}
+
+// readReply reads a single RESP reply, the concrete type of returned value can be
+// string (simple string), Error, int64, []byte (bulk string), []interface{} (array) or nil
+func readReply(br *bufio.Reader) (interface{}, error) {
+	line, err := readLine(br)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errProtocol
+	}
+
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, errProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := readFull(br, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, errProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		values := make([]interface{}, n)
+		for i := range values {
+			values[i], err = readReply(br)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+
+	return nil, errProtocol
+}
+
+func readLine(br *bufio.Reader) ([]byte, error) {
+	line, err := br.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	i := len(line) - 2
+	if i < 0 || line[i] != '\r' {
+		return nil, errProtocol
+	}
+	return line[:i], nil
+}
+
+func readFull(br *bufio.Reader, b []byte) (int, error) {
+	n := 0
+	for n < len(b) {
+		m, err := br.Read(b[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Bytes converts a reply to bytes, returns ErrNil for nil reply
+func Bytes(reply interface{}, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case nil:
+		return nil, ErrNil
+	case Error:
+		return nil, v
+	}
+	return nil, fmt.Errorf("cache:redis: unexpected type %T for bytes reply", reply)
+}
+
+// Int64 converts a reply to int64, returns ErrNil for nil reply
+func Int64(reply interface{}, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch v := reply.(type) {
+	case int64:
+		return v, nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case nil:
+		return 0, ErrNil
+	case Error:
+		return 0, v
+	}
+	return 0, fmt.Errorf("cache:redis: unexpected type %T for integer reply", reply)
+}
+
+// String converts a reply to string, returns ErrNil for nil reply
+func String(reply interface{}, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	switch v := reply.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case nil:
+		return "", ErrNil
+	case Error:
+		return "", v
+	}
+	return "", fmt.Errorf("cache:redis: unexpected type %T for string reply", reply)
+}
+
+// Values converts a reply to a slice of values, returns ErrNil for nil reply
+func Values(reply interface{}, err error) ([]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case []interface{}:
+		return v, nil
+	case nil:
+		return nil, ErrNil
+	case Error:
+		return nil, v
+	}
+	return nil, fmt.Errorf("cache:redis: unexpected type %T for array reply", reply)
+}
@@ -1,6 +1,9 @@
 package group
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 // Group represents a class of work and forms a namespace in which
 // units of work can be executed with duplicate suppression.
@@ -11,9 +14,15 @@ type Group struct {
 
 // Call represents an in-flight or completed work
 type Call struct {
-	wg  sync.WaitGroup
-	val interface{}
-	err error
+	done chan struct{} // closed once the call is completed
+	val  interface{}
+	err  error
+}
+
+// Result is the result of a call
+type Result struct {
+	Val interface{}
+	Err error
 }
 
 // NewGroup creates a empty Group
@@ -26,7 +35,8 @@ func NewGroup() *Group {
 }
 
 // AddCalls tries to add calls and return waitingCallsMap which indicates the key is being handled by others
-// and toHandleKeys which indicate the keys to be handled by the caller
+// and toHandleKeys which indicate the keys to be handled by the caller.
+// The caller MUST complete the calls of toHandleKeys by CompleteCalls, otherwise the waiters of them are blocked forever.
 func (g *Group) AddCalls(keys []string) (waitingCallsMap map[string]*Call, toHandleKeys []string) {
 	waitingCallsMap = make(map[string]*Call)
 	toHandleKeys = make([]string, 0, len(keys))
@@ -37,9 +47,7 @@ func (g *Group) AddCalls(keys []string) (waitingCallsMap map[string]*Call, toHan
 			waitingCallsMap[key] = curCall
 		} else {
 			toHandleKeys = append(toHandleKeys, key)
-			newCall := &Call{}
-			newCall.wg.Add(1)
-			g.m[key] = newCall
+			g.m[key] = &Call{done: make(chan struct{})}
 		}
 	}
 	g.mu.Unlock()
@@ -47,23 +55,36 @@ func (g *Group) AddCalls(keys []string) (waitingCallsMap map[string]*Call, toHan
 	return waitingCallsMap, toHandleKeys
 }
 
-// CompleteCalls completes multiple calls for key value pairs in valueMap
-func (g *Group) CompleteCalls(valueMap map[string]interface{}) {
+// CompleteCalls completes multiple calls with the values and errors in resultMap, all waiters of a call receive the same result
+func (g *Group) CompleteCalls(resultMap map[string]Result) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	for key, val := range valueMap {
-		g.m[key].val = val
-		g.m[key].wg.Done()
+	for key, result := range resultMap {
+		call, ok := g.m[key]
+		if !ok {
+			continue
+		}
+		call.val = result.Val
+		call.err = result.Err
+		close(call.done)
 		delete(g.m, key)
 	}
 }
 
 // WaitCall waits a call to complete
 func (g *Group) WaitCall(call *Call) (interface{}, error) {
-	call.wg.Wait()
-	val := call.val
-	err := call.err
+	<-call.done
+	return call.val, call.err
+}
 
-	return val, err
+// WaitCallContext waits a call to complete, or returns the error of ctx once ctx is done.
+// The call is not affected if ctx is done, so that each waiter can give up independently.
+func (g *Group) WaitCallContext(ctx context.Context, call *Call) (interface{}, error) {
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
@@ -26,6 +26,10 @@ type Header struct {
 	CompressionType      *int64   `protobuf:"varint,1,opt,name=CompressionType" json:"CompressionType,omitempty"`
 	SoftTimeoutTs        *int64   `protobuf:"varint,2,opt,name=SoftTimeoutTs" json:"SoftTimeoutTs,omitempty"`
 	HardTimeoutTs        *int64   `protobuf:"varint,3,opt,name=HardTimeoutTs" json:"HardTimeoutTs,omitempty"`
+	Tags                 []string `protobuf:"bytes,4,rep,name=Tags" json:"Tags,omitempty"`
+	TagVersions          []int64  `protobuf:"varint,5,rep,name=TagVersions" json:"TagVersions,omitempty"`
+	Version              *int64   `protobuf:"varint,6,opt,name=Version" json:"Version,omitempty"`
+	ComputeTimeMs        *int64   `protobuf:"varint,7,opt,name=ComputeTimeMs" json:"ComputeTimeMs,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -85,6 +89,34 @@ func (m *Header) GetHardTimeoutTs() int64 {
 	return 0
 }
 
+func (m *Header) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *Header) GetTagVersions() []int64 {
+	if m != nil {
+		return m.TagVersions
+	}
+	return nil
+}
+
+func (m *Header) GetVersion() int64 {
+	if m != nil && m.Version != nil {
+		return *m.Version
+	}
+	return 0
+}
+
+func (m *Header) GetComputeTimeMs() int64 {
+	if m != nil && m.ComputeTimeMs != nil {
+		return *m.ComputeTimeMs
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Header)(nil), "headerproto.Header")
 }
@@ -92,15 +124,17 @@ func init() {
 func init() { proto.RegisterFile("header.proto", fileDescriptor_6398613e36d6c2ce) }
 
 var fileDescriptor_6398613e36d6c2ce = []byte{
-	// 124 bytes of a gzipped FileDescriptorProto
+	// 158 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xc9, 0x48, 0x4d, 0x4c,
-	0x49, 0x2d, 0xd2, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x86, 0xf0, 0xc0, 0x1c, 0x25, 0x7f,
-	0x2e, 0x36, 0x0f, 0x30, 0x57, 0x48, 0x9c, 0x8b, 0xdf, 0x39, 0x3f, 0xb7, 0xa0, 0x28, 0xb5, 0xb8,
-	0x38, 0x33, 0x3f, 0x2f, 0xa4, 0xb2, 0x20, 0x55, 0x82, 0x51, 0x81, 0x51, 0x83, 0x59, 0x48, 0x94,
-	0x8b, 0x37, 0x38, 0x3f, 0xad, 0x24, 0x24, 0x33, 0x37, 0x35, 0xbf, 0xb4, 0x24, 0xa4, 0x58, 0x82,
-	0x09, 0x26, 0xec, 0x91, 0x58, 0x94, 0x82, 0x10, 0x66, 0x06, 0x09, 0x3b, 0x09, 0x9c, 0x78, 0x24,
-	0xc7, 0x78, 0xe1, 0x91, 0x1c, 0xe3, 0x83, 0x47, 0x72, 0x8c, 0x33, 0x1e, 0xcb, 0x31, 0x00, 0x02,
-	0x00, 0x00, 0xff, 0xff, 0x85, 0x7b, 0xd6, 0xe4, 0x7e, 0x00, 0x00, 0x00,
+	0x49, 0x2d, 0xd2, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x86, 0xf0, 0xc0, 0x1c, 0xa5, 0x59,
+	0x8c, 0x5c, 0x6c, 0x1e, 0x60, 0xbe, 0x90, 0x38, 0x17, 0xbf, 0x73, 0x7e, 0x6e, 0x41, 0x51, 0x6a,
+	0x71, 0x71, 0x66, 0x7e, 0x5e, 0x48, 0x65, 0x41, 0xaa, 0x04, 0xa3, 0x02, 0xa3, 0x06, 0xb3, 0x90,
+	0x28, 0x17, 0x6f, 0x70, 0x7e, 0x5a, 0x49, 0x48, 0x66, 0x6e, 0x6a, 0x7e, 0x69, 0x49, 0x48, 0xb1,
+	0x04, 0x13, 0x4c, 0xd8, 0x23, 0xb1, 0x28, 0x05, 0x21, 0xcc, 0x0c, 0x16, 0xe6, 0xe1, 0x62, 0x09,
+	0x49, 0x4c, 0x2f, 0x96, 0x60, 0x51, 0x60, 0xd6, 0xe0, 0x14, 0x12, 0xe6, 0xe2, 0x0e, 0x49, 0x4c,
+	0x0f, 0x4b, 0x2d, 0x02, 0x99, 0x59, 0x2c, 0xc1, 0xaa, 0xc0, 0xac, 0xc1, 0x2c, 0xc4, 0xcf, 0xc5,
+	0x0e, 0x15, 0x91, 0x60, 0x83, 0x19, 0x05, 0xb2, 0xba, 0xb4, 0x24, 0x15, 0x64, 0x9a, 0x6f, 0xb1,
+	0x04, 0x3b, 0x48, 0x18, 0x30, 0x00, 0xa8, 0xf2, 0xd7, 0xa9, 0xb8, 0x00, 0x00, 0x00,
 }
 
 func (m *Header) Marshal() (dAtA []byte, err error) {
@@ -127,6 +161,32 @@ func (m *Header) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if m.ComputeTimeMs != nil {
+		i = encodeVarintHeader(dAtA, i, uint64(*m.ComputeTimeMs))
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.Version != nil {
+		i = encodeVarintHeader(dAtA, i, uint64(*m.Version))
+		i--
+		dAtA[i] = 0x30
+	}
+	if len(m.TagVersions) > 0 {
+		for iNdEx := len(m.TagVersions) - 1; iNdEx >= 0; iNdEx-- {
+			i = encodeVarintHeader(dAtA, i, uint64(m.TagVersions[iNdEx]))
+			i--
+			dAtA[i] = 0x28
+		}
+	}
+	if len(m.Tags) > 0 {
+		for iNdEx := len(m.Tags) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Tags[iNdEx])
+			copy(dAtA[i:], m.Tags[iNdEx])
+			i = encodeVarintHeader(dAtA, i, uint64(len(m.Tags[iNdEx])))
+			i--
+			dAtA[i] = 0x22
+		}
+	}
 	if m.HardTimeoutTs != nil {
 		i = encodeVarintHeader(dAtA, i, uint64(*m.HardTimeoutTs))
 		i--
@@ -171,6 +231,23 @@ func (m *Header) Size() (n int) {
 	if m.HardTimeoutTs != nil {
 		n += 1 + sovHeader(uint64(*m.HardTimeoutTs))
 	}
+	if len(m.Tags) > 0 {
+		for _, s := range m.Tags {
+			l = len(s)
+			n += 1 + l + sovHeader(uint64(l))
+		}
+	}
+	if len(m.TagVersions) > 0 {
+		for _, e := range m.TagVersions {
+			n += 1 + sovHeader(uint64(e))
+		}
+	}
+	if m.Version != nil {
+		n += 1 + sovHeader(uint64(*m.Version))
+	}
+	if m.ComputeTimeMs != nil {
+		n += 1 + sovHeader(uint64(*m.ComputeTimeMs))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -272,6 +349,154 @@ func (m *Header) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.HardTimeoutTs = &v
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Tags", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHeader
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthHeader
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthHeader
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Tags = append(m.Tags, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 5:
+			if wireType == 0 {
+				var v int64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowHeader
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= int64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.TagVersions = append(m.TagVersions, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowHeader
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthHeader
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthHeader
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				var count int
+				for _, integer := range dAtA[iNdEx:postIndex] {
+					if integer < 128 {
+						count++
+					}
+				}
+				elementCount = count
+				if elementCount != 0 && len(m.TagVersions) == 0 {
+					m.TagVersions = make([]int64, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v int64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowHeader
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= int64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.TagVersions = append(m.TagVersions, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field TagVersions", wireType)
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHeader
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Version = &v
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ComputeTimeMs", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHeader
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ComputeTimeMs = &v
 		default:
 			iNdEx = preIndex
 			skippy, err := skipHeader(dAtA[iNdEx:])
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// ItemMeta is the meta info of a cached item, refer to GetWithMeta and TTL of Cache interface.
+// The expiration of an item is recorded in seconds, so HardTTL and SoftTTL are accurate to a second.
+type ItemMeta struct {
+	// HardTTL is the remaining time before the item expires, NoExpiration if the item never expires.
+	// It is 0 if the expiration is unknown, e.g. the item is not written by this library or encoding is disabled.
+	HardTTL time.Duration
+	// SoftTTL is the remaining time before the item needs update, refer to WithSoftExpiration.
+	// It is 0 if the soft expiration is not set or already reached.
+	SoftTTL time.Duration
+	// IsStale is true once the soft expiration of the item is reached, i.e. the item is served but needs update
+	IsStale bool
+	// EncodedSize is the size in bytes of the item stored in the cache, 0 for in-memory cache
+	EncodedSize int
+	// CompressionAlgo is the compression algorithm of the item, e.g. "Snappy", "Gzip" or "None".
+	// It is empty if the item is not encoded, e.g. for in-memory cache or if encoding is disabled.
+	CompressionAlgo string
+	// Version is the version of the item generated on every write, refer to CompareAndSwap.
+	// It is 0 if the item is not written by this library or encoding is disabled.
+	Version int64
+}
+
+func genItemMeta(header metaHeader, encodedSize int, now time.Time) ItemMeta {
+	meta := ItemMeta{
+		IsStale:         needUpdate(now, header),
+		EncodedSize:     encodedSize,
+		CompressionAlgo: header.Compression,
+		Version:         header.Version,
+	}
+
+	switch {
+	case header.HardTimeoutTs == hardTimeoutForeverIndicator:
+		meta.HardTTL = NoExpiration
+	case header.HardTimeoutTs > 0:
+		meta.HardTTL = getRemainingTTL(now, header.HardTimeoutTs)
+	}
+	if header.SoftTimeoutTs > 0 {
+		meta.SoftTTL = getRemainingTTL(now, header.SoftTimeoutTs)
+	}
+
+	return meta
+}
+
+// getRemainingTTL returns the remaining time from now to timeoutTs, 0 if timeoutTs is reached
+func getRemainingTTL(now time.Time, timeoutTs int64) time.Duration {
+	ttl := time.Unix(timeoutTs, 0).Sub(now)
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// getWithMetaThroughLayers gets the value of key and its meta from the first layer holding key.
+// Unlike Get, the value found in an inner layer is not backfilled to the outer layers, so that the meta is not altered.
+func getWithMetaThroughLayers(ctx context.Context, layers []ComposableCache, command string, key string, receiver interface{}, opts []OperationOption) (ItemMeta, error) {
+	for _, layer := range layers {
+		meta, err := layer.loadWrapper().getWithMeta(ctx, command, key, receiver, opts...)
+		if err == ErrCacheMiss {
+			continue
+		}
+		return meta, err
+	}
+	return ItemMeta{}, ErrCacheMiss
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-eCache/internal/compression"
+)
+
+func TestRedisCacheGetWithMeta(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{Address: server.addr()})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	if err := c.Set(ctx, "key", "val", time.Minute, WithSoftExpiration(30*time.Second)); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	var val string
+	meta, err := c.GetWithMeta(ctx, "key", &val)
+	if err != nil || val != "val" {
+		t.Fatalf("expect val, got: %v, err: %v", val, err)
+	}
+	if meta.HardTTL <= 58*time.Second || meta.HardTTL > time.Minute {
+		t.Fatalf("expect hard ttl about a minute, got: %v", meta.HardTTL)
+	}
+	if meta.SoftTTL <= 28*time.Second || meta.SoftTTL > 30*time.Second || meta.IsStale {
+		t.Fatalf("expect soft ttl about 30s and not stale, got: %+v", meta)
+	}
+	if meta.EncodedSize == 0 || meta.CompressionAlgo != compression.None.String() {
+		t.Fatalf("expect encoded size and compression algo, got: %+v", meta)
+	}
+
+	if err := c.Set(ctx, "forever", "val", NoExpiration); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	if meta, err = c.TTL(ctx, "forever"); err != nil || meta.HardTTL != NoExpiration || meta.SoftTTL != 0 {
+		t.Fatalf("expect no expiration, got: %+v, err: %v", meta, err)
+	}
+
+	// the item is stale once its soft expiration is reached
+	encoded, err := bytesEncode([]byte(`"val"`), compression.None, withSoftTimeoutTs(time.Now().Add(-time.Minute).Unix()),
+		withHardTimeoutTs(hardTimeoutForeverIndicator))
+	if err != nil {
+		t.Fatalf("encode err: %v", err)
+	}
+	server.mu.Lock()
+	server.data["stale"] = encoded
+	server.mu.Unlock()
+	if meta, err = c.TTL(ctx, "stale"); err != nil || !meta.IsStale || meta.SoftTTL != 0 {
+		t.Fatalf("expect stale, got: %+v, err: %v", meta, err)
+	}
+
+	if _, err = c.TTL(ctx, "not_exist"); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss, got: %v", err)
+	}
+}
+
+func TestInMemoryCacheGetWithMeta(t *testing.T) {
+	ctx := context.Background()
+	c := newTestInMemoryCache(t)
+
+	if err := c.Set(ctx, "key", "val", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	var val string
+	meta, err := c.GetWithMeta(ctx, "key", &val)
+	if err != nil || val != "val" {
+		t.Fatalf("expect val, got: %v, err: %v", val, err)
+	}
+	if meta.HardTTL <= 58*time.Second || meta.HardTTL > time.Minute || meta.EncodedSize != 0 || meta.CompressionAlgo != "" {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+
+	if err := c.Expire(ctx, "key", NoExpiration); err != nil {
+		t.Fatalf("expire err: %v", err)
+	}
+	if meta, err = c.TTL(ctx, "key"); err != nil || meta.HardTTL != NoExpiration {
+		t.Fatalf("expect no expiration after expire, got: %+v, err: %v", meta, err)
+	}
+}
+
+func TestMultiLayerCacheGetWithMeta(t *testing.T) {
+	ctx := context.Background()
+	c, local, remote := newTestMultiLayerCache(t)
+
+	if err := remote.Set(ctx, "key", "val", time.Minute); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	var val string
+	meta, err := c.GetWithMeta(ctx, "key", &val)
+	if err != nil || val != "val" || meta.CompressionAlgo == "" {
+		t.Fatalf("expect the meta of the remote layer, got: %+v, val: %v, err: %v", meta, val, err)
+	}
+	if _, err = local.TTL(ctx, "key"); err != ErrCacheMiss {
+		t.Fatalf("expect no backfill, got: %v", err)
+	}
+
+	if err := c.Set(ctx, "key", "val", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	if meta, err = c.TTL(ctx, "key"); err != nil || meta.CompressionAlgo != "" {
+		t.Fatalf("expect the meta of the local layer, got: %+v, err: %v", meta, err)
+	}
+}
@@ -2,7 +2,8 @@ package cache
 
 import (
 	"context"
-	"go-eCache/internal/utils"
+	"math"
+	"math/rand"
 	"reflect"
 	"time"
 )
@@ -24,6 +25,8 @@ type loadResult struct {
 // Besides, if users adopt AcrossInstanceSignal, we will acquire, maintain, and release the distributed lock here.
 func loadHandleKeys(ctx context.Context, inner *cacheWrapperInner, keys []string, receiverMap map[string]interface{}, loader DataLoader, expire time.Duration, curManufacturerHandler manufacturerHandler, curCodecHandler codecHandler, option cacheOperationOptions) map[string]loadResult {
 	loadResultMap, waitingAcrossInstanceKeys, randValue := handleDataLoaderLayerForInner(ctx, inner, keys, loader, expire, curManufacturerHandler, curCodecHandler, option)
+	// the stale data replaces the failed results before being set, so that it is not overwritten by the on-error results
+	loadResultMap = serveStaleIfError(loadResultMap, option.staleResultMap)
 	setManyErr := setManyForLoad(ctx, inner, loadResultMap, inner.codecHandler, option)
 	if setManyErr != nil {
 		loadResultMap = genErrResultsFromMap(loadResultMap, setManyErr)
@@ -32,9 +35,28 @@ func loadHandleKeys(ctx context.Context, inner *cacheWrapperInner, keys []string
 		releaseDlock(ctx, inner, loadResultMap, randValue)
 		loadResultMap = waitAcrossInstance(ctx, inner, waitingAcrossInstanceKeys, receiverMap, loadResultMap, curManufacturerHandler.acrossInstanceSignalConfig.retryInterval, curCodecHandler, option)
 	}
+	return serveStaleIfError(loadResultMap, option.staleResultMap)
+}
+
+// serveStaleIfError replaces the failed results in loadResultMap with the stale data in staleResultMap kept by stale-if-error
+func serveStaleIfError(loadResultMap map[string]loadResult, staleResultMap map[string]loadResult) map[string]loadResult {
+	for key, result := range loadResultMap {
+		loadResultMap[key] = getStaleResultIfError(key, result, staleResultMap)
+	}
 	return loadResultMap
 }
 
+// getStaleResultIfError returns the stale data of key in staleResultMap if result is failed, otherwise result itself
+func getStaleResultIfError(key string, result loadResult, staleResultMap map[string]loadResult) loadResult {
+	if result.err == nil || result.err == ErrCacheMiss {
+		return result
+	}
+	if staleResult, ok := staleResultMap[key]; ok {
+		return staleResult
+	}
+	return result
+}
+
 // waitAcrossInstance keeps (re)trying to get the waitingAcrossInstanceKeys, the missing keys which are (being) loaded by another instance.
 // This function is applicable only when stampede mitigation strategy is `AcrossInstanceSignal`.
 //
@@ -44,7 +66,7 @@ func waitAcrossInstance(ctx context.Context, inner *cacheWrapperInner, waitingAc
 	if len(waitingAcrossInstanceKeys) == 0 {
 		return loadResultMap
 	}
-	missingKeys, _, resultMap, getManyErr := getManyForLoad(ctx, inner, waitingAcrossInstanceKeys, receiverMap, curCodecHandler, option)
+	missingKeys, _, resultMap, _, getManyErr := getManyForLoad(ctx, inner, waitingAcrossInstanceKeys, receiverMap, curCodecHandler, option)
 	if getManyErr != nil {
 		resultMap = genErrResults(waitingAcrossInstanceKeys, getManyErr)
 	}
@@ -66,7 +88,7 @@ func waitAcrossInstance(ctx context.Context, inner *cacheWrapperInner, waitingAc
 				endTicker = true
 			case <-ticker.C:
 				resultMap = fillMissingWaitingKeys(lostDockKeys, resultMap, errDlockLoss)
-				preRoundMissingKeys, _, retryResultMap, getManyErr = getManyForLoad(ctx, inner, missingKeys, receiverMap, curCodecHandler, option)
+				preRoundMissingKeys, _, retryResultMap, _, getManyErr = getManyForLoad(ctx, inner, missingKeys, receiverMap, curCodecHandler, option)
 				if getManyErr != nil {
 					retryResultMap = genErrResults(missingKeys, getManyErr)
 				}
@@ -80,6 +102,10 @@ func waitAcrossInstance(ctx context.Context, inner *cacheWrapperInner, waitingAc
 					break
 				}
 				lostDockKeys = getLostDlockKeys(ctx, inner, missingKeys, dlockMap)
+				if len(lostDockKeys) > 0 {
+					inner.observationConfig.warnf(ctx, "dlock is lost before data is loaded, fill in nil data: cache_name=%v, keys=%v",
+						inner.name, formatKeysForLog(lostDockKeys))
+				}
 			}
 		}
 	}
@@ -97,7 +123,9 @@ func fillMissingWaitingKeys(lostDockKeys []string, resultMap map[string]loadResu
 	return resultMap
 }
 
-func getManyForLoad(ctx context.Context, inner *cacheWrapperInner, keys []string, receiverMap map[string]interface{}, codecHandler codecHandler, option cacheOperationOptions) (missingKeys, toUpdateKeys []string, successKeyResultMap map[string]loadResult, err error) {
+// getManyForLoad gets keys from the cache for Load(Many).
+// The data past hard expiration is regarded as missing, and returned by staleKeyResultMap if it is within the grace window of stale-if-error.
+func getManyForLoad(ctx context.Context, inner *cacheWrapperInner, keys []string, receiverMap map[string]interface{}, codecHandler codecHandler, option cacheOperationOptions) (missingKeys, toUpdateKeys []string, successKeyResultMap, staleKeyResultMap map[string]loadResult, err error) {
 
 	fixedKeys, requestSize := inner.getFixedKeys(ctx, keys)
 
@@ -108,10 +136,10 @@ func getManyForLoad(ctx context.Context, inner *cacheWrapperInner, keys []string
 		TotalKeyCount:  len(fixedKeys),
 		RequestSize:    requestSize,
 		hostName:       inner.cacheHostName,
-		req:            fixedKeys,
+		req:            keys,
 	}
-	requestStatsDecorator(ctx, stats, func() error {
-		values, innerCacheErr := inner.cache.getMany(ctx, fixedKeys...)
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
+		values, innerCacheErr := inner.getManyFromCache(ctx, fixedKeys)
 		if innerCacheErr != nil {
 			err = innerCacheErr
 			return err
@@ -135,6 +163,11 @@ func getManyForLoad(ctx context.Context, inner *cacheWrapperInner, keys []string
 				missingKeys = append(missingKeys, curKey)
 				continue
 			}
+			if header.Tombstone {
+				// the non-existence of the key is cached, which is served without calling DataLoader
+				successKeyResultMap[curKey] = loadResult{nil, nil, nil, header}
+				continue
+			}
 
 			// check skipCodec option and cache type
 			if option.skipCodec {
@@ -159,25 +192,59 @@ func getManyForLoad(ctx context.Context, inner *cacheWrapperInner, keys []string
 				successKeyResultMap[curKey] = loadResult{dataBytes, nil, nil, header}
 			}
 			respMap[curKey] = data
-			if needUpdate(now, header) {
+			if needUpdate(now, header) || needEarlyRecomputation(now, header, option.earlyRecomputationBeta) {
 				toUpdateKeys = append(toUpdateKeys, curKey)
 			}
 		}
 
+		// the data whose tags are invalidated are treated as missing
+		staleKeys := getStaleKeysByTags(ctx, inner, successKeyResultMap)
+		for _, key := range staleKeys {
+			delete(successKeyResultMap, key)
+			respMap[key] = nil
+			missingKeys = append(missingKeys, key)
+			successKeyCount--
+		}
+		if len(staleKeys) > 0 {
+			toUpdateKeys = excludeKeys(toUpdateKeys, staleKeys)
+		}
+
+		// the data past hard expiration is only kept for stale-if-error
+		var hardExpiredKeys []string
+		for key, result := range successKeyResultMap {
+			if !isHardExpired(now, result.header) {
+				continue
+			}
+			hardExpiredKeys = append(hardExpiredKeys, key)
+			delete(successKeyResultMap, key)
+			respMap[key] = nil
+			missingKeys = append(missingKeys, key)
+			successKeyCount--
+			if isInStaleGraceWindow(now, result.header, option.staleIfError) {
+				if staleKeyResultMap == nil {
+					staleKeyResultMap = make(map[string]loadResult)
+				}
+				staleKeyResultMap[key] = result
+			}
+		}
+		if len(hardExpiredKeys) > 0 {
+			toUpdateKeys = excludeKeys(toUpdateKeys, hardExpiredKeys)
+		}
+
 		stats.SuccessKeyCount = successKeyCount
 		stats.ResponseSize = responseSize
 		stats.resp = respMap
 
 		return err
 	})
-	return missingKeys, toUpdateKeys, successKeyResultMap, err
+	return missingKeys, toUpdateKeys, successKeyResultMap, staleKeyResultMap, err
 }
 
 func setManyForLoad(ctx context.Context, inner *cacheWrapperInner, loadResultMap map[string]loadResult, codecHandler codecHandler, option cacheOperationOptions) error {
 	if len(loadResultMap) == 0 {
 		return nil
 	}
-	if inner.isDisabledCtxOrConfig(ctx) {
+	if inner.isWriteSkipped(ctx) {
 		return nil
 	}
 
@@ -189,13 +256,31 @@ func setManyForLoad(ctx context.Context, inner *cacheWrapperInner, loadResultMap
 		hostName:       inner.cacheHostName,
 	}
 	var err error
-	requestStatsDecorator(ctx, stats, func() error {
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
+		// the tags applied by the operation, or carried by the data read from other caches e.g. the next layer
+		tagsMap := make(map[string][]string)
+		for key, loadResult := range loadResultMap {
+			if len(option.tags) > 0 {
+				tagsMap[key] = option.tags
+			} else if len(loadResult.header.Tags) > 0 {
+				tagsMap[key] = loadResult.header.Tags
+			}
+		}
+		tagVersionMap, tagErr := getTagVersionsForLoad(ctx, inner, tagsMap)
+		if tagErr != nil {
+			err = tagErr
+		}
+
 		valMap := make(map[string]interface{}, len(loadResultMap))
 		expMap := make(map[string]time.Duration, len(loadResultMap)) // fully relies on expMap for expiration
-		stats.req = valMap
+		reqMap := make(map[string]interface{}, len(loadResultMap))   // reqMap is keyed by the original keys for the statCollector
+		stats.req = reqMap
 		requestSize := 0
 		for key, loadResult := range loadResultMap {
-			if loadResult.dataBytes == nil && loadResult.data == nil {
+			if loadResult.dataBytes == nil && loadResult.data == nil && !loadResult.header.Tombstone {
+				continue
+			}
+			if loadResult.header.Tombstone && !inner.isTombstoneSupported() {
 				continue
 			}
 			expire := getHardExpiration(loadResult.header.HardTimeoutTs)
@@ -206,13 +291,26 @@ func setManyForLoad(ctx context.Context, inner *cacheWrapperInner, loadResultMap
 			expire = inner.translateExpire(ctx, expire) // each layer may have different maxExpiration and defaultExpiration
 			option.softTimeoutTs = loadResult.header.SoftTimeoutTs
 			option.hardTimeoutTs = loadResult.header.HardTimeoutTs
+			option.version = loadResult.header.Version
+			option.computeTimeMs = loadResult.header.ComputeTimeMs
 			option.skipEncodeDecode = false
+			option.tags = tagsMap[key]
+			option.tagVersions = nil
+			if len(option.tags) > 0 && inner.cacheType != inMemory {
+				// the data can not be tagged without the versions of tags
+				if tagErr != nil {
+					continue
+				}
+				option.tagVersions = genTagVersions(option.tags, tagVersionMap)
+			}
 
 			var encodedData interface{}
 			var encodeErr error
-			if option.skipCodec {
+			if loadResult.header.Tombstone {
+				encodedData, encodeErr = inner.encodeTombstone(&option)
+			} else if option.skipCodec {
 				if inner.cacheType == inMemory {
-					data := utils.GetValue(loadResult.data)
+					data := getSkipCodecData(loadResult.data)
 					encodedData, encodeErr = inner.encode(data, &option)
 				} else {
 					if loadResult.dataBytes != nil {
@@ -237,7 +335,8 @@ func setManyForLoad(ctx context.Context, inner *cacheWrapperInner, loadResultMap
 
 			fixedKey := inner.getFixedKey(ctx, key)
 			valMap[fixedKey] = encodedData
-			expMap[fixedKey] = expire
+			expMap[fixedKey] = genPhysicalExpiration(expire, option.staleIfError)
+			reqMap[key] = encodedData
 			requestSize += len(fixedKey) + inner.getEncodedDataSize(encodedData)
 		}
 		stats.RequestSize = requestSize
@@ -245,9 +344,23 @@ func setManyForLoad(ctx context.Context, inner *cacheWrapperInner, loadResultMap
 			stats.skipOperationLogs = err == nil
 			return err
 		}
+		var tags []string
+		for key := range reqMap {
+			tags = append(tags, tagsMap[key]...)
+		}
+		unlock := inner.lockTags(tags)
+		defer unlock()
+
 		setManyErr := inner.cache.setMany(ctx, valMap, unsetExpiration, withNoReply(option.noReply), withExpirationMap(expMap), withWaitRistretto(option.waitRistretto))
+		for fixedKey := range valMap {
+			inner.hotKeyHandler.invalidate(fixedKey)
+		}
 		if setManyErr != nil {
 			err = setManyErr
+		} else {
+			for key := range reqMap {
+				inner.indexTags(tagsMap[key], inner.getFixedKey(ctx, key))
+			}
 		}
 
 		return err
@@ -255,6 +368,58 @@ func setManyForLoad(ctx context.Context, inner *cacheWrapperInner, loadResultMap
 	return err
 }
 
+// getStaleKeysByTags returns the keys of resultMap whose data is stale by tags
+func getStaleKeysByTags(ctx context.Context, inner *cacheWrapperInner, resultMap map[string]loadResult) []string {
+	keys := make([]string, 0, len(resultMap))
+	headers := make([]metaHeader, 0, len(resultMap))
+	for key, result := range resultMap {
+		keys = append(keys, key)
+		headers = append(headers, result.header)
+	}
+
+	var staleKeys []string
+	for idx, stale := range inner.getStaleByTags(ctx, headers) {
+		if stale {
+			staleKeys = append(staleKeys, keys[idx])
+		}
+	}
+	return staleKeys
+}
+
+// getTagVersionsForLoad returns the current versions of all the tags of tagsMap for shared caches
+func getTagVersionsForLoad(ctx context.Context, inner *cacheWrapperInner, tagsMap map[string][]string) (map[string]int64, error) {
+	if len(tagsMap) == 0 || inner.cacheType == inMemory {
+		return nil, nil
+	}
+
+	var tags []string
+	tagSet := make(map[string]struct{})
+	for _, keyTags := range tagsMap {
+		for _, tag := range keyTags {
+			if _, ok := tagSet[tag]; !ok {
+				tagSet[tag] = struct{}{}
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return inner.getTagVersions(ctx, tags)
+}
+
+func excludeKeys(keys []string, excludedKeys []string) []string {
+	excludedKeySet := make(map[string]struct{}, len(excludedKeys))
+	for _, key := range excludedKeys {
+		excludedKeySet[key] = struct{}{}
+	}
+
+	remainingKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := excludedKeySet[key]; !ok {
+			remainingKeys = append(remainingKeys, key)
+		}
+	}
+	return remainingKeys
+}
+
 // handleDataLoaderLayerForInner handles the data-loading at the cacheWrapperInner. If users adopt AcrossInstanceSignal, distributed lock is set and maintained here at the cacheWrapperInner.
 // Besides, when using multilayer cache, dataloading logics are handled in the cacheWrapperInner of the last cache layer.
 func handleDataLoaderLayerForInner(ctx context.Context, inner *cacheWrapperInner, keys []string, loader DataLoader, expire time.Duration, curManufacturerHandler manufacturerHandler, curCodecHandler codecHandler, option cacheOperationOptions) (map[string]loadResult, []string, []byte) {
@@ -292,10 +457,10 @@ func handleDataLoaderLayerForInner(ctx context.Context, inner *cacheWrapperInner
 				}
 			}
 		}()
-		loadResultMap = handleDataLoaderLayer(ctx, inner, toHandleKeys, loader, expire, curCodecHandler, option)
+		loadResultMap = handleDataLoaderLayer(ctx, inner, toHandleKeys, loader, expire, curManufacturerHandler, curCodecHandler, option)
 		finishDataLoadSignal <- struct{}{}
 	} else {
-		loadResultMap = handleDataLoaderLayer(ctx, inner, keys, loader, expire, curCodecHandler, option)
+		loadResultMap = handleDataLoaderLayer(ctx, inner, keys, loader, expire, curManufacturerHandler, curCodecHandler, option)
 	}
 
 	return loadResultMap, waitingAcrossInstanceKeys, randValue
@@ -305,7 +470,7 @@ func handleDataLoaderLayerForInner(ctx context.Context, inner *cacheWrapperInner
 //
 // It returns `loadResult` that incorporates marshaled data bytes, un-marshaled data, error, and meta-header (soft/hard time out included).
 // The length of loadResult is exactly the SAME as length of input keys.
-func handleDataLoaderLayer(ctx context.Context, inner *cacheWrapperInner, keys []string, loader DataLoader, expire time.Duration, codecHandler codecHandler, option cacheOperationOptions) (loadResultMap map[string]loadResult) {
+func handleDataLoaderLayer(ctx context.Context, inner *cacheWrapperInner, keys []string, loader DataLoader, expire time.Duration, curManufacturerHandler manufacturerHandler, codecHandler codecHandler, option cacheOperationOptions) (loadResultMap map[string]loadResult) {
 	loadResultMap = make(map[string]loadResult, len(keys))
 
 	if loader == nil {
@@ -320,7 +485,9 @@ func handleDataLoaderLayer(ctx context.Context, inner *cacheWrapperInner, keys [
 		}
 	}()
 
-	dataList, err := loader(ctx, keys)
+	loadStart := time.Now()
+	dataList, err := callDataLoaderInBatch(ctx, inner, loader, keys, curManufacturerHandler.batcher, option)
+	computeTimeMs := time.Since(loadStart).Milliseconds()
 	if len(dataList) != len(keys) {
 		if err != nil {
 			loadResultMap = genErrResults(keys, err)
@@ -340,8 +507,12 @@ func handleDataLoaderLayer(ctx context.Context, inner *cacheWrapperInner, keys [
 		curKey := keys[idx]
 		softTimeoutTs := genSoftTimeoutTs(now, option.softExpiration)
 		hardTimeoutTs := genHardTimeoutTs(inner, curKey, expire, now, option, onErr)
-		header := metaHeader{SoftTimeoutTs: softTimeoutTs, HardTimeoutTs: hardTimeoutTs}
+		header := metaHeader{SoftTimeoutTs: softTimeoutTs, HardTimeoutTs: hardTimeoutTs, ComputeTimeMs: computeTimeMs}
 		if dataFromLoader == nil {
+			if err == nil && option.negativeCacheTTL > 0 {
+				loadResultMap[curKey] = loadResult{nil, nil, nil, genTombstoneHeader(now, option.negativeCacheTTL)}
+				continue
+			}
 			loadResultMap[curKey] = loadResult{nil, nil, err, metaHeader{}}
 			continue
 		}
@@ -360,6 +531,33 @@ func handleDataLoaderLayer(ctx context.Context, inner *cacheWrapperInner, keys [
 	return loadResultMap
 }
 
+// callDataLoaderInBatch calls loader by the batch of option.batchName in batcher if it is set, otherwise calls loader directly.
+// batcher is of the manufacturerHandler captured by the caller, so that the keys are loaded and completed by the same handler.
+func callDataLoaderInBatch(ctx context.Context, inner *cacheWrapperInner, loader DataLoader, keys []string, batcher *loaderBatcher, option cacheOperationOptions) ([]interface{}, error) {
+	if option.batchName == "" || batcher == nil {
+		return callDataLoader(ctx, inner, loader, keys)
+	}
+
+	return batcher.load(ctx, newLoaderBatchKey(option.batchName, loader), func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return callDataLoader(ctx, inner, loader, keys)
+	}, keys)
+}
+
+// callDataLoader calls loader within a span, the panic of loader is propagated to the caller
+func callDataLoader(ctx context.Context, inner *cacheWrapperInner, loader DataLoader, keys []string) (dataList []interface{}, err error) {
+	spanCtx, span := inner.observationConfig.startSpan(ctx, spanNameDataLoader)
+	span.SetAttributes(SpanAttribute{Key: "cache.name", Value: inner.name}, SpanAttribute{Key: "cache.total_key_count", Value: len(keys)})
+	defer func() {
+		if r := recover(); r != nil {
+			span.Finish(errDataLoaderPanic)
+			panic(r)
+		}
+		span.Finish(err)
+	}()
+
+	return loader(spanCtx, keys)
+}
+
 func setLoadResultsToReceiverMap(resultMap map[string]loadResult, receiverMap map[string]interface{}, codecHandler codecHandler, option cacheOperationOptions) error {
 	for key, result := range resultMap {
 		if err := setLoadResultToReceiver(key, result, receiverMap, codecHandler, option); err != nil {
@@ -379,10 +577,12 @@ func setLoadResultToReceiver(key string, result loadResult, receiverMap map[stri
 		if receiverMap[key] == nil {
 			return errNilReceiver
 		}
+		if option.staleKeys != nil && isHardExpired(time.Now(), result.header) {
+			option.staleKeys[key] = true
+		}
 
 		if option.skipCodec {
-			data := utils.GetValue(result.data)
-			err = utils.SetValue(data, receiverMap[key])
+			err = setSkipCodecLoadResultData(result.data, receiverMap[key])
 		} else {
 			err = codecHandler.unmarshal(result.dataBytes, receiverMap[key], option.codecType, option.customCodec)
 		}
@@ -466,6 +666,41 @@ func needUpdate(now time.Time, header metaHeader) bool {
 	return 0 < header.SoftTimeoutTs && header.SoftTimeoutTs <= now.Unix()
 }
 
+// needEarlyRecomputation decides whether to refresh the data before its hard expiration, following the probabilistic
+// early expiration (XFetch): the data is refreshed once now - computeTime * beta * ln(rand) reaches the hard timeout,
+// so the probability grows as the hard expiration approaches, and is higher for the data taking longer to load.
+// The data never expiring or without compute time (e.g. not loaded by DataLoader) is not refreshed early.
+func needEarlyRecomputation(now time.Time, header metaHeader, beta float64) bool {
+	if beta <= 0 || header.ComputeTimeMs <= 0 || header.HardTimeoutTs <= 0 || header.HardTimeoutTs == hardTimeoutForeverIndicator {
+		return false
+	}
+
+	// 1 - rand.Float64() is in (0, 1], so that the logarithm is finite
+	gap := float64(header.ComputeTimeMs) * beta * -math.Log(1-rand.Float64())
+	return now.Add(time.Duration(gap*float64(time.Millisecond))).Unix() >= header.HardTimeoutTs
+}
+
+// isHardExpired checks whether the data is past its hard expiration, which is kept in the cache only for stale-if-error.
+// The hard timeout is truncated to seconds, so the data is regarded as expired a second later,
+// in order not to expire earlier than it does in the cache.
+func isHardExpired(now time.Time, header metaHeader) bool {
+	return header.HardTimeoutTs > hardTimeoutForeverIndicator && header.HardTimeoutTs+1 < now.Unix()
+}
+
+// isInStaleGraceWindow checks whether the data past hard expiration can still be served within maxStale by stale-if-error
+func isInStaleGraceWindow(now time.Time, header metaHeader, maxStale time.Duration) bool {
+	return maxStale > 0 && now.Before(time.Unix(header.HardTimeoutTs, 0).Add(maxStale))
+}
+
+// genPhysicalExpiration returns the expiration of the data in the cache,
+// which is extended by the grace window of stale-if-error beyond the hard expiration.
+func genPhysicalExpiration(expire time.Duration, staleIfError time.Duration) time.Duration {
+	if staleIfError <= 0 || expire <= 0 {
+		return expire
+	}
+	return expire + staleIfError
+}
+
 func getHardExpiration(hardTimeoutTs int64) time.Duration {
 	if hardTimeoutTs == hardTimeoutForeverIndicator {
 		return NoExpiration
@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-eCache/internal/compression"
+)
+
+func TestNeedEarlyRecomputation(t *testing.T) {
+	now := time.Now()
+	header := metaHeader{HardTimeoutTs: now.Add(time.Minute).Unix(), ComputeTimeMs: 100}
+
+	if needEarlyRecomputation(now, header, 0) {
+		t.Fatalf("expect no early recomputation if disabled")
+	}
+	if needEarlyRecomputation(now, metaHeader{HardTimeoutTs: header.HardTimeoutTs}, 1) {
+		t.Fatalf("expect no early recomputation without compute time")
+	}
+	if needEarlyRecomputation(now, metaHeader{HardTimeoutTs: hardTimeoutForeverIndicator, ComputeTimeMs: 100}, 1e9) {
+		t.Fatalf("expect no early recomputation for the data never expiring")
+	}
+	if !needEarlyRecomputation(now, metaHeader{HardTimeoutTs: now.Unix(), ComputeTimeMs: 100}, 1) {
+		t.Fatalf("expect early recomputation once hard expiration is reached")
+	}
+
+	// the probability grows as the hard expiration approaches
+	countRecomputation := func(remaining time.Duration) int {
+		count := 0
+		for i := 0; i < 1000; i++ {
+			if needEarlyRecomputation(now, metaHeader{HardTimeoutTs: now.Add(remaining).Unix(), ComputeTimeMs: 1000}, 1) {
+				count++
+			}
+		}
+		return count
+	}
+	if far, near := countRecomputation(time.Hour), countRecomputation(2*time.Second); far >= near {
+		t.Fatalf("expect more recomputation near hard expiration, got far: %v, near: %v", far, near)
+	}
+}
+
+func TestLoadEarlyRecomputation(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{Address: server.addr()})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	var loadCount int32
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		atomic.AddInt32(&loadCount, 1)
+		time.Sleep(10 * time.Millisecond)
+		return []interface{}{"val"}, nil
+	}
+
+	var val string
+	if err := c.Load(ctx, loader, "key", &val, time.Minute); err != nil || val != "val" {
+		t.Fatalf("expect val, got: %v, err: %v", val, err)
+	}
+	server.mu.Lock()
+	_, header, err := bytesDecode(server.data["key"])
+	server.mu.Unlock()
+	if err != nil || header.ComputeTimeMs < 10 {
+		t.Fatalf("expect compute time recorded, got: %v, err: %v", header.ComputeTimeMs, err)
+	}
+	if err := c.Load(ctx, loader, "key", &val, time.Minute); err != nil || atomic.LoadInt32(&loadCount) != 1 {
+		t.Fatalf("expect no recomputation without the option, got: %v, err: %v", atomic.LoadInt32(&loadCount), err)
+	}
+
+	// a huge beta makes the recomputation almost certain
+	if err := c.Load(ctx, loader, "key", &val, time.Minute, WithEarlyRecomputation(1e9)); err != nil || val != "val" {
+		t.Fatalf("expect val served while refreshing, got: %v, err: %v", val, err)
+	}
+	for i := 0; i < 100 && atomic.LoadInt32(&loadCount) == 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if count := atomic.LoadInt32(&loadCount); count != 2 {
+		t.Fatalf("expect the data refreshed in background, got load count: %v", count)
+	}
+}
+
+func TestLoadRefreshAfterCallerCanceled(t *testing.T) {
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{
+		Address:            server.addr(),
+		ManufacturerConfig: ManufacturerConfig{CacheStampedeMitigation: AcrossInstanceSignal},
+	})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(context.Background()) }()
+
+	now := time.Now()
+	encoded, err := bytesEncode([]byte(`"old"`), compression.None, withSoftTimeoutTs(now.Add(-time.Second).Unix()), withHardTimeoutTs(now.Add(time.Minute).Unix()))
+	if err != nil {
+		t.Fatalf("encode err: %v", err)
+	}
+	server.mu.Lock()
+	server.data["key"] = encoded
+	server.mu.Unlock()
+
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		time.Sleep(10 * time.Millisecond)
+		return []interface{}{"new"}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var val string
+	err = c.Load(ctx, loader, "key", &val, time.Minute)
+	cancel()
+	if err != nil || val != "old" {
+		t.Fatalf("expect the stale data served while refreshing, got: %v, err: %v", val, err)
+	}
+
+	for i := 0; i < 100; i++ {
+		if err = c.Get(context.Background(), "key", &val); err == nil && val == "new" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expect the data refreshed after the caller canceled, got: %v, err: %v", val, err)
+}
+
+func TestLoadStaleIfError(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{Address: server.addr()})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	loaderErr := errors.New("loader err")
+	failedLoader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return nil, loaderErr
+	}
+
+	// the data is kept in the cache beyond its hard expiration
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return []interface{}{"new"}, nil
+	}
+	var val string
+	if err = c.Load(ctx, loader, "key", &val, time.Minute, WithStaleIfError(time.Hour)); err != nil || val != "new" {
+		t.Fatalf("expect new, got: %v, err: %v", val, err)
+	}
+	server.mu.Lock()
+	ttl := time.Until(server.expires["key"])
+	server.mu.Unlock()
+	if ttl <= time.Hour || ttl > time.Hour+time.Minute {
+		t.Fatalf("expect the grace window added to the expiration in the cache, got: %v", ttl)
+	}
+
+	encoded, err := bytesEncode([]byte(`"old"`), compression.None, withHardTimeoutTs(time.Now().Add(-10*time.Second).Unix()))
+	if err != nil {
+		t.Fatalf("encode err: %v", err)
+	}
+	server.mu.Lock()
+	server.data["key"] = encoded
+	server.mu.Unlock()
+
+	if err = c.Get(ctx, "key", &val); err != ErrCacheMiss {
+		t.Fatalf("expect the data past hard expiration missing, got: %v", err)
+	}
+	if err = c.Load(ctx, failedLoader, "key", &val, time.Minute); err != loaderErr {
+		t.Fatalf("expect loader err without stale-if-error, got: %v", err)
+	}
+	if err = c.Load(ctx, failedLoader, "key", &val, time.Minute, WithStaleIfError(5*time.Second)); err != loaderErr {
+		t.Fatalf("expect loader err out of the grace window, got: %v", err)
+	}
+
+	staleKeys := make(map[string]bool)
+	val = ""
+	if err = c.Load(ctx, failedLoader, "key", &val, time.Minute, WithStaleIfError(time.Minute), WithStaleKeys(staleKeys)); err != nil || val != "old" {
+		t.Fatalf("expect the stale data served, got: %v, err: %v", val, err)
+	}
+	if !staleKeys["key"] {
+		t.Fatalf("expect the key flagged stale, got: %v", staleKeys)
+	}
+
+	// the stale data is not overwritten by the on-error results
+	onErrLoader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return []interface{}{"on_err"}, loaderErr
+	}
+	if err = c.Load(ctx, onErrLoader, "key", &val, time.Minute, WithStaleIfError(time.Minute)); err != nil || val != "old" {
+		t.Fatalf("expect the stale data served, got: %v, err: %v", val, err)
+	}
+
+	staleKeys = make(map[string]bool)
+	if err = c.Load(ctx, loader, "key", &val, time.Minute, WithStaleIfError(time.Minute), WithStaleKeys(staleKeys)); err != nil || val != "new" {
+		t.Fatalf("expect the data reloaded, got: %v, err: %v", val, err)
+	}
+	if len(staleKeys) != 0 {
+		t.Fatalf("expect no key flagged stale, got: %v", staleKeys)
+	}
+}
+
+func TestLoadStaleIfErrorConfig(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{
+		Address:            server.addr(),
+		ManufacturerConfig: ManufacturerConfig{CacheStampedeMitigation: InProcessSignal, StaleIfErrorMillis: time.Minute.Milliseconds()},
+	})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	encoded, err := bytesEncode([]byte(`"old"`), compression.None, withHardTimeoutTs(time.Now().Add(-10*time.Second).Unix()))
+	if err != nil {
+		t.Fatalf("encode err: %v", err)
+	}
+	server.mu.Lock()
+	server.data["key"] = encoded
+	server.mu.Unlock()
+
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return nil, errors.New("loader err")
+	}
+	var val string
+	if err = c.Load(ctx, loader, "key", &val, time.Minute); err != nil || val != "old" {
+		t.Fatalf("expect the stale data served by default, got: %v, err: %v", val, err)
+	}
+
+	if _, err = NewRedisCache("test_redis", RedisConfig{
+		Address:            server.addr(),
+		ManufacturerConfig: ManufacturerConfig{StaleIfErrorMillis: -1},
+	}); err == nil {
+		t.Fatalf("expect err for negative stale if error")
+	}
+}
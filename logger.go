@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maxKeysInOperationLog is the max number of keys printed in a single operation log
+const maxKeysInOperationLog = 10
+
+// Logger defines interface of logger used by cache
+type Logger interface {
+	Warnf(ctx context.Context, format string, args ...interface{})
+	Errorf(ctx context.Context, format string, args ...interface{})
+}
+
+func (c observationConfig) warnf(ctx context.Context, format string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.Warnf(ctx, format, args...)
+	}
+}
+
+func (c observationConfig) errorf(ctx context.Context, format string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.Errorf(ctx, format, args...)
+	}
+}
+
+// logSlowOperation logs the operation if it is slower than the threshold in slowOperationLogConfig
+func (c observationConfig) logSlowOperation(ctx context.Context, stats *RequestStats) {
+	if c.slowOperationLogConfig.disable || stats.Elapsed < c.slowOperationLogConfig.elapseThresholdForSlowLog {
+		return
+	}
+
+	c.warnf(ctx, "slow cache operation: cache_name=%v, cache_type=%v, operation=%v, elapsed=%v, keys=%v, request_size=%v, response_size=%v, err=%v",
+		stats.CacheName, stats.CacheType, stats.CacheOperation, stats.Elapsed, formatKeysForLog(getStatsKeys(stats)),
+		stats.RequestSize, stats.ResponseSize, stats.Err)
+}
+
+// getStatsKeys gets the keys of the operation from the request of stats
+func getStatsKeys(stats *RequestStats) []string {
+	switch req := stats.req.(type) {
+	case string:
+		return []string{req}
+	case []string:
+		return req
+	case map[string]interface{}:
+		keys := make([]string, 0, len(req))
+		for key := range req {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	default:
+		return nil
+	}
+}
+
+// formatKeysForLog formats keys with at most maxKeysInOperationLog keys
+func formatKeysForLog(keys []string) string {
+	if len(keys) <= maxKeysInOperationLog {
+		return fmt.Sprint(keys)
+	}
+	return fmt.Sprintf("%v...(%v more)", keys[:maxKeysInOperationLog], len(keys)-maxKeysInOperationLog)
+}
+
+func newSlowOperationLogConfig(config SlowOperationLogConfig) slowOperationLogConfig {
+	threshold := time.Duration(config.ElapseThresholdMillis) * time.Millisecond
+	if config.ElapseThresholdMillis == 0 {
+		threshold = defaultElapseThresholdForSlowLogMillis * time.Millisecond
+	}
+
+	return slowOperationLogConfig{
+		disable:                   config.Disable,
+		elapseThresholdForSlowLog: threshold,
+	}
+}
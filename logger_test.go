@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingLogger struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *recordingLogger) Warnf(ctx context.Context, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	l.Warnf(ctx, format, args...)
+}
+
+func TestSlowOperationLog(t *testing.T) {
+	ctx := context.Background()
+	logger := &recordingLogger{}
+	observation := newObservationConfig(ObservationConfig{
+		Logger:                 logger,
+		SlowOperationLogConfig: SlowOperationLogConfig{ElapseThresholdMillis: 1},
+	})
+	slowFunc := func(ctx context.Context) error {
+		time.Sleep(5 * time.Millisecond)
+		return ErrCacheMiss
+	}
+
+	keys := make([]string, 12)
+	for idx := range keys {
+		keys[idx] = fmt.Sprintf("k%02d", idx)
+	}
+	requestStatsDecorator(ctx, observation, &RequestStats{CacheName: "c", CacheOperation: cmdGetMany, RequestSize: 36, req: keys}, slowFunc)
+	requestStatsDecorator(ctx, observation, &RequestStats{CacheName: "c", CacheOperation: cmdGet, req: "fast"}, func(ctx context.Context) error { return nil })
+	requestStatsDecorator(ctx, observation, &RequestStats{CacheName: "c", CacheOperation: cmdSetMany, skipOperationLogs: true}, slowFunc)
+
+	if len(logger.logs) != 1 {
+		t.Fatalf("expect only the slow operation logged, got: %v", logger.logs)
+	}
+	for _, expected := range []string{"cache_name=c", "operation=GetMany", "keys=[k00 k01", "k09]...(2 more)", "request_size=36", "err=cache:cache_miss"} {
+		if !strings.Contains(logger.logs[0], expected) {
+			t.Fatalf("expect %q in log: %v", expected, logger.logs[0])
+		}
+	}
+
+	disabled := newObservationConfig(ObservationConfig{Logger: logger, SlowOperationLogConfig: SlowOperationLogConfig{Disable: true, ElapseThresholdMillis: 1}})
+	requestStatsDecorator(ctx, disabled, &RequestStats{CacheName: "c", CacheOperation: cmdGet}, slowFunc)
+	if len(logger.logs) != 1 {
+		t.Fatalf("expect no log when slow operation log is disabled, got: %v", logger.logs)
+	}
+	if observation := newObservationConfig(ObservationConfig{}); observation.logger != nil {
+		t.Fatalf("expect no logging without Logger, got: %v", observation.logger)
+	}
+
+	if err := (InMemoryCacheConfig{
+		CacheType:         Ristretto,
+		ObservationConfig: ObservationConfig{SlowOperationLogConfig: SlowOperationLogConfig{ElapseThresholdMillis: -1}},
+	}).Validate(); err == nil {
+		t.Fatalf("expect error on negative elapse threshold")
+	}
+}
@@ -0,0 +1,377 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Manager manages cache instances by name.
+// It creates caches from configs, applies config updates to them, and closes them on shutdown.
+//
+// Composite caches (i.e. MultiLayer and Migration) refer to single caches (i.e. Redis, InMemory and Memcached)
+// managed by the same Manager by name, a single cache can be shared by several composite caches.
+type Manager struct {
+	mu       sync.RWMutex
+	configs  map[string]Config
+	caches   map[string]Cache
+	isClosed bool
+}
+
+// compositeCache is implemented by the composite caches, whose underlying caches are owned by Manager
+type compositeCache interface {
+	// closeSelf closes the composite cache without closing the underlying caches
+	closeSelf()
+}
+
+// NewManager creates a new manager with caches created from configs, which maps cache names to their configs
+func NewManager(configs map[string]Config) (*Manager, error) {
+	if err := validateManagerConfigs(configs); err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		configs: make(map[string]Config, len(configs)),
+		caches:  make(map[string]Cache, len(configs)),
+	}
+	if err := m.createCaches(configs, sortManagerCacheNames(configs, false)); err != nil {
+		_ = m.Close(context.Background())
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Get returns the cache of name
+func (m *Manager) Get(name string) (Cache, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.isClosed {
+		return nil, ErrCacheClosed
+	}
+	c, ok := m.caches[name]
+	if !ok {
+		return nil, errCacheNotExist
+	}
+	return c, nil
+}
+
+// UpdateConfig applies configs to the managed caches:
+//   - caches not in configs are closed and removed
+//   - caches only in configs are created
+//   - caches with changed config are updated in place, the Type of a cache can not be changed
+//
+// A composite cache is re-created if its config is changed, except a Migration cache switching mode only.
+// Configs are validated as a whole before any change is applied, and the update is applied as a whole:
+// if any cache fails to be updated or created, the caches updated in place are reverted to their previous configs,
+// the caches created are closed, and the managed caches are left unchanged.
+//
+// Configs are compared with the functions (e.g. CostFunc) compared by identity, refer to isConfigEqual.
+func (m *Manager) UpdateConfig(configs map[string]Config) error {
+	if err := validateManagerConfigs(configs); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.isClosed {
+		return ErrCacheClosed
+	}
+	for name, config := range configs {
+		if curConfig, ok := m.configs[name]; ok && curConfig.Type != config.Type {
+			return cacheErr("manager_cache_type_not_changeable: " + name)
+		}
+	}
+
+	var toCreateNames, toRecreateNames, updatedNames []string
+	for _, name := range sortManagerCacheNames(configs, false) {
+		config := configs[name]
+		curConfig, ok := m.configs[name]
+		switch {
+		case !ok:
+			toCreateNames = append(toCreateNames, name)
+		case isConfigEqual(curConfig, config):
+			continue
+		case config.isSingle(),
+			config.Type == Migration && curConfig.Migration.Primary == config.Migration.Primary &&
+				curConfig.Migration.Secondary == config.Migration.Secondary:
+			if err := m.updateCache(name, config); err != nil {
+				m.revertCaches(updatedNames)
+				return err
+			}
+			updatedNames = append(updatedNames, name)
+		default:
+			toRecreateNames = append(toRecreateNames, name)
+		}
+	}
+
+	createdCaches, err := m.newCaches(configs, append(toCreateNames, toRecreateNames...))
+	if err != nil {
+		m.revertCaches(updatedNames)
+		return err
+	}
+
+	// no error can occur from here on
+	for _, name := range updatedNames {
+		m.configs[name] = configs[name]
+	}
+	// remove composite caches first, so that no alive composite cache refers to a closed single cache
+	for _, name := range sortManagerCacheNames(m.configs, true) {
+		if _, ok := configs[name]; !ok {
+			_ = m.closeCache(context.Background(), name)
+		}
+	}
+	for _, name := range toRecreateNames {
+		_ = m.closeCache(context.Background(), name)
+	}
+	for name, c := range createdCaches {
+		m.configs[name] = configs[name]
+		m.caches[name] = c
+	}
+
+	return nil
+}
+
+// updateCache updates the cache of name in place with config
+func (m *Manager) updateCache(name string, config Config) error {
+	if config.isSingle() {
+		return m.caches[name].(composableCacheInner).loadWrapper().updateConfig(config)
+	}
+	return m.caches[name].(*MigrationCache).UpdateConfig(config.Migration)
+}
+
+// revertCaches reverts the caches of names updated in place to their recorded configs, in the best effort
+func (m *Manager) revertCaches(names []string) {
+	for _, name := range names {
+		_ = m.updateCache(name, m.configs[name])
+	}
+}
+
+// Close closes all the managed caches, should not perform any operations after close
+func (m *Manager) Close(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.isClosed {
+		return nil
+	}
+	m.isClosed = true
+
+	var err error
+	for _, name := range sortManagerCacheNames(m.configs, true) {
+		if closeErr := m.closeCache(ctx, name); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// createCaches creates caches of names in order, the caches a composite cache refers to must be created beforehand
+func (m *Manager) createCaches(configs map[string]Config, names []string) error {
+	for _, name := range names {
+		c, err := m.newCache(name, configs[name], m.caches)
+		if err != nil {
+			return err
+		}
+		m.configs[name] = configs[name]
+		m.caches[name] = c
+	}
+	return nil
+}
+
+// newCaches creates caches of names in order without managing them, the caches a composite cache refers to
+// are looked up from the caches created beforehand, then the managed caches.
+// If any cache fails to be created, the caches created are closed.
+func (m *Manager) newCaches(configs map[string]Config, names []string) (map[string]Cache, error) {
+	createdCaches := make(map[string]Cache, len(names))
+	referredCaches := make(map[string]Cache, len(m.caches)+len(names))
+	for name, c := range m.caches {
+		referredCaches[name] = c
+	}
+
+	for _, name := range names {
+		c, err := m.newCache(name, configs[name], referredCaches)
+		if err != nil {
+			closeCreatedCaches(createdCaches)
+			return nil, err
+		}
+		createdCaches[name] = c
+		referredCaches[name] = c
+	}
+	return createdCaches, nil
+}
+
+// closeCreatedCaches closes the caches not managed yet, composite caches first
+func closeCreatedCaches(caches map[string]Cache) {
+	for _, c := range caches {
+		if composite, ok := c.(compositeCache); ok {
+			composite.closeSelf()
+		}
+	}
+	for _, c := range caches {
+		if _, ok := c.(compositeCache); !ok {
+			_ = c.Close(context.Background())
+		}
+	}
+}
+
+// newCache creates the cache of name, the caches a composite cache refers to are looked up from caches
+func (m *Manager) newCache(name string, config Config, caches map[string]Cache) (Cache, error) {
+	switch config.Type {
+	case Redis:
+		return NewRedisCache(name, config.Redis)
+	case InMemory:
+		return NewInMemoryCache(name, config.InMemory)
+	case Memcached:
+		return NewMemcachedCache(name, config.Memcached)
+	case MultiLayer:
+		layers := make([]ComposableCache, 0, len(config.MultiLayer.Layers))
+		for _, layerName := range config.MultiLayer.Layers {
+			layers = append(layers, caches[layerName].(ComposableCache))
+		}
+		return NewMultiLayerCache(name, config.MultiLayer, layers...)
+	case Migration:
+		primary := caches[config.Migration.Primary].(ComposableCache)
+		secondary := caches[config.Migration.Secondary].(ComposableCache)
+		return NewMigrationCache(name, config.Migration, primary, secondary)
+	default:
+		return nil, errorConfigTypeNotSupported
+	}
+}
+
+// closeCache closes and removes the cache of name, the caches a composite cache refers to are kept open
+func (m *Manager) closeCache(ctx context.Context, name string) error {
+	c := m.caches[name]
+	delete(m.caches, name)
+	delete(m.configs, name)
+
+	if composite, ok := c.(compositeCache); ok {
+		composite.closeSelf()
+		return nil
+	}
+	return c.Close(ctx)
+}
+
+// validateManagerConfigs checks every config, and the caches referred by composite caches must be single caches in configs
+func validateManagerConfigs(configs map[string]Config) error {
+	for _, config := range configs {
+		if err := config.Validate(); err != nil {
+			return err
+		}
+		if !config.isComposite() {
+			continue
+		}
+
+		rawConfig, _ := config.RawConfig()
+		for _, cacheName := range rawConfig.(compositeCacheConfig).cacheNames() {
+			referredConfig, ok := configs[cacheName]
+			if !ok {
+				return errCacheNotExist
+			}
+			if !referredConfig.isSingle() {
+				return errNotSingleCache
+			}
+		}
+	}
+	return nil
+}
+
+// sortManagerCacheNames sorts the names of configs by name, with single caches first or composite caches first
+func sortManagerCacheNames(configs map[string]Config, compositeFirst bool) []string {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		iComposite, jComposite := configs[names[i]].isComposite(), configs[names[j]].isComposite()
+		if iComposite != jComposite {
+			return iComposite == compositeFirst
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// isConfigEqual reports whether the configs are deeply equal, with the functions (e.g. CostFunc of RistrettoCacheConfig
+// and the functions of TracerAdapter) regarded as equal if they are the same function, rather than never equal by reflect.DeepEqual.
+func isConfigEqual(a, b Config) bool {
+	return isValueEqual(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func isValueEqual(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	case reflect.Ptr:
+		if a.Pointer() == b.Pointer() {
+			return true
+		}
+		if a.IsNil() || b.IsNil() {
+			return false
+		}
+		return isValueEqual(a.Elem(), b.Elem())
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return isValueEqual(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !isValueEqual(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if a.IsNil() != b.IsNil() {
+			return false
+		}
+		fallthrough
+	case reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !isValueEqual(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() || a.Len() != b.Len() {
+			return false
+		}
+		iter := a.MapRange()
+		for iter.Next() {
+			bValue := b.MapIndex(iter.Key())
+			if !bValue.IsValid() || !isValueEqual(iter.Value(), bValue) {
+				return false
+			}
+		}
+		return true
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	}
+	return false
+}
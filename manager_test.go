@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-eCache/codec"
+)
+
+func newTestManagerConfigs(t *testing.T) map[string]Config {
+	server := newFakeRedisServer(t)
+	return map[string]Config{
+		"local":  {Type: InMemory, InMemory: InMemoryCacheConfig{CacheType: Ristretto}},
+		"remote": {Type: Redis, Redis: RedisConfig{Address: server.addr()}},
+		"layered": {Type: MultiLayer, MultiLayer: MultiLayerConfig{
+			Layers: []string{"local", "remote"},
+		}},
+	}
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	configs := newTestManagerConfigs(t)
+	m, err := NewManager(configs)
+	if err != nil {
+		t.Fatalf("new manager err: %v", err)
+	}
+
+	layered, err := m.Get("layered")
+	if err != nil {
+		t.Fatalf("get cache err: %v", err)
+	}
+	if err = layered.Set(ctx, "k", "v", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	remote, _ := m.Get("remote")
+	var val string
+	if err = remote.Get(ctx, "k", &val); err != nil || val != "v" {
+		t.Fatalf("expect layers shared with the manager, got %v, err: %v", val, err)
+	}
+	if _, err = m.Get("non_exist"); err != errCacheNotExist {
+		t.Fatalf("expect cache not exist, got: %v", err)
+	}
+
+	// update in place
+	remoteConfig := configs["remote"]
+	remoteConfig.Redis.DefaultExpirationSecs = 10
+	configs["remote"] = remoteConfig
+	// replace the multilayer cache by a migration cache
+	delete(configs, "layered")
+	configs["migration"] = Config{Type: Migration, Migration: MigrationConfig{
+		Mode: ReadPrimaryDualWrite, Primary: "local", Secondary: "remote",
+	}}
+	if err = m.UpdateConfig(configs); err != nil {
+		t.Fatalf("update config err: %v", err)
+	}
+	if updated, _ := m.Get("remote"); updated != remote || remote.(*RedisCache).loadInner().defaultExpiration != 10*time.Second {
+		t.Fatalf("expect redis cache updated in place")
+	}
+	if err = layered.Get(ctx, "k", &val); err != ErrCacheClosed {
+		t.Fatalf("expect removed cache closed, got: %v", err)
+	}
+	if err = remote.Get(ctx, "k", &val); err != nil || val != "v" {
+		t.Fatalf("expect layers of removed cache kept open, got %v, err: %v", val, err)
+	}
+	if _, err = m.Get("migration"); err != nil {
+		t.Fatalf("get cache err: %v", err)
+	}
+
+	// switch migration mode in place
+	migration, _ := m.Get("migration")
+	migrationConfig := configs["migration"]
+	migrationConfig.Migration.Mode = ShadowCompare
+	configs["migration"] = migrationConfig
+	if err = m.UpdateConfig(configs); err != nil {
+		t.Fatalf("update config err: %v", err)
+	}
+	if updated, _ := m.Get("migration"); updated != migration || migration.(*MigrationCache).loadConfig().Mode != ShadowCompare {
+		t.Fatalf("expect migration cache updated in place")
+	}
+
+	configs["remote"] = Config{Type: InMemory, InMemory: InMemoryCacheConfig{CacheType: Ristretto}}
+	if err = m.UpdateConfig(configs); err == nil {
+		t.Fatalf("expect error on changing cache type")
+	}
+
+	if err = m.Close(ctx); err != nil {
+		t.Fatalf("close err: %v", err)
+	}
+	if err = remote.Get(ctx, "k", &val); err != ErrCacheClosed {
+		t.Fatalf("expect caches closed with the manager, got: %v", err)
+	}
+	if _, err = m.Get("remote"); err != ErrCacheClosed {
+		t.Fatalf("expect manager closed, got: %v", err)
+	}
+}
+
+func TestManagerUpdateConfigFailed(t *testing.T) {
+	ctx := context.Background()
+	configs := newTestManagerConfigs(t)
+	m, err := NewManager(configs)
+	if err != nil {
+		t.Fatalf("new manager err: %v", err)
+	}
+	defer func() { _ = m.Close(ctx) }()
+	remote, _ := m.Get("remote")
+	layered, _ := m.Get("layered")
+
+	// the layers of the re-created multilayer cache mismatch in codec type after remote is updated
+	newConfigs := make(map[string]Config, len(configs))
+	for name, config := range configs {
+		newConfigs[name] = config
+	}
+	remoteConfig := newConfigs["remote"]
+	remoteConfig.Redis.DefaultExpirationSecs = 10
+	remoteConfig.Redis.CodecConfig.Type = codec.Gob
+	newConfigs["remote"] = remoteConfig
+	newConfigs["layered"] = Config{Type: MultiLayer, MultiLayer: MultiLayerConfig{Layers: []string{"remote", "local"}}}
+	newConfigs["created"] = Config{Type: InMemory, InMemory: InMemoryCacheConfig{CacheType: Ristretto}}
+	if err = m.UpdateConfig(newConfigs); err == nil {
+		t.Fatalf("expect error on creating multilayer cache with mismatched codec types")
+	}
+
+	if inner := remote.(*RedisCache).loadInner(); inner.defaultExpiration == 10*time.Second || inner.codecHandler.defaultCodecType != codec.JSON {
+		t.Fatalf("expect the cache updated in place reverted")
+	}
+	if c, err := m.Get("layered"); err != nil || c != layered {
+		t.Fatalf("expect the cache to re-create kept, got: %v, err: %v", c, err)
+	}
+	var val string
+	if err = layered.Get(ctx, "k", &val); err != ErrCacheMiss {
+		t.Fatalf("expect the cache to re-create kept open, got: %v", err)
+	}
+	if _, err = m.Get("created"); err != errCacheNotExist {
+		t.Fatalf("expect no cache created, got: %v", err)
+	}
+	if err = m.UpdateConfig(configs); err != nil {
+		t.Fatalf("update config err: %v", err)
+	}
+}
+
+func TestIsConfigEqual(t *testing.T) {
+	costFunc := func(val interface{}) int64 { return 1 }
+	newConfig := func(costFunc func(val interface{}) int64) Config {
+		return Config{Type: InMemory, InMemory: InMemoryCacheConfig{
+			CacheType:            Ristretto,
+			RistrettoCacheConfig: RistrettoCacheConfig{CostFunc: costFunc},
+		}}
+	}
+
+	if !isConfigEqual(newConfig(costFunc), newConfig(costFunc)) {
+		t.Fatalf("expect configs with the same function equal")
+	}
+	if isConfigEqual(newConfig(costFunc), newConfig(nil)) {
+		t.Fatalf("expect configs with different functions not equal")
+	}
+	config := newConfig(costFunc)
+	config.InMemory.MaxExpirationSecs = 10
+	if isConfigEqual(newConfig(costFunc), config) {
+		t.Fatalf("expect configs with different fields not equal")
+	}
+}
+
+func TestManagerConfigValidate(t *testing.T) {
+	configs := newTestManagerConfigs(t)
+	configs["layered"] = Config{Type: MultiLayer, MultiLayer: MultiLayerConfig{Layers: []string{"local", "non_exist"}}}
+	if _, err := NewManager(configs); err != errCacheNotExist {
+		t.Fatalf("expect cache not exist, got: %v", err)
+	}
+
+	configs["layered"] = Config{Type: MultiLayer, MultiLayer: MultiLayerConfig{Layers: []string{"local", "remote"}}}
+	configs["nested"] = Config{Type: MultiLayer, MultiLayer: MultiLayerConfig{Layers: []string{"local", "layered"}}}
+	if _, err := NewManager(configs); err != errNotSingleCache {
+		t.Fatalf("expect not single cache, got: %v", err)
+	}
+}
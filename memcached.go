@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+var _ Cache = (*MemcachedCache)(nil)
+
+// MemcachedCache implements Cache interface
+type MemcachedCache struct {
+	inner *cacheWrapper
+}
+
+// NewMemcachedCache creates a new memcached cache, connections are established lazily
+func NewMemcachedCache(name string, config MemcachedConfig) (*MemcachedCache, error) {
+	sc, err := newCacheWrapper(name, config.Config())
+	if err != nil {
+		return nil, err
+	}
+	return &MemcachedCache{inner: sc}, nil
+}
+
+// Get (refer to Get of Cache interface)
+func (c *MemcachedCache) Get(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) error {
+	return c.inner.get(ctx, key, receiver, opts...)
+}
+
+// GetWithMeta (refer to GetWithMeta of Cache interface)
+func (c *MemcachedCache) GetWithMeta(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) (ItemMeta, error) {
+	return c.inner.getWithMeta(ctx, cmdGetWithMeta, key, receiver, opts...)
+}
+
+// GetWithVersion (refer to GetWithVersion of Cache interface)
+func (c *MemcachedCache) GetWithVersion(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) (int64, error) {
+	meta, err := c.inner.getWithMeta(ctx, cmdGetWithVersion, key, receiver, opts...)
+	return meta.Version, err
+}
+
+// TTL (refer to TTL of Cache interface)
+func (c *MemcachedCache) TTL(ctx context.Context, key string, opts ...OperationOption) (ItemMeta, error) {
+	return c.inner.getWithMeta(ctx, cmdTTL, key, nil, opts...)
+}
+
+// GetMany (refer to GetMany of Cache interface)
+func (c *MemcachedCache) GetMany(ctx context.Context, receiverMap map[string]interface{}, opts ...OperationOption) error {
+	return c.inner.getMany(ctx, receiverMap, opts...)
+}
+
+// Set (refer to Set of Cache interface)
+func (c *MemcachedCache) Set(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error {
+	return c.inner.set(ctx, key, value, expire, opts...)
+}
+
+// SetMany (refer to SetMany of Cache interface)
+// By default, `NoReply` is applied so that failures of individual items are not reported, refer to WithNoReply.
+func (c *MemcachedCache) SetMany(ctx context.Context, valueMap map[string]interface{}, expire time.Duration, opts ...OperationOption) error {
+	return c.inner.setMany(ctx, valueMap, expire, opts...)
+}
+
+// CompareAndSwap (refer to CompareAndSwap of Cache interface)
+func (c *MemcachedCache) CompareAndSwap(ctx context.Context, key string, value interface{}, version int64, expire time.Duration, opts ...OperationOption) error {
+	return c.inner.compareAndSwap(ctx, key, value, version, expire, opts...)
+}
+
+// Delete (refer to Delete of Cache interface)
+func (c *MemcachedCache) Delete(ctx context.Context, key string, opts ...OperationOption) error {
+	return c.inner.delete(ctx, key, opts...)
+}
+
+// DeleteMany (refer to DeleteMany of Cache interface)
+// By default, `NoReply` is applied so that failures of individual items are not reported, refer to WithNoReply.
+func (c *MemcachedCache) DeleteMany(ctx context.Context, keys []string, opts ...OperationOption) error {
+	return c.inner.deleteMany(ctx, keys, opts...)
+}
+
+// InvalidateTags (refer to InvalidateTags of Cache interface)
+func (c *MemcachedCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	return c.inner.invalidateTags(ctx, tags)
+}
+
+// Add (refer to Add of Cache interface)
+func (c *MemcachedCache) Add(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error {
+	return c.inner.add(ctx, key, value, expire, opts...)
+}
+
+// Replace (refer to Replace of Cache interface)
+func (c *MemcachedCache) Replace(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error {
+	return c.inner.replace(ctx, key, value, expire, opts...)
+}
+
+// Increment (refer to Increment of Cache interface)
+func (c *MemcachedCache) Increment(ctx context.Context, key string, delta uint64, opts ...OperationOption) (int64, error) {
+	return c.inner.increment(ctx, key, delta, opts...)
+}
+
+// Decrement (refer to Decrement of Cache interface)
+func (c *MemcachedCache) Decrement(ctx context.Context, key string, delta uint64, opts ...OperationOption) (int64, error) {
+	return c.inner.decrement(ctx, key, delta, opts...)
+}
+
+// Expire (refer to Expire of Cache interface)
+func (c *MemcachedCache) Expire(ctx context.Context, key string, expire time.Duration, opts ...OperationOption) error {
+	return c.inner.expire(ctx, key, expire, opts...)
+}
+
+// Load (refer to Load of Cache interface)
+func (c *MemcachedCache) Load(ctx context.Context, loader DataLoader, key string, receiver interface{}, expire time.Duration, opts ...OperationOption) error {
+	return c.inner.load(ctx, loader, key, receiver, expire, opts...)
+}
+
+// LoadMany (refer to LoadMany of Cache interface)
+func (c *MemcachedCache) LoadMany(ctx context.Context, loader DataLoader, receiverMap map[string]interface{}, expire time.Duration, opts ...OperationOption) error {
+	return c.inner.loadMany(ctx, loader, receiverMap, expire, opts...)
+}
+
+// Flush (refer to Flush of Cache interface)
+func (c *MemcachedCache) Flush(ctx context.Context) error {
+	return c.inner.flush(ctx)
+}
+
+// Ping (refer to Ping of Cache interface)
+func (c *MemcachedCache) Ping(ctx context.Context) error {
+	return c.inner.ping(ctx)
+}
+
+// Close releases all open resources
+func (c *MemcachedCache) Close(ctx context.Context) error {
+	return c.inner.close()
+}
+
+// HotKeys returns the detected hot keys in descending order of frequency, nil if hot key detection is disabled
+func (c *MemcachedCache) HotKeys() []HotKey {
+	return c.loadInner().hotKeyHandler.getHotKeys()
+}
+
+// UpdateConfig updates current memcached cache based on config
+func (c *MemcachedCache) UpdateConfig(config MemcachedConfig) error {
+	return c.inner.updateConfig(Config{
+		Type:      Memcached,
+		Memcached: config,
+	})
+}
+
+func (c *MemcachedCache) loadInner() *cacheWrapperInner {
+	return (*cacheWrapperInner)(atomic.LoadPointer(&c.inner.inner))
+}
+
+func (c *MemcachedCache) loadWrapper() *cacheWrapper {
+	return c.inner
+}
+
+func (c *MemcachedCache) getCacheType() cacheType {
+	return memcached
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go-eCache/codec"
+	memcachedclient "go-eCache/internal/client/memcached"
+)
+
+const (
+	// defaultMemcachedExpiration defines default expiration for memcached cache if not set by client explicitly
+	defaultMemcachedExpiration = time.Duration(86400) * time.Second
+
+	defaultMemcachedMaxIdle           = 10
+	defaultMemcachedDialTimeoutMillis = 1000
+	defaultMemcachedTimeoutMillis     = 500
+
+	// memcachedMaxKeyLength is the key length limit of memcached, which is the default KeyConfig.MaxKeyLength of MemcachedCache
+	memcachedMaxKeyLength = 250
+)
+
+// MemcachedPoolConfig defines the connection pool behavior of MemcachedCache
+type MemcachedPoolConfig struct {
+	// MaxIdle is the maximum number of idle connections kept for each server. Default value is 10.
+	MaxIdle int `yaml:"max_idle" json:"max_idle"`
+
+	// DialTimeoutMillis is the timeout for establishing new connections. Default value is 1000.
+	DialTimeoutMillis int `yaml:"dial_timeout_millis" json:"dial_timeout_millis"`
+
+	// TimeoutMillis is the read/write timeout of a single request, the deadline of ctx takes effect if it is earlier.
+	// Default value is 500.
+	TimeoutMillis int `yaml:"timeout_millis" json:"timeout_millis"`
+}
+
+// Validate checks if the MemcachedPoolConfig is valid.
+func (c MemcachedPoolConfig) Validate() error {
+	if c.MaxIdle < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_max_idle: %v", c.MaxIdle))
+	}
+	if c.DialTimeoutMillis < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_dial_timeout_millis: %v", c.DialTimeoutMillis))
+	}
+	if c.TimeoutMillis < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_timeout_millis: %v", c.TimeoutMillis))
+	}
+	return nil
+}
+
+func (c *MemcachedPoolConfig) setDefaultValue() {
+	if c.MaxIdle == 0 {
+		c.MaxIdle = defaultMemcachedMaxIdle
+	}
+	if c.DialTimeoutMillis == 0 {
+		c.DialTimeoutMillis = defaultMemcachedDialTimeoutMillis
+	}
+	if c.TimeoutMillis == 0 {
+		c.TimeoutMillis = defaultMemcachedTimeoutMillis
+	}
+}
+
+// MemcachedConfig defines config used to construct a MemcachedCache
+type MemcachedConfig struct {
+	// Addresses is the list of `host:port` of memcached servers, keys are distributed among them by hash
+	Addresses []string `yaml:"addresses" json:"addresses"`
+
+	// PoolConfig defines the connection pool behavior
+	PoolConfig MemcachedPoolConfig `yaml:"pool_config" json:"pool_config"`
+
+	// DefaultExpirationSecs defines default cache data expiration in seconds. If zero, default value 86400 is used.
+	DefaultExpirationSecs int `yaml:"default_expiration_secs" json:"default_expiration_secs"`
+
+	// MaxExpirationSecs defines the max expiration of a key in cache
+	// Default value is 0, same as not set, means no max expiration
+	// It has higher priority than DefaultExpirationSecs, but lower priority than NoExpiration.
+	MaxExpirationSecs int `yaml:"max_expiration_secs" json:"max_expiration_secs"`
+
+	// CodecConfig is the config to control default codec type
+	CodecConfig codec.Config `yaml:"codec_config" json:"codec_config"`
+
+	// EncodingConfig is the config to control built in bytes protocol and compression
+	EncodingConfig EncodingConfig `yaml:"encoding_config" json:"encoding_config"`
+
+	// ManufacturerConfig defines the behavior for `Load` and `LoadMany`
+	ManufacturerConfig ManufacturerConfig `yaml:"manufacturer_config" json:"manufacturer_config"`
+
+	// KeyConfig defines how keys are transformed before being sent to the cache e.g. namespacing
+	KeyConfig KeyConfig `yaml:"key_config" json:"key_config"`
+
+	// DisableConfig defines whether the cache is disabled, refer to DisableConfig for the behavior of operations
+	DisableConfig DisableConfig `yaml:"disable_config" json:"disable_config"`
+
+	// ObservationConfig defines how the operations of the cache are observed e.g. stats collection
+	ObservationConfig ObservationConfig `yaml:"observation_config" json:"observation_config"`
+
+	// HotKeyConfig defines the detection of hot keys
+	HotKeyConfig HotKeyConfig `yaml:"hot_key_config" json:"hot_key_config"`
+}
+
+// Validate checks if config is valid
+func (c MemcachedConfig) Validate() error {
+	if len(c.Addresses) == 0 {
+		return cacheErr("invalid_config_addresses_empty")
+	}
+	for _, address := range c.Addresses {
+		if address == "" {
+			return cacheErr("invalid_config_address_empty")
+		}
+	}
+	if c.DefaultExpirationSecs < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_default_expiration_secs: %v", c.DefaultExpirationSecs))
+	}
+	if c.MaxExpirationSecs < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_max_expiration_secs: %v", c.MaxExpirationSecs))
+	}
+	if err := c.PoolConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.CodecConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.EncodingConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.ManufacturerConfig.Validate(Memcached); err != nil {
+		return err
+	}
+	keyConfig := c.KeyConfig
+	if keyConfig.MaxKeyLength == 0 {
+		keyConfig.MaxKeyLength = memcachedMaxKeyLength
+	}
+	if err := keyConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.ObservationConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.HotKeyConfig.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Config wraps this MemcachedConfig in a generic Config struct.
+func (c MemcachedConfig) Config() Config {
+	return Config{
+		Type:      Memcached,
+		Memcached: c,
+	}
+}
+
+func (c MemcachedConfig) defaultExpiration() time.Duration {
+	configDefaultExpire := time.Duration(c.DefaultExpirationSecs) * time.Second
+	if configDefaultExpire == DefaultExpiration {
+		return defaultMemcachedExpiration
+	}
+	return configDefaultExpire
+}
+
+func (c MemcachedConfig) hostName() string {
+	return strings.Join(c.Addresses, ",")
+}
+
+// clientConfig converts MemcachedConfig to the config of internal memcached client
+func (c MemcachedConfig) clientConfig() memcachedclient.Config {
+	poolConfig := c.PoolConfig
+	poolConfig.setDefaultValue()
+
+	return memcachedclient.Config{
+		Addresses:   append([]string(nil), c.Addresses...),
+		MaxIdle:     poolConfig.MaxIdle,
+		DialTimeout: time.Duration(poolConfig.DialTimeoutMillis) * time.Millisecond,
+		Timeout:     time.Duration(poolConfig.TimeoutMillis) * time.Millisecond,
+	}
+}
@@ -0,0 +1,299 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	memcachedclient "go-eCache/internal/client/memcached"
+)
+
+// maxMemcachedRelativeExpiration is the max expiration memcached treats as relative time,
+// larger expiration must be sent as an absolute unix timestamp
+const maxMemcachedRelativeExpiration = 30 * 24 * time.Hour
+
+// memcachedCacheInner is a wrapper of memcached client which impl innerCache Interface
+type memcachedCacheInner struct {
+	client       unsafe.Pointer // of type *memcachedclient.Client
+	clientConfig memcachedclient.Config
+}
+
+func newMemcachedCache(config MemcachedConfig) (*memcachedCacheInner, error) {
+	clientConfig := config.clientConfig()
+
+	return &memcachedCacheInner{
+		client:       unsafe.Pointer(memcachedclient.NewClient(clientConfig)),
+		clientConfig: clientConfig,
+	}, nil
+}
+
+func (c *memcachedCacheInner) get(ctx context.Context, key string) (interface{}, error) {
+	items, err := c.loadClient().Get(ctx, key)
+	if err != nil {
+		return nil, convertMemcachedErr(err)
+	}
+
+	item, ok := items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+
+	return item.Value, nil
+}
+
+func (c *memcachedCacheInner) getMany(ctx context.Context, keys ...string) ([]interface{}, error) {
+	items, err := c.loadClient().Get(ctx, keys...)
+	if err != nil {
+		return nil, convertMemcachedErr(err)
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if item, ok := items[key]; ok {
+			values[i] = item.Value
+		}
+	}
+
+	return values, nil
+}
+
+func (c *memcachedCacheInner) set(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...innerOperationOption) error {
+	item, err := newMemcachedItem(key, value, expire)
+	if err != nil {
+		return err
+	}
+
+	return convertMemcachedErr(c.loadClient().Set(ctx, item))
+}
+
+func (c *memcachedCacheInner) setMany(ctx context.Context, valueMap map[string]interface{}, expire time.Duration, opts ...innerOperationOption) error {
+	options := newInnerCacheOperationOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	items := make([]*memcachedclient.Item, 0, len(valueMap))
+	for key, value := range valueMap {
+		expiration := expire
+		if exp, ok := options.expirationMap[key]; ok {
+			expiration = exp
+		}
+		item, err := newMemcachedItem(key, value, expiration)
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+
+	return convertMemcachedErr(c.loadClient().SetMany(ctx, items, options.noReply))
+}
+
+func (c *memcachedCacheInner) add(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...innerOperationOption) error {
+	item, err := newMemcachedItem(key, value, expire)
+	if err != nil {
+		return err
+	}
+
+	return convertMemcachedErr(c.loadClient().Add(ctx, item))
+}
+
+func (c *memcachedCacheInner) replace(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...innerOperationOption) error {
+	item, err := newMemcachedItem(key, value, expire)
+	if err != nil {
+		return err
+	}
+
+	return convertMemcachedErr(c.loadClient().Replace(ctx, item))
+}
+
+// compareAndSwap applies the native compare-and-swap of memcached with the CAS token read along with the current value
+func (c *memcachedCacheInner) compareAndSwap(ctx context.Context, key string, match func(value interface{}) bool, value interface{}, expire time.Duration, opts ...innerOperationOption) error {
+	item, err := newMemcachedItem(key, value, expire)
+	if err != nil {
+		return err
+	}
+
+	client := c.loadClient()
+	items, err := client.Get(ctx, key)
+	if err != nil {
+		return convertMemcachedErr(err)
+	}
+	cur, ok := items[key]
+	if !ok || !match(cur.Value) {
+		return ErrNotStored
+	}
+	item.CasID = cur.CasID
+
+	return convertMemcachedErr(client.CompareAndSwap(ctx, item))
+}
+
+// nolint:predeclared
+func (c *memcachedCacheInner) delete(ctx context.Context, key string) error {
+	return convertMemcachedErr(c.loadClient().Delete(ctx, key))
+}
+
+func (c *memcachedCacheInner) deleteMany(ctx context.Context, keys []string, opts ...innerOperationOption) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	options := newInnerCacheOperationOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return convertMemcachedErr(c.loadClient().DeleteMany(ctx, keys, options.noReply))
+}
+
+func (c *memcachedCacheInner) deleteManyWithResult(ctx context.Context, keys []string) ([]bool, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	existed, err := c.loadClient().DeleteManyWithResult(ctx, keys)
+	if err != nil {
+		return nil, convertMemcachedErr(err)
+	}
+	return existed, nil
+}
+
+func (c *memcachedCacheInner) increment(ctx context.Context, key string, delta uint64, opts ...innerOperationOption) (int64, error) {
+	return c.incrDecr(ctx, key, delta, true, opts...)
+}
+
+// decrement decrements the value of key. Memcached stores counters as unsigned integers,
+// so the value is capped at zero and a non-exist key is initialized with 0 if `initNonExistKey` is applied.
+func (c *memcachedCacheInner) decrement(ctx context.Context, key string, delta uint64, opts ...innerOperationOption) (int64, error) {
+	return c.incrDecr(ctx, key, delta, false, opts...)
+}
+
+// incrDecr performs incr or decr. If the key does not exist and `initNonExistKey` is applied,
+// the key is created by `add` and the operation is retried once if another client created it in between.
+func (c *memcachedCacheInner) incrDecr(ctx context.Context, key string, delta uint64, isIncr bool, opts ...innerOperationOption) (int64, error) {
+	options := newInnerCacheOperationOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	client := c.loadClient()
+	do := client.Decrement
+	if isIncr {
+		do = client.Increment
+	}
+
+	val, err := do(ctx, key, delta)
+	if err == memcachedclient.ErrCacheMiss && options.initNonExistKey {
+		initVal := uint64(0)
+		if isIncr {
+			initVal = delta
+		}
+		err = client.Add(ctx, &memcachedclient.Item{Key: key, Value: []byte(strconv.FormatUint(initVal, 10))})
+		if err == nil {
+			return convertMemcachedCounter(initVal)
+		}
+		if err == memcachedclient.ErrNotStored {
+			val, err = do(ctx, key, delta)
+		}
+	}
+	if err != nil {
+		return 0, convertMemcachedErr(err)
+	}
+
+	return convertMemcachedCounter(val)
+}
+
+func (c *memcachedCacheInner) expire(ctx context.Context, key string, expire time.Duration, opts ...innerOperationOption) error {
+	return convertMemcachedErr(c.loadClient().Touch(ctx, key, memcachedExpiration(expire)))
+}
+
+func (c *memcachedCacheInner) flush(ctx context.Context) error {
+	return convertMemcachedErr(c.loadClient().FlushAll(ctx))
+}
+
+func (c *memcachedCacheInner) ping(ctx context.Context) error {
+	return convertMemcachedErr(c.loadClient().Ping(ctx))
+}
+
+// rawClient returns the underlying *memcachedclient.Client
+func (c *memcachedCacheInner) rawClient() interface{} {
+	return c.loadClient()
+}
+
+// nolint:predeclared
+func (c *memcachedCacheInner) close() error {
+	return c.loadClient().Close()
+}
+
+func (c *memcachedCacheInner) loadClient() *memcachedclient.Client {
+	return (*memcachedclient.Client)(atomic.LoadPointer(&c.client))
+}
+
+// updateConfig replaces the client if any connection related config is changed.
+// The old client is closed, its connections in use are closed once returned.
+func (c *memcachedCacheInner) updateConfig(newConfig MemcachedConfig) error {
+	clientConfig := newConfig.clientConfig()
+	if reflect.DeepEqual(clientConfig, c.clientConfig) {
+		return nil
+	}
+
+	oldClient := c.loadClient()
+	atomic.StorePointer(&c.client, unsafe.Pointer(memcachedclient.NewClient(clientConfig)))
+	c.clientConfig = clientConfig
+
+	return oldClient.Close()
+}
+
+func newMemcachedItem(key string, value interface{}, expire time.Duration) (*memcachedclient.Item, error) {
+	b, ok := value.([]byte)
+	if !ok {
+		return nil, cacheErr("data_to_memcached_is_not_bytes")
+	}
+
+	return &memcachedclient.Item{
+		Key:        key,
+		Value:      b,
+		Expiration: memcachedExpiration(expire),
+	}, nil
+}
+
+// memcachedExpiration converts expire to memcached expiration in seconds.
+// Sub-second expiration is rounded up to avoid being treated as no expiration,
+// and expiration longer than 30 days is converted to an absolute unix timestamp.
+func memcachedExpiration(expire time.Duration) int32 {
+	if expire <= 0 {
+		return 0
+	}
+	if expire > maxMemcachedRelativeExpiration {
+		return int32(time.Now().Add(expire).Unix())
+	}
+
+	secs := int32(expire / time.Second)
+	if expire%time.Second != 0 {
+		secs++
+	}
+	return secs
+}
+
+func convertMemcachedCounter(val uint64) (int64, error) {
+	if val > math.MaxInt64 {
+		return 0, cacheErr("memcached_counter_overflow")
+	}
+	return int64(val), nil
+}
+
+// convertMemcachedErr converts errors of memcached client to the errors defined by this package
+func convertMemcachedErr(err error) error {
+	switch err {
+	case memcachedclient.ErrCacheMiss:
+		return ErrCacheMiss
+	case memcachedclient.ErrNotStored, memcachedclient.ErrCASConflict:
+		return ErrNotStored
+	case memcachedclient.ErrMalformedKey:
+		return errMemcachedMalformedKey
+	}
+	return err
+}
@@ -0,0 +1,402 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMemcachedServer is an in-process memcached text protocol stand-in which supports the commands used by MemcachedCache
+type fakeMemcachedServer struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	data    map[string][]byte
+	cas     map[string]uint64
+	expires map[string]time.Time
+	nextCas uint64
+	// replies counts the replies written, to verify `noreply` is sent
+	replies int
+}
+
+func newFakeMemcachedServer(t *testing.T) *fakeMemcachedServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen err: %v", err)
+	}
+
+	s := &fakeMemcachedServer{
+		listener: listener,
+		data:     make(map[string][]byte),
+		cas:      make(map[string]uint64),
+		expires:  make(map[string]time.Time),
+	}
+	go s.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+
+	return s
+}
+
+func (s *fakeMemcachedServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeMemcachedServer) replyCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replies
+}
+
+func (s *fakeMemcachedServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeMemcachedServer) handle(conn net.Conn) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	bw := bufio.NewWriter(conn)
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+
+		var value []byte
+		switch args[0] {
+		case "set", "add", "replace", "cas":
+			size, _ := strconv.Atoi(args[4])
+			value = make([]byte, size+2)
+			if _, err = readFullFake(br, value); err != nil {
+				return
+			}
+			value = value[:size]
+		}
+
+		s.exec(bw, args, value)
+		if br.Buffered() == 0 {
+			if err = bw.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// lookup returns the value of an unexpired key, must be called with lock held
+func (s *fakeMemcachedServer) lookup(key string) ([]byte, bool) {
+	if exp, ok := s.expires[key]; ok && !time.Now().Before(exp) {
+		delete(s.data, key)
+		delete(s.expires, key)
+	}
+	val, ok := s.data[key]
+	return val, ok
+}
+
+// store must be called with lock held
+func (s *fakeMemcachedServer) store(key string, value []byte, exptime int) {
+	s.data[key] = value
+	s.nextCas++
+	s.cas[key] = s.nextCas
+	delete(s.expires, key)
+	if exptime > 0 {
+		s.expires[key] = time.Now().Add(time.Duration(exptime) * time.Second)
+	}
+}
+
+// nolint:funlen,gocyclo
+func (s *fakeMemcachedServer) exec(bw *bufio.Writer, args []string, value []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	noReply := args[len(args)-1] == "noreply"
+	reply := func(line string) {
+		if !noReply {
+			s.replies++
+			bw.WriteString(line + "\r\n")
+		}
+	}
+
+	switch args[0] {
+	case "get", "gets":
+		for _, key := range args[1:] {
+			if val, ok := s.lookup(key); ok {
+				bw.WriteString("VALUE " + key + " 0 " + strconv.Itoa(len(val)) + " " + strconv.FormatUint(s.cas[key], 10) + "\r\n")
+				bw.Write(val)
+				bw.WriteString("\r\n")
+			}
+		}
+		reply("END")
+	case "set", "add", "replace", "cas":
+		key := args[1]
+		exptime, _ := strconv.Atoi(args[3])
+		_, exists := s.lookup(key)
+		switch {
+		case args[0] == "add" && exists, args[0] == "replace" && !exists:
+			reply("NOT_STORED")
+			return
+		case args[0] == "cas" && !exists:
+			reply("NOT_FOUND")
+			return
+		case args[0] == "cas" && args[5] != strconv.FormatUint(s.cas[key], 10):
+			reply("EXISTS")
+			return
+		}
+		s.store(key, value, exptime)
+		reply("STORED")
+	case "delete":
+		if _, ok := s.lookup(args[1]); !ok {
+			reply("NOT_FOUND")
+			return
+		}
+		delete(s.data, args[1])
+		delete(s.expires, args[1])
+		reply("DELETED")
+	case "incr", "decr":
+		val, ok := s.lookup(args[1])
+		if !ok {
+			reply("NOT_FOUND")
+			return
+		}
+		cur, err := strconv.ParseUint(string(val), 10, 64)
+		if err != nil {
+			reply("CLIENT_ERROR cannot increment or decrement non-numeric value")
+			return
+		}
+		delta, _ := strconv.ParseUint(args[2], 10, 64)
+		if args[0] == "incr" {
+			cur += delta
+		} else if delta > cur {
+			cur = 0
+		} else {
+			cur -= delta
+		}
+		s.data[args[1]] = []byte(strconv.FormatUint(cur, 10))
+		reply(strconv.FormatUint(cur, 10))
+	case "touch":
+		if _, ok := s.lookup(args[1]); !ok {
+			reply("NOT_FOUND")
+			return
+		}
+		exptime, _ := strconv.Atoi(args[2])
+		delete(s.expires, args[1])
+		if exptime > 0 {
+			s.expires[args[1]] = time.Now().Add(time.Duration(exptime) * time.Second)
+		}
+		reply("TOUCHED")
+	case "flush_all":
+		s.data = make(map[string][]byte)
+		s.expires = make(map[string]time.Time)
+		reply("OK")
+	case "version":
+		reply("VERSION 1.6.0-fake")
+	default:
+		reply("ERROR")
+	}
+}
+
+func newTestMemcachedCache(t *testing.T) (*MemcachedCache, *fakeMemcachedServer) {
+	server := newFakeMemcachedServer(t)
+	c, err := NewMemcachedCache("test_memcached", MemcachedConfig{Addresses: []string{server.addr()}})
+	if err != nil {
+		t.Fatalf("new memcached cache err: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+	return c, server
+}
+
+func TestMemcachedCacheGetSet(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestMemcachedCache(t)
+
+	msg := "hello"
+	if err := c.Set(ctx, "k1", &cacheResp{Id: 1, Msg: "one", PointerMsg: &msg}, time.Minute); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	var resp cacheResp
+	if err := c.Get(ctx, "k1", &resp); err != nil {
+		t.Fatalf("get err: %v", err)
+	}
+	if resp.Id != 1 || resp.Msg != "one" || resp.PointerMsg == nil || *resp.PointerMsg != msg {
+		t.Fatalf("unexpected resp: %+v", resp)
+	}
+	if err := c.Get(ctx, "not_exist", &resp); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss, got: %v", err)
+	}
+
+	// no reply is expected from server for SetMany by default
+	replies := server.replyCount()
+	if err := c.SetMany(ctx, map[string]interface{}{"k2": 2, "k3": 3}, time.Minute); err != nil {
+		t.Fatalf("set many err: %v", err)
+	}
+	if n := server.replyCount(); n != replies {
+		t.Fatalf("expect no reply for set many with noreply, got %v", n-replies)
+	}
+	if err := c.SetMany(ctx, map[string]interface{}{"k4": 4}, time.Minute, WithNoReply(false)); err != nil {
+		t.Fatalf("set many err: %v", err)
+	}
+	if n := server.replyCount(); n != replies+1 {
+		t.Fatalf("expect one reply for set many without noreply, got %v", n-replies)
+	}
+
+	var v2, v3, v4, v5 int
+	receiverMap := map[string]interface{}{"k2": &v2, "k3": &v3, "k4": &v4, "k5": &v5}
+	if err := c.GetMany(ctx, receiverMap); err != nil {
+		t.Fatalf("get many err: %v", err)
+	}
+	if v2 != 2 || v3 != 3 || v4 != 4 || receiverMap["k5"] != nil {
+		t.Fatalf("unexpected get many result: %v, %v, %v, %v", v2, v3, v4, receiverMap["k5"])
+	}
+
+	if err := c.Delete(ctx, "k2"); err != nil {
+		t.Fatalf("delete err: %v", err)
+	}
+	if err := c.Delete(ctx, "k2"); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss for deleted key, got: %v", err)
+	}
+
+	inner := c.loadInner().cache
+	replies = server.replyCount()
+	if err := inner.deleteMany(ctx, []string{"k3", "k4"}, withNoReply(true)); err != nil {
+		t.Fatalf("delete many err: %v", err)
+	}
+	if n := server.replyCount(); n != replies {
+		t.Fatalf("expect no reply for delete many with noreply, got %v", n-replies)
+	}
+	if err := c.Get(ctx, "k3", &v3); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss after delete many, got: %v", err)
+	}
+}
+
+func TestMemcachedCacheConditionalOperations(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestMemcachedCache(t)
+
+	if err := c.Replace(ctx, "k", "v1", time.Minute); err != ErrNotStored {
+		t.Fatalf("expect not stored on replacing non-exist key, got: %v", err)
+	}
+	if err := c.Add(ctx, "k", "v1", time.Minute); err != nil {
+		t.Fatalf("add err: %v", err)
+	}
+	if err := c.Add(ctx, "k", "v2", time.Minute); err != ErrNotStored {
+		t.Fatalf("expect not stored on adding exist key, got: %v", err)
+	}
+	if err := c.Replace(ctx, "k", "v3", time.Minute); err != nil {
+		t.Fatalf("replace err: %v", err)
+	}
+	var val string
+	if err := c.Get(ctx, "k", &val); err != nil || val != "v3" {
+		t.Fatalf("unexpected value %v, err: %v", val, err)
+	}
+
+	if _, err := c.Increment(ctx, "counter", 1, WithInitNonExistKey(false)); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss on incrementing non-exist key, got: %v", err)
+	}
+	if n, err := c.Increment(ctx, "counter", 5); err != nil || n != 5 {
+		t.Fatalf("unexpected increment result %v, err: %v", n, err)
+	}
+	if n, err := c.Increment(ctx, "counter", 2); err != nil || n != 7 {
+		t.Fatalf("unexpected increment result %v, err: %v", n, err)
+	}
+	if n, err := c.Decrement(ctx, "counter", 3); err != nil || n != 4 {
+		t.Fatalf("unexpected decrement result %v, err: %v", n, err)
+	}
+	if n, err := c.Decrement(ctx, "another_counter", 3); err != nil || n != 0 {
+		t.Fatalf("unexpected decrement result on non-exist key %v, err: %v", n, err)
+	}
+
+	if err := c.Expire(ctx, "not_exist", time.Minute); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss on expiring non-exist key, got: %v", err)
+	}
+	if err := c.Expire(ctx, "k", time.Minute); err != nil {
+		t.Fatalf("expire err: %v", err)
+	}
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("ping err: %v", err)
+	}
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("flush err: %v", err)
+	}
+	if err := c.Get(ctx, "k", &val); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss after flush, got: %v", err)
+	}
+}
+
+func TestMemcachedExpiration(t *testing.T) {
+	if exp := memcachedExpiration(NoExpiration); exp != 0 {
+		t.Fatalf("expect 0 for no expiration, got %v", exp)
+	}
+	if exp := memcachedExpiration(100 * time.Millisecond); exp != 1 {
+		t.Fatalf("expect sub-second expiration rounded up to 1, got %v", exp)
+	}
+	if exp := memcachedExpiration(time.Hour); exp != 3600 {
+		t.Fatalf("expect relative expiration 3600, got %v", exp)
+	}
+	exp := memcachedExpiration(60 * 24 * time.Hour)
+	if expected := time.Now().Add(60 * 24 * time.Hour).Unix(); int64(exp) < expected-1 || int64(exp) > expected+1 {
+		t.Fatalf("expect absolute timestamp around %v, got %v", expected, exp)
+	}
+}
+
+func TestMemcachedCacheLongKey(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestMemcachedCache(t)
+
+	// keys longer than the key length limit of memcached are hashed by default
+	longKey := strings.Repeat("k", 300)
+	if err := c.Set(ctx, longKey, "v", time.Minute); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	var val string
+	if err := c.Get(ctx, longKey, &val); err != nil || val != "v" {
+		t.Fatalf("expect value got by long key, got: %v, err: %v", val, err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	for key := range server.data {
+		if len(key) > memcachedMaxKeyLength {
+			t.Fatalf("expect long key hashed, got: %v", key)
+		}
+	}
+}
+
+func TestMemcachedCacheDeleteMany(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestMemcachedCache(t)
+
+	if err := c.SetMany(ctx, map[string]interface{}{"k1": 1, "k2": 2, "k3": 3}, time.Minute, WithNoReply(false)); err != nil {
+		t.Fatalf("set many err: %v", err)
+	}
+	if err := c.DeleteMany(ctx, []string{"k1"}, WithNoReply(false)); err != nil {
+		t.Fatalf("delete many err: %v", err)
+	}
+	if _, ok := server.lookup("k1"); ok {
+		t.Fatalf("expect k1 deleted")
+	}
+
+	deletedKeys := make(map[string]bool)
+	if err := c.DeleteMany(ctx, []string{"k1", "k2"}, WithDeletedKeys(deletedKeys)); err != nil {
+		t.Fatalf("delete many err: %v", err)
+	}
+	if len(deletedKeys) != 2 || deletedKeys["k1"] || !deletedKeys["k2"] {
+		t.Fatalf("expect only k2 existed, got: %v", deletedKeys)
+	}
+	if _, ok := server.lookup("k3"); !ok {
+		t.Fatalf("expect k3 kept")
+	}
+}
@@ -0,0 +1,616 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"go-eCache/internal/utils"
+	"go-eCache/internal/xcontext"
+)
+
+// migrationCacheTypeName is the cache type reported in RequestStats by MigrationCache
+const migrationCacheTypeName = "migration"
+
+var _ Cache = (*MigrationCache)(nil)
+
+// MigrationCache implements Cache interface, it wraps a primary and a secondary cache,
+// so that traffic can be moved from one cache backend or configuration to another without a cold start.
+// Refer to MigrationMode for how reads and writes are routed.
+type MigrationCache struct {
+	name      string
+	config    unsafe.Pointer // of type *MigrationConfig
+	primary   ComposableCache
+	secondary ComposableCache
+	isClosed  uint32
+}
+
+// NewMigrationCache creates a new migration cache, primary and secondary must match the names in config.
+// Both caches must use the same codec type, since data is passed between them in bytes.
+func NewMigrationCache(name string, config MigrationConfig, primary, secondary ComposableCache) (*MigrationCache, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if primary == nil || secondary == nil {
+		return nil, cacheErr("migration_cache_is_nil")
+	}
+	if err := checkMigrationCaches(config, primary, secondary); err != nil {
+		return nil, err
+	}
+
+	primaryInner, secondaryInner := primary.loadInner(), secondary.loadInner()
+	if primaryInner.codecHandler.defaultCodecType != secondaryInner.codecHandler.defaultCodecType {
+		return nil, cacheErr("migration_cache_codec_type_not_match")
+	}
+
+	return &MigrationCache{
+		name:      name,
+		config:    unsafe.Pointer(&config),
+		primary:   primary,
+		secondary: secondary,
+	}, nil
+}
+
+func checkMigrationCaches(config MigrationConfig, primary, secondary ComposableCache) error {
+	if name := primary.loadInner().name; name != config.Primary {
+		return cacheErr("migration_primary_cache_name_not_match: " + name)
+	}
+	if name := secondary.loadInner().name; name != config.Secondary {
+		return cacheErr("migration_secondary_cache_name_not_match: " + name)
+	}
+	return nil
+}
+
+// Get (refer to Get of Cache interface)
+func (c *MigrationCache) Get(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	option := newCacheOperationOptions()
+	defer recycleCacheOperationOptions(option)
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	receiverMap := map[string]interface{}{key: receiver}
+	resultMap, missingKeys, err := c.getMany(ctx, []string{key}, receiverMap, *option)
+	if err != nil {
+		return err
+	}
+	if len(missingKeys) > 0 {
+		return ErrCacheMiss
+	}
+
+	if err = setLoadResultToReceiver(key, resultMap[key], receiverMap, c.codecHandler(), *option); err != nil {
+		return err
+	}
+	if receiverMap[key] == nil {
+		// the non-existence of key is cached, refer to WithNegativeCaching
+		return ErrCacheMiss
+	}
+
+	return nil
+}
+
+// GetWithMeta (refer to GetWithMeta of Cache interface)
+// The key is read based on the current migration mode, without comparison in ShadowCompare mode.
+func (c *MigrationCache) GetWithMeta(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) (ItemMeta, error) {
+	if c.isCacheClosed() {
+		return ItemMeta{}, ErrCacheClosed
+	}
+
+	return getWithMetaThroughLayers(ctx, c.readLayers(), cmdGetWithMeta, key, receiver, opts)
+}
+
+// GetWithVersion (refer to GetWithVersion of Cache interface)
+// The key is only read from the primary cache, since CompareAndSwap is checked against the primary cache.
+func (c *MigrationCache) GetWithVersion(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) (int64, error) {
+	if c.isCacheClosed() {
+		return 0, ErrCacheClosed
+	}
+
+	meta, err := c.primary.loadWrapper().getWithMeta(ctx, cmdGetWithVersion, key, receiver, opts...)
+	return meta.Version, err
+}
+
+// TTL (refer to TTL of Cache interface)
+// The key is read based on the current migration mode.
+func (c *MigrationCache) TTL(ctx context.Context, key string, opts ...OperationOption) (ItemMeta, error) {
+	if c.isCacheClosed() {
+		return ItemMeta{}, ErrCacheClosed
+	}
+
+	return getWithMetaThroughLayers(ctx, c.readLayers(), cmdTTL, key, nil, opts)
+}
+
+// GetMany (refer to GetMany of Cache interface)
+func (c *MigrationCache) GetMany(ctx context.Context, receiverMap map[string]interface{}, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+	if len(receiverMap) == 0 {
+		return nil
+	}
+
+	option := newCacheOperationOptions()
+	defer recycleCacheOperationOptions(option)
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	keys := make([]string, 0, len(receiverMap))
+	for key := range receiverMap {
+		keys = append(keys, key)
+	}
+
+	resultMap, missingKeys, err := c.getMany(ctx, keys, receiverMap, *option)
+	if err != nil {
+		return err
+	}
+	for _, key := range missingKeys {
+		handleMissingKey(option.nonExistKeyStrategy, receiverMap, key)
+	}
+
+	return setLoadResultsToReceiverMap(resultMap, receiverMap, c.codecHandler(), *option)
+}
+
+// Set (refer to Set of Cache interface)
+func (c *MigrationCache) Set(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	return c.dualWrite(func(cache ComposableCache) error {
+		return cache.loadWrapper().set(ctx, key, value, expire, opts...)
+	})
+}
+
+// SetMany (refer to SetMany of Cache interface)
+func (c *MigrationCache) SetMany(ctx context.Context, valueMap map[string]interface{}, expire time.Duration, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	return c.dualWrite(func(cache ComposableCache) error {
+		return cache.loadWrapper().setMany(ctx, valueMap, expire, opts...)
+	})
+}
+
+// Delete (refer to Delete of Cache interface)
+// Returns ErrCacheMiss only if the key does not exist in both caches.
+func (c *MigrationCache) Delete(ctx context.Context, key string, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	primaryErr := c.primary.loadWrapper().delete(ctx, key, opts...)
+	if primaryErr != nil && primaryErr != ErrCacheMiss {
+		return primaryErr
+	}
+	secondaryErr := c.secondary.loadWrapper().delete(ctx, key, opts...)
+	if secondaryErr != nil && secondaryErr != ErrCacheMiss {
+		return secondaryErr
+	}
+
+	if primaryErr == ErrCacheMiss && secondaryErr == ErrCacheMiss {
+		return ErrCacheMiss
+	}
+	return nil
+}
+
+// DeleteMany (refer to DeleteMany of Cache interface)
+func (c *MigrationCache) DeleteMany(ctx context.Context, keys []string, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	return c.dualWrite(func(cache ComposableCache) error {
+		return cache.loadWrapper().deleteMany(ctx, keys, opts...)
+	})
+}
+
+// InvalidateTags (refer to InvalidateTags of Cache interface)
+func (c *MigrationCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	return c.dualWrite(func(cache ComposableCache) error {
+		return cache.loadWrapper().invalidateTags(ctx, tags)
+	})
+}
+
+// Add (refer to Add of Cache interface)
+// The condition is only checked against the primary cache, the secondary cache is overwritten once the primary cache succeeds.
+func (c *MigrationCache) Add(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	if err := c.primary.loadWrapper().add(ctx, key, value, expire, opts...); err != nil {
+		return err
+	}
+	return c.secondary.loadWrapper().set(ctx, key, value, expire, opts...)
+}
+
+// Replace (refer to Replace of Cache interface)
+// The condition is only checked against the primary cache, the secondary cache is overwritten once the primary cache succeeds.
+func (c *MigrationCache) Replace(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	if err := c.primary.loadWrapper().replace(ctx, key, value, expire, opts...); err != nil {
+		return err
+	}
+	return c.secondary.loadWrapper().set(ctx, key, value, expire, opts...)
+}
+
+// CompareAndSwap (refer to CompareAndSwap of Cache interface)
+// The version is only checked against the primary cache, the secondary cache is overwritten once the primary cache succeeds.
+func (c *MigrationCache) CompareAndSwap(ctx context.Context, key string, value interface{}, version int64, expire time.Duration, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	if err := c.primary.loadWrapper().compareAndSwap(ctx, key, value, version, expire, opts...); err != nil {
+		return err
+	}
+	return c.secondary.loadWrapper().set(ctx, key, value, expire, opts...)
+}
+
+// Increment (refer to Increment of Cache interface)
+// The counter is updated in both caches and the value of the primary cache is returned once the primary cache succeeds,
+// along with the error of the secondary cache if any, which means only the secondary cache is not updated.
+// A counter missing in the secondary cache is initialized on its own if `initNonExistKey` is applied,
+// so it may differ from the primary cache until the key expires, otherwise it is left missing.
+func (c *MigrationCache) Increment(ctx context.Context, key string, delta uint64, opts ...OperationOption) (int64, error) {
+	if c.isCacheClosed() {
+		return 0, ErrCacheClosed
+	}
+
+	return c.dualWriteCounter(func(cache ComposableCache) (int64, error) {
+		return cache.loadWrapper().increment(ctx, key, delta, opts...)
+	})
+}
+
+// Decrement (refer to Decrement of Cache interface)
+// Refer to Increment for how the counter is kept in both caches.
+func (c *MigrationCache) Decrement(ctx context.Context, key string, delta uint64, opts ...OperationOption) (int64, error) {
+	if c.isCacheClosed() {
+		return 0, ErrCacheClosed
+	}
+
+	return c.dualWriteCounter(func(cache ComposableCache) (int64, error) {
+		return cache.loadWrapper().decrement(ctx, key, delta, opts...)
+	})
+}
+
+// Expire (refer to Expire of Cache interface)
+// Returns ErrCacheMiss only if the key does not exist in both caches.
+func (c *MigrationCache) Expire(ctx context.Context, key string, expire time.Duration, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	primaryErr := c.primary.loadWrapper().expire(ctx, key, expire, opts...)
+	if primaryErr != nil && primaryErr != ErrCacheMiss {
+		return primaryErr
+	}
+	secondaryErr := c.secondary.loadWrapper().expire(ctx, key, expire, opts...)
+	if secondaryErr != nil && secondaryErr != ErrCacheMiss {
+		return secondaryErr
+	}
+
+	if primaryErr == ErrCacheMiss && secondaryErr == ErrCacheMiss {
+		return ErrCacheMiss
+	}
+	return nil
+}
+
+// Load (refer to Load of Cache interface)
+func (c *MigrationCache) Load(ctx context.Context, loader DataLoader, key string, receiver interface{}, expire time.Duration, opts ...OperationOption) error {
+	receiverMap := map[string]interface{}{key: receiver}
+
+	err := c.LoadMany(ctx, loader, receiverMap, expire, opts...)
+	if err != nil {
+		return err
+	}
+	if receiverMap[key] == nil {
+		return ErrCacheMiss
+	}
+
+	return nil
+}
+
+// LoadMany (refer to LoadMany of Cache interface)
+// In ReadPrimaryDualWrite and ShadowCompare mode, data is loaded through the primary cache, and the loaded data is also set to the secondary cache.
+// In ReadSecondaryFallback mode, data is loaded through the secondary cache, whose DataLoader loads data through the primary cache.
+func (c *MigrationCache) LoadMany(ctx context.Context, loader DataLoader, receiverMap map[string]interface{}, expire time.Duration, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+	if len(receiverMap) == 0 {
+		return nil
+	}
+
+	switch c.loadConfig().Mode {
+	case ReadSecondaryFallback:
+		return c.secondary.loadWrapper().loadMany(ctx, c.genLoaderThroughPrimary(loader, receiverMap, expire, opts), receiverMap, expire, opts...)
+	case ShadowCompare:
+		receivers := copyReceiverMap(receiverMap)
+		err := c.primary.loadWrapper().loadMany(ctx, c.genDualWriteLoader(loader, expire, opts), receiverMap, expire, opts...)
+		if err == nil {
+			option := newCacheOperationOptions()
+			defer recycleCacheOperationOptions(option)
+			for _, opt := range opts {
+				opt(option)
+			}
+			c.asyncCompare(ctx, receivers, nil, *option)
+		}
+		return err
+	default:
+		return c.primary.loadWrapper().loadMany(ctx, c.genDualWriteLoader(loader, expire, opts), receiverMap, expire, opts...)
+	}
+}
+
+// Flush (refer to Flush of Cache interface)
+func (c *MigrationCache) Flush(ctx context.Context) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	return c.dualWrite(func(cache ComposableCache) error {
+		return cache.loadWrapper().flush(ctx)
+	})
+}
+
+// Ping (refer to Ping of Cache interface)
+func (c *MigrationCache) Ping(ctx context.Context) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	return c.dualWrite(func(cache ComposableCache) error {
+		return cache.loadWrapper().ping(ctx)
+	})
+}
+
+// Close releases all open resources, including the primary and the secondary cache
+func (c *MigrationCache) Close(ctx context.Context) error {
+	if !atomic.CompareAndSwapUint32(&c.isClosed, 0, 1) {
+		return nil
+	}
+
+	primaryErr := c.primary.loadWrapper().close()
+	secondaryErr := c.secondary.loadWrapper().close()
+	if primaryErr != nil {
+		return primaryErr
+	}
+	return secondaryErr
+}
+
+// UpdateConfig switches the migration mode at runtime.
+// The primary and the secondary cache can not be changed, a new MigrationCache should be created instead.
+func (c *MigrationCache) UpdateConfig(config MigrationConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if err := checkMigrationCaches(config, c.primary, c.secondary); err != nil {
+		return err
+	}
+
+	atomic.StorePointer(&c.config, unsafe.Pointer(&config))
+	return nil
+}
+
+func (c *MigrationCache) loadConfig() *MigrationConfig {
+	return (*MigrationConfig)(atomic.LoadPointer(&c.config))
+}
+
+// closeSelf closes the cache without closing the underlying caches, which are owned by Manager
+func (c *MigrationCache) closeSelf() {
+	atomic.StoreUint32(&c.isClosed, 1)
+}
+
+func (c *MigrationCache) isCacheClosed() bool {
+	return atomic.LoadUint32(&c.isClosed) == 1
+}
+
+// codecHandler returns the codec handler used to set data to receivers, both caches share the same codec type
+func (c *MigrationCache) codecHandler() codecHandler {
+	return c.primary.loadInner().codecHandler
+}
+
+// dualWrite performs f on the primary cache and then the secondary cache.
+// The secondary cache is not written if the primary cache fails, so that it never holds data the primary cache does not.
+func (c *MigrationCache) dualWrite(f func(cache ComposableCache) error) error {
+	if err := f(c.primary); err != nil {
+		return err
+	}
+	return f(c.secondary)
+}
+
+// dualWriteCounter updates a counter by f in the primary cache and then in the secondary cache, and returns the value of the primary cache.
+// The counter missing in the secondary cache is not regarded as an error, since the primary cache is already updated.
+func (c *MigrationCache) dualWriteCounter(f func(cache ComposableCache) (int64, error)) (int64, error) {
+	num, err := f(c.primary)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = f(c.secondary); err != nil && err != ErrCacheMiss {
+		return num, err
+	}
+	return num, nil
+}
+
+// readLayers returns the caches to read in order based on the current migration mode
+func (c *MigrationCache) readLayers() []ComposableCache {
+	if c.loadConfig().Mode == ReadSecondaryFallback {
+		return []ComposableCache{c.secondary, c.primary}
+	}
+	return []ComposableCache{c.primary}
+}
+
+// getMany reads keys based on the current migration mode
+func (c *MigrationCache) getMany(ctx context.Context, keys []string, receiverMap map[string]interface{}, option cacheOperationOptions) (map[string]loadResult, []string, error) {
+	switch c.loadConfig().Mode {
+	case ReadSecondaryFallback:
+		resultMap, missingKeys, _, _, err := getManyThroughLayers(ctx, []ComposableCache{c.secondary, c.primary}, keys, receiverMap, option)
+		return resultMap, missingKeys, err
+	case ShadowCompare:
+		receivers := copyReceiverMap(receiverMap)
+		resultMap, missingKeys, _, _, err := getManyThroughLayers(ctx, []ComposableCache{c.primary}, keys, receiverMap, option)
+		if err == nil {
+			c.asyncCompare(ctx, receivers, resultMap, option)
+		}
+		return resultMap, missingKeys, err
+	default:
+		resultMap, missingKeys, _, _, err := getManyThroughLayers(ctx, []ComposableCache{c.primary}, keys, receiverMap, option)
+		return resultMap, missingKeys, err
+	}
+}
+
+// genDualWriteLoader wraps loader so that the data loaded for the primary cache is also set to the secondary cache
+func (c *MigrationCache) genDualWriteLoader(loader DataLoader, expire time.Duration, opts []OperationOption) DataLoader {
+	if loader == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, keys []string) ([]interface{}, error) {
+		dataList, err := loader(ctx, keys)
+		if err != nil || len(dataList) != len(keys) {
+			return dataList, err
+		}
+
+		valueMap := make(map[string]interface{}, len(keys))
+		for idx, data := range dataList {
+			if data != nil {
+				valueMap[keys[idx]] = data
+			}
+		}
+		// failures are reported by stats of the secondary cache, the primary cache keeps serving
+		_ = c.secondary.loadWrapper().setMany(ctx, valueMap, expire, opts...)
+
+		return dataList, nil
+	}
+}
+
+// genLoaderThroughPrimary generates a DataLoader for the secondary cache, which loads data through the primary cache with loader
+func (c *MigrationCache) genLoaderThroughPrimary(loader DataLoader, receiverMap map[string]interface{}, expire time.Duration, opts []OperationOption) DataLoader {
+	receiverTypes := make(map[string]reflect.Type, len(receiverMap))
+	for key, receiver := range receiverMap {
+		if receiver != nil {
+			receiverTypes[key] = reflect.TypeOf(receiver).Elem()
+		}
+	}
+
+	return func(ctx context.Context, keys []string) ([]interface{}, error) {
+		primaryReceiverMap := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			receiverType, ok := receiverTypes[key]
+			if !ok {
+				return nil, errNilReceiver
+			}
+			primaryReceiverMap[key] = reflect.New(receiverType).Interface()
+		}
+
+		primaryOpts := make([]OperationOption, 0, len(opts)+1)
+		primaryOpts = append(primaryOpts, opts...)
+		primaryOpts = append(primaryOpts, WithNonExistKeyStrategy(FillNil))
+		err := c.primary.loadWrapper().loadMany(ctx, loader, primaryReceiverMap, expire, primaryOpts...)
+		if err != nil {
+			return nil, err
+		}
+
+		dataList := make([]interface{}, len(keys))
+		for idx, key := range keys {
+			dataList[idx] = primaryReceiverMap[key]
+		}
+		return dataList, nil
+	}
+}
+
+// asyncCompare compares data of keys in the primary cache with the secondary cache asynchronously.
+// primaryResultMap is the result already got from the primary cache, the primary cache is read again if it is nil.
+func (c *MigrationCache) asyncCompare(ctx context.Context, receiverMap map[string]interface{}, primaryResultMap map[string]loadResult, option cacheOperationOptions) {
+	go func() {
+		detachCtx := xcontext.Detach(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			detachCtx, cancel = context.WithDeadline(detachCtx, deadline)
+			defer cancel()
+		}
+		c.compare(detachCtx, receiverMap, primaryResultMap, option)
+	}()
+}
+
+// compare compares data of keys in the primary cache with the secondary cache, and reports the mismatched key count through RequestStats.
+// A key is mismatched if it only exists in one of the caches, or the data in both caches are different.
+func (c *MigrationCache) compare(ctx context.Context, receiverMap map[string]interface{}, primaryResultMap map[string]loadResult, option cacheOperationOptions) *RequestStats {
+	keys := make([]string, 0, len(receiverMap))
+	for key := range receiverMap {
+		keys = append(keys, key)
+	}
+
+	stats := &RequestStats{
+		CacheName:      c.name,
+		CacheType:      migrationCacheTypeName,
+		CacheOperation: cmdCompare,
+		TotalKeyCount:  len(keys),
+		req:            keys,
+	}
+
+	requestStatsDecorator(ctx, c.primary.loadInner().observationConfig, stats, func(ctx context.Context) error {
+		if primaryResultMap == nil {
+			primaryInner := c.primary.loadInner()
+			_, _, resultMap, _, err := getManyForLoad(ctx, primaryInner, keys, receiverMap, primaryInner.codecHandler, option)
+			if err != nil {
+				return err
+			}
+			primaryResultMap = resultMap
+		}
+
+		secondaryInner := c.secondary.loadInner()
+		_, _, secondaryResultMap, _, err := getManyForLoad(ctx, secondaryInner, keys, receiverMap, secondaryInner.codecHandler, option)
+		if err != nil {
+			return err
+		}
+
+		mismatchedKeys := make([]string, 0)
+		for _, key := range keys {
+			primaryResult, inPrimary := primaryResultMap[key]
+			secondaryResult, inSecondary := secondaryResultMap[key]
+			if inPrimary != inSecondary || (inPrimary && !isLoadResultEqual(primaryResult, secondaryResult)) {
+				mismatchedKeys = append(mismatchedKeys, key)
+			}
+		}
+
+		stats.SuccessKeyCount = len(keys) - len(mismatchedKeys)
+		stats.MismatchKeyCount = len(mismatchedKeys)
+		stats.resp = mismatchedKeys
+		return nil
+	})
+
+	return stats
+}
+
+func isLoadResultEqual(a, b loadResult) bool {
+	if a.dataBytes != nil && b.dataBytes != nil {
+		return bytes.Equal(a.dataBytes, b.dataBytes)
+	}
+	return reflect.DeepEqual(utils.GetValue(a.data), utils.GetValue(b.data))
+}
+
+// copyReceiverMap copies receiverMap, so that it can be used by another goroutine to know the types of receivers
+func copyReceiverMap(receiverMap map[string]interface{}) map[string]interface{} {
+	receivers := make(map[string]interface{}, len(receiverMap))
+	for key, receiver := range receiverMap {
+		receivers[key] = receiver
+	}
+	return receivers
+}
@@ -0,0 +1,60 @@
+package cache
+
+import "fmt"
+
+// MigrationMode defines how MigrationCache routes reads and writes between the primary and the secondary cache
+type MigrationMode int
+
+const (
+	// ReadPrimaryDualWrite reads from the primary cache only, and writes to both caches.
+	// It is used to warm up the secondary cache before switching reads to it.
+	ReadPrimaryDualWrite MigrationMode = 1
+
+	// ReadSecondaryFallback reads from the secondary cache, and falls back to the primary cache on miss,
+	// back-filling the secondary cache with the data got or loaded from the primary cache. Writes go to both caches.
+	ReadSecondaryFallback MigrationMode = 2
+
+	// ShadowCompare reads from the primary cache as the result, and asynchronously compares it with the secondary cache.
+	// The number of mismatched keys is reported through RequestStats.MismatchKeyCount. Writes go to both caches.
+	ShadowCompare MigrationMode = 3
+)
+
+// MigrationConfig defines config used to construct a MigrationCache
+type MigrationConfig struct {
+	// Mode defines how reads and writes are routed, it can be switched at runtime through UpdateConfig
+	Mode MigrationMode `yaml:"mode" json:"mode"`
+
+	// Primary is the name of the cache currently serving the traffic, i.e. the cache to migrate from
+	Primary string `yaml:"primary" json:"primary"`
+
+	// Secondary is the name of the cache to migrate to
+	Secondary string `yaml:"secondary" json:"secondary"`
+}
+
+// Validate checks if config is valid
+func (c MigrationConfig) Validate() error {
+	switch c.Mode {
+	case ReadPrimaryDualWrite, ReadSecondaryFallback, ShadowCompare:
+	default:
+		return cacheErr(fmt.Sprintf("invalid_config_migration_mode: %v", c.Mode))
+	}
+	if c.Primary == "" || c.Secondary == "" {
+		return cacheErr("invalid_config_migration_cache_name_empty")
+	}
+	if c.Primary == c.Secondary {
+		return cacheErr(fmt.Sprintf("invalid_config_migration_cache_name_duplicated: %v", c.Primary))
+	}
+	return nil
+}
+
+// Config wraps this MigrationConfig in a generic Config struct.
+func (c MigrationConfig) Config() Config {
+	return Config{
+		Type:      Migration,
+		Migration: c,
+	}
+}
+
+func (c MigrationConfig) cacheNames() []string {
+	return []string{c.Primary, c.Secondary}
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestMigrationCache(t *testing.T, mode MigrationMode) (*MigrationCache, *InMemoryCache, *RedisCache) {
+	primary, err := NewInMemoryCache("test_migration_primary", InMemoryCacheConfig{CacheType: Ristretto})
+	if err != nil {
+		t.Fatalf("new in-memory cache err: %v", err)
+	}
+	secondary := newTestRedisCache(t)
+
+	c, err := NewMigrationCache("test_migration", MigrationConfig{
+		Mode:      mode,
+		Primary:   "test_migration_primary",
+		Secondary: "test_redis",
+	}, primary, secondary)
+	if err != nil {
+		t.Fatalf("new migration cache err: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+
+	return c, primary, secondary
+}
+
+func TestMigrationCacheDualWrite(t *testing.T) {
+	ctx := context.Background()
+	c, primary, secondary := newTestMigrationCache(t, ReadPrimaryDualWrite)
+
+	if err := c.Set(ctx, "k1", "v1", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+
+	calls := 0
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		calls++
+		res := make([]interface{}, len(keys))
+		for i, key := range keys {
+			res[i] = "loaded_" + key
+		}
+		return res, nil
+	}
+	var val string
+	if err := c.Load(ctx, loader, "k2", &val, time.Minute, WithWaitRistretto()); err != nil || val != "loaded_k2" {
+		t.Fatalf("unexpected loaded value %v, err: %v", val, err)
+	}
+	if calls != 1 {
+		t.Fatalf("expect loader to be called once, got %v", calls)
+	}
+
+	for _, cache := range []Cache{primary, secondary} {
+		for key, expected := range map[string]string{"k1": "v1", "k2": "loaded_k2"} {
+			var got string
+			if err := cache.Load(ctx, nil, key, &got, time.Minute); err != nil || got != expected {
+				t.Fatalf("expect %v written to both caches, got %v, err: %v", key, got, err)
+			}
+		}
+	}
+}
+
+func TestMigrationCacheCounterMissingInSecondary(t *testing.T) {
+	ctx := context.Background()
+	c, primary, secondary := newTestMigrationCache(t, ReadPrimaryDualWrite)
+
+	if _, err := primary.Increment(ctx, "counter", 5); err != nil {
+		t.Fatalf("increment err: %v", err)
+	}
+	if n, err := c.Increment(ctx, "counter", 1, WithInitNonExistKey(false)); err != nil || n != 6 {
+		t.Fatalf("expect the value of the primary cache, got: %v, err: %v", n, err)
+	}
+	if n, err := c.Decrement(ctx, "counter", 2, WithInitNonExistKey(false)); err != nil || n != 4 {
+		t.Fatalf("expect the value of the primary cache, got: %v, err: %v", n, err)
+	}
+	if _, err := secondary.Increment(ctx, "counter", 0, WithInitNonExistKey(false)); err != ErrCacheMiss {
+		t.Fatalf("expect the counter left missing in the secondary cache, got: %v", err)
+	}
+
+	if _, err := c.Increment(ctx, "not_exist", 1, WithInitNonExistKey(false)); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss if the counter is missing in the primary cache, got: %v", err)
+	}
+}
+
+func TestMigrationCacheReadSecondaryFallback(t *testing.T) {
+	ctx := context.Background()
+	c, primary, secondary := newTestMigrationCache(t, ReadPrimaryDualWrite)
+
+	if err := primary.Set(ctx, "k", "v", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set to primary err: %v", err)
+	}
+
+	err := c.UpdateConfig(MigrationConfig{Mode: ReadSecondaryFallback, Primary: "test_migration_primary", Secondary: "another"})
+	if err == nil {
+		t.Fatalf("expect error on changing secondary cache")
+	}
+	err = c.UpdateConfig(MigrationConfig{Mode: ReadSecondaryFallback, Primary: "test_migration_primary", Secondary: "test_redis"})
+	if err != nil {
+		t.Fatalf("update config err: %v", err)
+	}
+
+	var val string
+	if err = c.Get(ctx, "k", &val); err != nil || val != "v" {
+		t.Fatalf("unexpected value %v, err: %v", val, err)
+	}
+	if err = secondary.Get(ctx, "k", &val); err != nil || val != "v" {
+		t.Fatalf("expect secondary back-filled, got %v, err: %v", val, err)
+	}
+
+	// data missing in both caches is loaded through the primary cache
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return []interface{}{"loaded"}, nil
+	}
+	var loaded string
+	if err = c.Load(ctx, loader, "k2", &loaded, time.Minute, WithWaitRistretto()); err != nil || loaded != "loaded" {
+		t.Fatalf("unexpected loaded value %v, err: %v", loaded, err)
+	}
+	for _, cache := range []Cache{primary, secondary} {
+		var got string
+		if err = cache.Load(ctx, nil, "k2", &got, time.Minute); err != nil || got != "loaded" {
+			t.Fatalf("expect loaded value in both caches, got %v, err: %v", got, err)
+		}
+	}
+}
+
+func TestMigrationCacheShadowCompare(t *testing.T) {
+	ctx := context.Background()
+	c, primary, secondary := newTestMigrationCache(t, ShadowCompare)
+
+	if err := c.Set(ctx, "same", "v", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	if err := primary.Set(ctx, "primary_only", "v", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set to primary err: %v", err)
+	}
+	if err := primary.Set(ctx, "different", "v1", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set to primary err: %v", err)
+	}
+	if err := secondary.Set(ctx, "different", "v2", time.Minute); err != nil {
+		t.Fatalf("set to secondary err: %v", err)
+	}
+
+	var v1, v2, v3 string
+	receiverMap := map[string]interface{}{"same": &v1, "primary_only": &v2, "different": &v3}
+	if err := c.GetMany(ctx, receiverMap); err != nil {
+		t.Fatalf("get many err: %v", err)
+	}
+	if v1 != "v" || v2 != "v" || v3 != "v1" {
+		t.Fatalf("expect data read from primary, got %v, %v, %v", v1, v2, v3)
+	}
+
+	option := newCacheOperationOptions()
+	defer recycleCacheOperationOptions(option)
+	stats := c.compare(ctx, receiverMap, nil, *option)
+	if stats.Err != nil || stats.TotalKeyCount != 3 || stats.MismatchKeyCount != 2 {
+		t.Fatalf("unexpected compare stats: %+v", stats)
+	}
+}
@@ -0,0 +1,696 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go-eCache/internal/xcontext"
+)
+
+var _ Cache = (*MultiLayerCache)(nil)
+
+// MultiLayerCache implements Cache interface, it composes several caches as layers.
+//
+// Reads go through the layers from the outermost to the innermost, and the layers above the one hitting the data are back-filled.
+// Writes go through all layers from the innermost to the outermost, so that an outer layer never holds data an inner layer failed to store.
+// For Load/LoadMany, the DataLoader is only invoked at the last layer, with the stampede mitigation strategy of the last layer.
+type MultiLayerCache struct {
+	name     string
+	layers   []ComposableCache
+	isClosed uint32
+}
+
+// NewMultiLayerCache creates a new multilayer cache composed by layers, which must be in the same order as config.Layers.
+// All layers must use the same codec type, since data is passed between layers in bytes.
+func NewMultiLayerCache(name string, config MultiLayerConfig, layers ...ComposableCache) (*MultiLayerCache, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if len(layers) != len(config.Layers) {
+		return nil, cacheErr("multilayer_layer_number_not_match")
+	}
+
+	for idx, layer := range layers {
+		if layer == nil {
+			return nil, cacheErr("multilayer_layer_is_nil")
+		}
+		inner := layer.loadInner()
+		if inner.name != config.Layers[idx] {
+			return nil, cacheErr("multilayer_layer_name_not_match: " + inner.name)
+		}
+		if inner.codecHandler.defaultCodecType != layers[0].loadInner().codecHandler.defaultCodecType {
+			return nil, cacheErr("multilayer_layer_codec_type_not_match: " + inner.name)
+		}
+	}
+
+	return &MultiLayerCache{
+		name:   name,
+		layers: layers,
+	}, nil
+}
+
+// Get (refer to Get of Cache interface)
+func (c *MultiLayerCache) Get(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	option := newCacheOperationOptions()
+	defer recycleCacheOperationOptions(option)
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	receiverMap := map[string]interface{}{key: receiver}
+	resultMap, missingKeys, _, _, err := getManyThroughLayers(ctx, c.layers, []string{key}, receiverMap, *option)
+	if err != nil {
+		return err
+	}
+	if len(missingKeys) > 0 {
+		return ErrCacheMiss
+	}
+
+	if err = setLoadResultToReceiver(key, resultMap[key], receiverMap, c.codecHandler(), *option); err != nil {
+		return err
+	}
+	if receiverMap[key] == nil {
+		// the non-existence of key is cached, refer to WithNegativeCaching
+		return ErrCacheMiss
+	}
+
+	return nil
+}
+
+// GetWithMeta (refer to GetWithMeta of Cache interface)
+// The meta is of the first layer holding the key, and the value is not backfilled to the outer layers.
+func (c *MultiLayerCache) GetWithMeta(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) (ItemMeta, error) {
+	if c.isCacheClosed() {
+		return ItemMeta{}, ErrCacheClosed
+	}
+
+	return getWithMetaThroughLayers(ctx, c.layers, cmdGetWithMeta, key, receiver, opts)
+}
+
+// GetWithVersion (refer to GetWithVersion of Cache interface)
+// The key is only read from the last layer, since CompareAndSwap is checked against the last layer.
+func (c *MultiLayerCache) GetWithVersion(ctx context.Context, key string, receiver interface{}, opts ...OperationOption) (int64, error) {
+	if c.isCacheClosed() {
+		return 0, ErrCacheClosed
+	}
+
+	meta, err := c.layers[len(c.layers)-1].loadWrapper().getWithMeta(ctx, cmdGetWithVersion, key, receiver, opts...)
+	return meta.Version, err
+}
+
+// TTL (refer to TTL of Cache interface)
+// The meta is of the first layer holding the key.
+func (c *MultiLayerCache) TTL(ctx context.Context, key string, opts ...OperationOption) (ItemMeta, error) {
+	if c.isCacheClosed() {
+		return ItemMeta{}, ErrCacheClosed
+	}
+
+	return getWithMetaThroughLayers(ctx, c.layers, cmdTTL, key, nil, opts)
+}
+
+// GetMany (refer to GetMany of Cache interface)
+func (c *MultiLayerCache) GetMany(ctx context.Context, receiverMap map[string]interface{}, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+	if len(receiverMap) == 0 {
+		return nil
+	}
+
+	option := newCacheOperationOptions()
+	defer recycleCacheOperationOptions(option)
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	keys := make([]string, 0, len(receiverMap))
+	for key := range receiverMap {
+		keys = append(keys, key)
+	}
+
+	resultMap, missingKeys, _, _, err := getManyThroughLayers(ctx, c.layers, keys, receiverMap, *option)
+	if err != nil {
+		return err
+	}
+	for _, key := range missingKeys {
+		handleMissingKey(option.nonExistKeyStrategy, receiverMap, key)
+	}
+
+	return setLoadResultsToReceiverMap(resultMap, receiverMap, c.codecHandler(), *option)
+}
+
+// Set (refer to Set of Cache interface)
+// The hard and soft expiration of each layer can be specified by WithHardExpirationMultiLayer and WithSoftExpirationMultiLayer.
+func (c *MultiLayerCache) Set(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	option := newCacheOperationOptions()
+	defer recycleCacheOperationOptions(option)
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	for idx := len(c.layers) - 1; idx >= 0; idx-- {
+		layerExpire := genHardTimeoutDurationForLayer(expire, idx, *option)
+		if err := c.layers[idx].loadWrapper().set(ctx, key, value, layerExpire, genLayerOperationOptions(idx, opts, *option)...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetMany (refer to SetMany of Cache interface)
+// The hard and soft expiration of each layer can be specified by WithHardExpirationMultiLayer and WithSoftExpirationMultiLayer.
+func (c *MultiLayerCache) SetMany(ctx context.Context, valueMap map[string]interface{}, expire time.Duration, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	option := newCacheOperationOptions()
+	defer recycleCacheOperationOptions(option)
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	for idx := len(c.layers) - 1; idx >= 0; idx-- {
+		layerExpire := genHardTimeoutDurationForLayer(expire, idx, *option)
+		if err := c.layers[idx].loadWrapper().setMany(ctx, valueMap, layerExpire, genLayerOperationOptions(idx, opts, *option)...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete (refer to Delete of Cache interface)
+// Returns ErrCacheMiss only if the key does not exist in all layers.
+func (c *MultiLayerCache) Delete(ctx context.Context, key string, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	deleted := false
+	for idx := len(c.layers) - 1; idx >= 0; idx-- {
+		err := c.layers[idx].loadWrapper().delete(ctx, key, opts...)
+		if err == ErrCacheMiss {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		deleted = true
+	}
+
+	if !deleted {
+		return ErrCacheMiss
+	}
+	return nil
+}
+
+// DeleteMany (refer to DeleteMany of Cache interface)
+func (c *MultiLayerCache) DeleteMany(ctx context.Context, keys []string, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	for idx := len(c.layers) - 1; idx >= 0; idx-- {
+		if err := c.layers[idx].loadWrapper().deleteMany(ctx, keys, opts...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InvalidateTags (refer to InvalidateTags of Cache interface)
+func (c *MultiLayerCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	for idx := len(c.layers) - 1; idx >= 0; idx-- {
+		if err := c.layers[idx].loadWrapper().invalidateTags(ctx, tags); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Add (refer to Add of Cache interface)
+// The condition is only checked against the last layer, the outer layers are overwritten once the last layer succeeds.
+func (c *MultiLayerCache) Add(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error {
+	return c.addOrReplace(ctx, key, value, expire, cmdAdd, opts)
+}
+
+// Replace (refer to Replace of Cache interface)
+// The condition is only checked against the last layer, the outer layers are overwritten once the last layer succeeds.
+func (c *MultiLayerCache) Replace(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...OperationOption) error {
+	return c.addOrReplace(ctx, key, value, expire, cmdReplace, opts)
+}
+
+func (c *MultiLayerCache) addOrReplace(ctx context.Context, key string, value interface{}, expire time.Duration, command string, opts []OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	option := newCacheOperationOptions()
+	defer recycleCacheOperationOptions(option)
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	lastIdx := len(c.layers) - 1
+	lastLayer := c.layers[lastIdx].loadWrapper()
+	lastLayerExpire := genHardTimeoutDurationForLayer(expire, lastIdx, *option)
+	lastLayerOpts := genLayerOperationOptions(lastIdx, opts, *option)
+	var err error
+	if command == cmdAdd {
+		err = lastLayer.add(ctx, key, value, lastLayerExpire, lastLayerOpts...)
+	} else {
+		err = lastLayer.replace(ctx, key, value, lastLayerExpire, lastLayerOpts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	for idx := lastIdx - 1; idx >= 0; idx-- {
+		layerExpire := genHardTimeoutDurationForLayer(expire, idx, *option)
+		if err = c.layers[idx].loadWrapper().set(ctx, key, value, layerExpire, genLayerOperationOptions(idx, opts, *option)...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CompareAndSwap (refer to CompareAndSwap of Cache interface)
+// The version is only checked against the last layer, the outer layers are overwritten once the last layer succeeds.
+func (c *MultiLayerCache) CompareAndSwap(ctx context.Context, key string, value interface{}, version int64, expire time.Duration, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	option := newCacheOperationOptions()
+	defer recycleCacheOperationOptions(option)
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	lastIdx := len(c.layers) - 1
+	lastLayerExpire := genHardTimeoutDurationForLayer(expire, lastIdx, *option)
+	err := c.layers[lastIdx].loadWrapper().compareAndSwap(ctx, key, value, version, lastLayerExpire, genLayerOperationOptions(lastIdx, opts, *option)...)
+	if err != nil {
+		return err
+	}
+
+	for idx := lastIdx - 1; idx >= 0; idx-- {
+		layerExpire := genHardTimeoutDurationForLayer(expire, idx, *option)
+		if err = c.layers[idx].loadWrapper().set(ctx, key, value, layerExpire, genLayerOperationOptions(idx, opts, *option)...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Increment (refer to Increment of Cache interface)
+// The counter is only kept in the last layer, the key is deleted from the outer layers.
+func (c *MultiLayerCache) Increment(ctx context.Context, key string, delta uint64, opts ...OperationOption) (int64, error) {
+	return c.incrDecr(ctx, key, delta, cmdIncrement, opts)
+}
+
+// Decrement (refer to Decrement of Cache interface)
+// The counter is only kept in the last layer, the key is deleted from the outer layers.
+func (c *MultiLayerCache) Decrement(ctx context.Context, key string, delta uint64, opts ...OperationOption) (int64, error) {
+	return c.incrDecr(ctx, key, delta, cmdDecrement, opts)
+}
+
+func (c *MultiLayerCache) incrDecr(ctx context.Context, key string, delta uint64, command string, opts []OperationOption) (int64, error) {
+	if c.isCacheClosed() {
+		return 0, ErrCacheClosed
+	}
+
+	lastIdx := len(c.layers) - 1
+	lastLayer := c.layers[lastIdx].loadWrapper()
+	var num int64
+	var err error
+	if command == cmdIncrement {
+		num, err = lastLayer.increment(ctx, key, delta, opts...)
+	} else {
+		num, err = lastLayer.decrement(ctx, key, delta, opts...)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// drop the stale copies, so that the following reads are back-filled from the last layer
+	for idx := lastIdx - 1; idx >= 0; idx-- {
+		if err = c.layers[idx].loadWrapper().delete(ctx, key, opts...); err != nil && err != ErrCacheMiss {
+			return 0, err
+		}
+	}
+
+	return num, nil
+}
+
+// Expire (refer to Expire of Cache interface)
+// The hard expiration of each layer can be specified by WithHardExpirationMultiLayer.
+// Returns ErrCacheMiss only if the key does not exist in all layers.
+func (c *MultiLayerCache) Expire(ctx context.Context, key string, expire time.Duration, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	option := newCacheOperationOptions()
+	defer recycleCacheOperationOptions(option)
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	updated := false
+	for idx := len(c.layers) - 1; idx >= 0; idx-- {
+		layerExpire := genHardTimeoutDurationForLayer(expire, idx, *option)
+		err := c.layers[idx].loadWrapper().expire(ctx, key, layerExpire, opts...)
+		if err == ErrCacheMiss {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		updated = true
+	}
+
+	if !updated {
+		return ErrCacheMiss
+	}
+	return nil
+}
+
+// Load (refer to Load of Cache interface)
+func (c *MultiLayerCache) Load(ctx context.Context, loader DataLoader, key string, receiver interface{}, expire time.Duration, opts ...OperationOption) error {
+	receiverMap := map[string]interface{}{key: receiver}
+
+	err := c.LoadMany(ctx, loader, receiverMap, expire, opts...)
+	if err != nil {
+		return err
+	}
+	if receiverMap[key] == nil {
+		return ErrCacheMiss
+	}
+
+	return nil
+}
+
+// LoadMany (refer to LoadMany of Cache interface)
+// The hard and soft expiration of each layer can be specified by WithHardExpirationMultiLayer and WithSoftExpirationMultiLayer.
+func (c *MultiLayerCache) LoadMany(ctx context.Context, loader DataLoader, receiverMap map[string]interface{}, expire time.Duration, opts ...OperationOption) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+	option := newCacheOperationOptions()
+	defer recycleCacheOperationOptions(option)
+	for _, opt := range opts {
+		opt(option)
+	}
+	if len(receiverMap) == 0 {
+		return nil
+	}
+	lastManufacturerHandler := c.layers[len(c.layers)-1].loadInner().manufacturerHandler
+	if option.staleIfError == 0 {
+		option.staleIfError = lastManufacturerHandler.staleIfError
+	}
+	if option.negativeCacheTTL == 0 {
+		option.negativeCacheTTL = lastManufacturerHandler.negativeCacheTTL
+	}
+
+	keys := make([]string, 0, len(receiverMap))
+	for key := range receiverMap {
+		keys = append(keys, key)
+	}
+
+	successKeyResultMap, missingKeys, toUpdateKeys, staleResultMap, err := getManyThroughLayers(ctx, c.layers, keys, receiverMap, *option)
+	if err != nil {
+		return err
+	}
+	option.staleResultMap = staleResultMap
+
+	if len(successKeyResultMap) > 0 {
+		if err = setLoadResultsToReceiverMap(successKeyResultMap, receiverMap, c.codecHandler(), *option); err != nil {
+			return err
+		}
+	}
+
+	if len(toUpdateKeys) > 0 && !c.layers[len(c.layers)-1].loadInner().isWriteSkipped(ctx) {
+		c.loadHandleToUpdateKeys(ctx, toUpdateKeys, receiverMap, loader, expire, *option)
+	}
+
+	if len(missingKeys) > 0 {
+		err = c.loadHandleMissingKeys(ctx, missingKeys, receiverMap, loader, expire, *option)
+	}
+
+	return err
+}
+
+// Flush (refer to Flush of Cache interface)
+func (c *MultiLayerCache) Flush(ctx context.Context) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	for idx := len(c.layers) - 1; idx >= 0; idx-- {
+		if err := c.layers[idx].loadWrapper().flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Ping (refer to Ping of Cache interface)
+func (c *MultiLayerCache) Ping(ctx context.Context) error {
+	if c.isCacheClosed() {
+		return ErrCacheClosed
+	}
+
+	for _, layer := range c.layers {
+		if err := layer.loadWrapper().ping(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close releases all open resources, including all layers
+func (c *MultiLayerCache) Close(ctx context.Context) error {
+	if !atomic.CompareAndSwapUint32(&c.isClosed, 0, 1) {
+		return nil
+	}
+
+	var err error
+	for _, layer := range c.layers {
+		if closeErr := layer.loadWrapper().close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// closeSelf closes the cache without closing the underlying caches, which are owned by Manager
+func (c *MultiLayerCache) closeSelf() {
+	atomic.StoreUint32(&c.isClosed, 1)
+}
+
+func (c *MultiLayerCache) isCacheClosed() bool {
+	return atomic.LoadUint32(&c.isClosed) == 1
+}
+
+// codecHandler returns the codec handler used to set data to receivers, all layers share the same codec type
+func (c *MultiLayerCache) codecHandler() codecHandler {
+	return c.layers[0].loadInner().codecHandler
+}
+
+// getManyThroughLayers reads keys through layers from the outermost to the innermost,
+// the layers above the one hitting the data are back-filled.
+// The stale data kept by stale-if-error is returned by staleKeyResultMap, the outermost one is taken if it is kept in several layers.
+func getManyThroughLayers(ctx context.Context, layers []ComposableCache, keys []string, receiverMap map[string]interface{}, option cacheOperationOptions) (successKeyResultMap map[string]loadResult, missingKeys, toUpdateKeys []string, staleKeyResultMap map[string]loadResult, err error) {
+	successKeyResultMap = make(map[string]loadResult, len(keys))
+	missingKeys = keys
+
+	for idx, layer := range layers {
+		inner := layer.loadInner()
+		if inner.isCacheClosed() {
+			return nil, nil, nil, nil, ErrCacheClosed
+		}
+		if inner.isReadSkipped(ctx) {
+			continue
+		}
+
+		layerMissingKeys, layerToUpdateKeys, layerResultMap, layerStaleResultMap, getManyErr := getManyForLoad(ctx, inner, missingKeys, receiverMap, inner.codecHandler, option)
+		if getManyErr != nil {
+			return nil, nil, nil, nil, getManyErr
+		}
+		for key, result := range layerStaleResultMap {
+			if _, ok := staleKeyResultMap[key]; ok {
+				continue
+			}
+			if staleKeyResultMap == nil {
+				staleKeyResultMap = make(map[string]loadResult)
+			}
+			staleKeyResultMap[key] = result
+		}
+
+		if idx > 0 && len(layerResultMap) > 0 {
+			backfillLayers(ctx, layers, idx, layerResultMap, option)
+		}
+		for key, result := range layerResultMap {
+			successKeyResultMap[key] = result
+		}
+		toUpdateKeys = append(toUpdateKeys, layerToUpdateKeys...)
+
+		missingKeys = layerMissingKeys
+		if len(missingKeys) == 0 {
+			break
+		}
+	}
+
+	return successKeyResultMap, missingKeys, toUpdateKeys, staleKeyResultMap, nil
+}
+
+// backfillLayers sets results got from the layer at layerIdx to the layers above it.
+// It is best-effort, failures are reported by stats of each layer but not returned.
+func backfillLayers(ctx context.Context, layers []ComposableCache, layerIdx int, resultMap map[string]loadResult, option cacheOperationOptions) {
+	now := time.Now()
+	for idx := layerIdx - 1; idx >= 0; idx-- {
+		inner := layers[idx].loadInner()
+		if inner.isCacheClosed() {
+			continue
+		}
+
+		layerResultMap := make(map[string]loadResult, len(resultMap))
+		for key, result := range resultMap {
+			result.header = genLayerHeader(ctx, inner, result.header, idx, option, now)
+			layerResultMap[key] = result
+		}
+		_ = setManyForLoad(ctx, inner, layerResultMap, inner.codecHandler, option)
+	}
+}
+
+// loadFromLastLayer invokes the loader at the last layer with its expiration, and back-fills the loaded results to other layers
+func (c *MultiLayerCache) loadFromLastLayer(ctx context.Context, keys []string, receiverMap map[string]interface{}, loader DataLoader, expire time.Duration, curManufacturerHandler manufacturerHandler, option cacheOperationOptions) map[string]loadResult {
+	lastIdx := len(c.layers) - 1
+	lastInner := c.layers[lastIdx].loadInner()
+
+	layerOption := option
+	layerOption.softExpiration = genSoftTimeoutDurationForLayer(lastIdx, option)
+	layerExpire := genHardTimeoutDurationForLayer(expire, lastIdx, option)
+
+	loadResultMap := loadHandleKeys(ctx, lastInner, keys, receiverMap, loader, layerExpire, curManufacturerHandler, lastInner.codecHandler, layerOption)
+	backfillLayers(ctx, c.layers, lastIdx, loadResultMap, option)
+
+	return loadResultMap
+}
+
+func (c *MultiLayerCache) loadHandleToUpdateKeys(ctx context.Context, toUpdateKeys []string, receiverMap map[string]interface{}, loader DataLoader, expire time.Duration, option cacheOperationOptions) {
+	// copy current reference of manufacturerHandler of the last layer
+	// so if users choose to update config for manufacturer policy,
+	// old requests still can proceed with old group lock
+	curManufacturerHandler := c.layers[len(c.layers)-1].loadInner().manufacturerHandlerForCtx(ctx)
+
+	// waitingInProcessSignalCallsMap will be loaded back to cache by another go-routine/instance, so we can ignore them in this go-routine.
+	toHandleKeys, _ := curManufacturerHandler.add(ctx, toUpdateKeys)
+
+	if len(toHandleKeys) > 0 {
+		go func() {
+			detachCtx := xcontext.Detach(ctx)
+			if deadline, ok := ctx.Deadline(); ok {
+				var cancel context.CancelFunc
+				detachCtx, cancel = context.WithDeadline(detachCtx, deadline)
+				defer cancel()
+			}
+			detachCtx, span := startBackgroundRefreshSpan(detachCtx, c.layers[len(c.layers)-1].loadInner(), toHandleKeys)
+			loadResultMap := curManufacturerHandler.handle(detachCtx, toHandleKeys, func() map[string]loadResult {
+				return c.loadFromLastLayer(detachCtx, toHandleKeys, receiverMap, loader, expire, curManufacturerHandler, option)
+			})
+			span.Finish(getFirstLoadResultErr(loadResultMap))
+		}()
+	}
+}
+
+func (c *MultiLayerCache) loadHandleMissingKeys(ctx context.Context, missingKeys []string, receiverMap map[string]interface{}, loader DataLoader, expire time.Duration, option cacheOperationOptions) error {
+	// copy current reference of manufacturerHandler of the last layer
+	// so if users choose to update config for manufacturer policy,
+	// old requests still can proceed with old group lock
+	curManufacturerHandler := c.layers[len(c.layers)-1].loadInner().manufacturerHandlerForCtx(ctx)
+	curCodecHandler := c.codecHandler()
+
+	toHandleKeys, waitingInProcessSignalCallsMap := curManufacturerHandler.add(ctx, missingKeys)
+
+	if len(toHandleKeys) > 0 {
+		loadResultMap := curManufacturerHandler.handle(ctx, toHandleKeys, func() map[string]loadResult {
+			return c.loadFromLastLayer(ctx, toHandleKeys, receiverMap, loader, expire, curManufacturerHandler, option)
+		})
+		err := setLoadResultsToReceiverMap(loadResultMap, receiverMap, curCodecHandler, option)
+		if err != nil {
+			return err
+		}
+	}
+	for key, call := range waitingInProcessSignalCallsMap {
+		val, waitErr := curManufacturerHandler.wait(ctx, call)
+		result, ok := val.(loadResult)
+		if !ok {
+			if waitErr == nil {
+				continue
+			}
+			result = loadResult{err: waitErr}
+		}
+		result = getStaleResultIfError(key, result, option.staleResultMap)
+		err := setLoadResultToReceiver(key, result, receiverMap, curCodecHandler, option)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// genLayerOperationOptions appends the soft expiration of the layer at layerIdx to opts
+func genLayerOperationOptions(layerIdx int, opts []OperationOption, option cacheOperationOptions) []OperationOption {
+	layerOpts := make([]OperationOption, 0, len(opts)+1)
+	layerOpts = append(layerOpts, opts...)
+	return append(layerOpts, WithSoftExpiration(genSoftTimeoutDurationForLayer(layerIdx, option)))
+}
+
+// genLayerHeader adjusts header for the layer at layerIdx based on the per-layer expirations in option.
+// The per-layer expiration never extends the timeouts carried by header,
+// so that a layer won't keep the data longer than the layer where the data comes from.
+func genLayerHeader(ctx context.Context, inner *cacheWrapperInner, header metaHeader, layerIdx int, option cacheOperationOptions, now time.Time) metaHeader {
+	if len(option.hardExpirationMultiLayer) > layerIdx {
+		layerExpire := inner.translateExpire(ctx, option.hardExpirationMultiLayer[layerIdx])
+		if layerExpire > 0 {
+			hardTimeoutTs := now.Add(layerExpire).Unix()
+			if header.HardTimeoutTs == hardTimeoutForeverIndicator || hardTimeoutTs < header.HardTimeoutTs {
+				header.HardTimeoutTs = hardTimeoutTs
+			}
+		}
+	}
+
+	if len(option.softExpirationMultiLayer) > layerIdx && option.softExpirationMultiLayer[layerIdx] > 0 {
+		softTimeoutTs := now.Add(option.softExpirationMultiLayer[layerIdx]).Unix()
+		if header.SoftTimeoutTs == 0 || softTimeoutTs < header.SoftTimeoutTs {
+			header.SoftTimeoutTs = softTimeoutTs
+		}
+	}
+
+	return header
+}
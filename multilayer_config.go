@@ -0,0 +1,43 @@
+package cache
+
+import "fmt"
+
+// MultiLayerConfig defines config used to construct a MultiLayerCache
+type MultiLayerConfig struct {
+	// Layers is the names of the caches composing the multilayer cache,
+	// ordered from the outermost layer (read first, e.g. in-memory) to the innermost layer (e.g. redis).
+	// At least 2 layers are required.
+	Layers []string `yaml:"layers" json:"layers"`
+}
+
+// Validate checks if config is valid
+func (c MultiLayerConfig) Validate() error {
+	if len(c.Layers) < 2 {
+		return cacheErr(fmt.Sprintf("invalid_config_multilayer_layer_number: %v", len(c.Layers)))
+	}
+
+	names := make(map[string]struct{}, len(c.Layers))
+	for _, name := range c.Layers {
+		if name == "" {
+			return cacheErr("invalid_config_multilayer_layer_name_empty")
+		}
+		if _, ok := names[name]; ok {
+			return cacheErr(fmt.Sprintf("invalid_config_multilayer_layer_name_duplicated: %v", name))
+		}
+		names[name] = struct{}{}
+	}
+
+	return nil
+}
+
+// Config wraps this MultiLayerConfig in a generic Config struct.
+func (c MultiLayerConfig) Config() Config {
+	return Config{
+		Type:       MultiLayer,
+		MultiLayer: c,
+	}
+}
+
+func (c MultiLayerConfig) cacheNames() []string {
+	return c.Layers
+}
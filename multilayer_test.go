@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestMultiLayerCache(t *testing.T) (*MultiLayerCache, *InMemoryCache, *RedisCache) {
+	inMemory, err := NewInMemoryCache("test_multilayer_inmemory", InMemoryCacheConfig{CacheType: Ristretto})
+	if err != nil {
+		t.Fatalf("new in-memory cache err: %v", err)
+	}
+	redisCache := newTestRedisCache(t)
+
+	c, err := NewMultiLayerCache("test_multilayer", MultiLayerConfig{
+		Layers: []string{"test_multilayer_inmemory", "test_redis"},
+	}, inMemory, redisCache)
+	if err != nil {
+		t.Fatalf("new multilayer cache err: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+
+	return c, inMemory, redisCache
+}
+
+// getLayerHeader returns the meta header of key stored in the layer
+func getLayerHeader(t *testing.T, layer ComposableCache, key string) metaHeader {
+	inner := layer.loadInner()
+	val, err := inner.cache.get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("get %v from layer %v err: %v", key, inner.name, err)
+	}
+	_, header, err := inner.decode(val, false)
+	if err != nil {
+		t.Fatalf("decode %v from layer %v err: %v", key, inner.name, err)
+	}
+	return header
+}
+
+func TestMultiLayerCacheSetWithLayerExpiration(t *testing.T) {
+	ctx := context.Background()
+	c, inMemory, redisCache := newTestMultiLayerCache(t)
+
+	err := c.Set(ctx, "k", "v", time.Hour, WithWaitRistretto(),
+		WithHardExpirationMultiLayer([]time.Duration{time.Minute, DefaultExpiration}),
+		WithSoftExpirationMultiLayer([]time.Duration{10 * time.Second, 0}))
+	if err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+
+	now := time.Now().Unix()
+	if header := getLayerHeader(t, inMemory, "k"); header.HardTimeoutTs-now > 60 || header.SoftTimeoutTs-now > 10 || header.SoftTimeoutTs == 0 {
+		t.Fatalf("unexpected header of in-memory layer: %+v", header)
+	}
+	if header := getLayerHeader(t, redisCache, "k"); header.HardTimeoutTs-now < int64(defaultRedisExpiration.Seconds())-1 || header.SoftTimeoutTs != 0 {
+		t.Fatalf("unexpected header of redis layer: %+v", header)
+	}
+
+	var val string
+	if err = c.Get(ctx, "k", &val); err != nil || val != "v" {
+		t.Fatalf("unexpected value %v, err: %v", val, err)
+	}
+
+	if err = c.Delete(ctx, "k"); err != nil {
+		t.Fatalf("delete err: %v", err)
+	}
+	if err = c.Delete(ctx, "k"); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss on deleting non-exist key, got: %v", err)
+	}
+}
+
+func TestMultiLayerCacheReadThrough(t *testing.T) {
+	ctx := context.Background()
+	c, inMemory, redisCache := newTestMultiLayerCache(t)
+
+	if err := redisCache.SetMany(ctx, map[string]interface{}{"k1": "v1", "k2": "v2"}, time.Minute); err != nil {
+		t.Fatalf("set to redis layer err: %v", err)
+	}
+
+	var v1, v2, v3 string
+	receiverMap := map[string]interface{}{"k1": &v1, "k2": &v2, "k3": &v3}
+	if err := c.GetMany(ctx, receiverMap, WithWaitRistretto(), WithHardExpirationMultiLayer([]time.Duration{time.Second})); err != nil {
+		t.Fatalf("get many err: %v", err)
+	}
+	if v1 != "v1" || v2 != "v2" || receiverMap["k3"] != nil {
+		t.Fatalf("unexpected get many result: %v, %v, %v", v1, v2, receiverMap["k3"])
+	}
+
+	// upper layer is back-filled with the per-layer expiration
+	var backfilled string
+	if err := inMemory.Get(ctx, "k1", &backfilled); err != nil || backfilled != "v1" {
+		t.Fatalf("expect in-memory layer back-filled, got %v, err: %v", backfilled, err)
+	}
+	if header := getLayerHeader(t, inMemory, "k1"); header.HardTimeoutTs-time.Now().Unix() > 1 {
+		t.Fatalf("unexpected header of back-filled data: %+v", header)
+	}
+}
+
+func TestMultiLayerCacheLoad(t *testing.T) {
+	ctx := context.Background()
+	c, inMemory, redisCache := newTestMultiLayerCache(t)
+
+	calls := 0
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		calls++
+		res := make([]interface{}, len(keys))
+		for i, key := range keys {
+			res[i] = "loaded_" + key
+		}
+		return res, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		var val string
+		if err := c.Load(ctx, loader, "k", &val, time.Minute, WithWaitRistretto()); err != nil {
+			t.Fatalf("load err: %v", err)
+		}
+		if val != "loaded_k" {
+			t.Fatalf("unexpected loaded value: %v", val)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expect loader to be called once, got %v", calls)
+	}
+
+	for _, layer := range []Cache{inMemory, redisCache} {
+		var val string
+		if err := layer.Load(ctx, nil, "k", &val, time.Minute); err != nil || val != "loaded_k" {
+			t.Fatalf("expect loaded value in every layer, got %v, err: %v", val, err)
+		}
+	}
+}
+
+func TestMultiLayerConfigValidate(t *testing.T) {
+	if err := (MultiLayerConfig{Layers: []string{"a"}}).Validate(); err == nil {
+		t.Fatalf("expect error for single layer")
+	}
+	if err := (MultiLayerConfig{Layers: []string{"a", "a"}}).Validate(); err == nil {
+		t.Fatalf("expect error for duplicated layers")
+	}
+	if err := (MultiLayerConfig{Layers: []string{"a", "b"}}).Validate(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
+
+func TestMultiLayerCacheConditionalOperations(t *testing.T) {
+	ctx := context.Background()
+	c, inMemory, redisCache := newTestMultiLayerCache(t)
+
+	if err := c.Replace(ctx, "k", "v1", time.Minute, WithWaitRistretto()); err != ErrNotStored {
+		t.Fatalf("expect not stored on replacing non-exist key, got: %v", err)
+	}
+	if err := c.Add(ctx, "k", "v1", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("add err: %v", err)
+	}
+	if err := c.Add(ctx, "k", "v2", time.Minute, WithWaitRistretto()); err != ErrNotStored {
+		t.Fatalf("expect not stored on adding exist key, got: %v", err)
+	}
+	if err := c.Replace(ctx, "k", "v3", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("replace err: %v", err)
+	}
+	for _, layer := range []Cache{inMemory, redisCache} {
+		var val string
+		if err := layer.Get(ctx, "k", &val); err != nil || val != "v3" {
+			t.Fatalf("expect value written to all layers, got %v, err: %v", val, err)
+		}
+	}
+
+	// the counter is kept in the last layer only
+	if err := inMemory.Set(ctx, "counter", "stale", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set to in-memory layer err: %v", err)
+	}
+	if n, err := c.Increment(ctx, "counter", 5); err != nil || n != 5 {
+		t.Fatalf("unexpected increment result %v, err: %v", n, err)
+	}
+	if n, err := c.Decrement(ctx, "counter", 2); err != nil || n != 3 {
+		t.Fatalf("unexpected decrement result %v, err: %v", n, err)
+	}
+	var stale string
+	if err := inMemory.Get(ctx, "counter", &stale); err != ErrCacheMiss {
+		t.Fatalf("expect counter deleted from in-memory layer, got %v, err: %v", stale, err)
+	}
+
+	if err := c.Expire(ctx, "non_exist", time.Minute); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss on expiring non-exist key, got: %v", err)
+	}
+}
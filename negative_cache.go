@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"time"
+)
+
+// genTombstoneHeader generates the header of the tombstone which expires after ttl from now.
+// The hard timeout is in seconds, so it is rounded up, otherwise a sub-second ttl expires the tombstone before it is cached.
+func genTombstoneHeader(now time.Time, ttl time.Duration) metaHeader {
+	expireAt := now.Add(ttl)
+	hardTimeoutTs := expireAt.Unix()
+	if expireAt.Nanosecond() > 0 {
+		hardTimeoutTs++
+	}
+	return metaHeader{HardTimeoutTs: hardTimeoutTs, Tombstone: true}
+}
+
+// isTombstoneSupported checks whether tombstones can be cached,
+// which are marked in the header of bytes protocol thus require encoding for the caches other than in-memory
+func (c *cacheWrapperInner) isTombstoneSupported() bool {
+	return c.cacheType == inMemory || !c.encodingHandler.disableEncoding
+}
+
+// encodeTombstone encodes a tombstone with option, the tombstone carries no data
+func (c *cacheWrapperInner) encodeTombstone(option *cacheOperationOptions) (interface{}, error) {
+	option.tombstone = true
+	defer func() {
+		option.tombstone = false
+	}()
+
+	if c.cacheType == inMemory {
+		return c.encode(nil, option)
+	}
+	return c.encode([]byte{}, option)
+}
+
+// isAbsentData checks whether the cached value is regarded as absent though it exists in the cache,
+// i.e. a tombstone or the data kept past hard expiration for stale-if-error
+func (c *cacheWrapperInner) isAbsentData(value interface{}) bool {
+	_, header, err := c.decode(value, false)
+	return err == nil && (header.Tombstone || isHardExpired(time.Now(), header))
+}
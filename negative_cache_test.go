@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func testCacheNegativeCaching(t *testing.T, c Cache, opts ...OperationOption) {
+	ctx := context.Background()
+
+	loadCount := 0
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		loadCount++
+		return make([]interface{}, len(keys)), nil
+	}
+	loadOpts := append([]OperationOption{WithNegativeCaching(time.Minute)}, opts...)
+
+	var val string
+	for i := 0; i < 2; i++ {
+		if err := c.Load(ctx, loader, "key", &val, time.Minute, loadOpts...); err != ErrCacheMiss {
+			t.Fatalf("expect cache miss, got: %v", err)
+		}
+	}
+	if loadCount != 1 {
+		t.Fatalf("expect the loader called once, got: %v", loadCount)
+	}
+
+	if err := c.Get(ctx, "key", &val); err != ErrCacheMiss {
+		t.Fatalf("expect the tombstone missing for get, got: %v", err)
+	}
+	receiverMap := map[string]interface{}{"key": &val}
+	if err := c.GetMany(ctx, receiverMap); err != nil || receiverMap["key"] != nil {
+		t.Fatalf("expect the tombstone missing for get many, got: %v, err: %v", receiverMap, err)
+	}
+
+	// the tombstone is overwritten by add
+	if err := c.Add(ctx, "key", "val", time.Minute, opts...); err != nil {
+		t.Fatalf("add err: %v", err)
+	}
+	if err := c.Get(ctx, "key", &val); err != nil || val != "val" {
+		t.Fatalf("expect val, got: %v, err: %v", val, err)
+	}
+
+	// the non-existence is not cached without negative caching
+	for i := 0; i < 2; i++ {
+		if err := c.Load(ctx, loader, "not_cached", &val, time.Minute, opts...); err != ErrCacheMiss {
+			t.Fatalf("expect cache miss, got: %v", err)
+		}
+	}
+	if loadCount != 3 {
+		t.Fatalf("expect the loader called on each load, got: %v", loadCount)
+	}
+}
+
+func TestRedisCacheNegativeCaching(t *testing.T) {
+	testCacheNegativeCaching(t, newTestRedisCache(t))
+}
+
+func TestMemcachedCacheNegativeCaching(t *testing.T) {
+	c, _ := newTestMemcachedCache(t)
+	testCacheNegativeCaching(t, c)
+}
+
+func TestInMemoryCacheNegativeCaching(t *testing.T) {
+	testCacheNegativeCaching(t, newTestInMemoryCache(t), WithWaitRistretto())
+}
+
+func TestMultiLayerCacheNegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	c, local, _ := newTestMultiLayerCache(t)
+	testCacheNegativeCaching(t, c, WithWaitRistretto())
+
+	// the tombstone is backfilled to the outer layers
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return make([]interface{}, len(keys)), nil
+	}
+	var val string
+	if err := c.Load(ctx, loader, "another", &val, time.Minute, WithNegativeCaching(time.Minute), WithWaitRistretto()); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss, got: %v", err)
+	}
+	failedLoader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		t.Fatalf("expect the tombstone served by the outer layer")
+		return nil, nil
+	}
+	if err := local.Load(ctx, failedLoader, "another", &val, time.Minute); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss, got: %v", err)
+	}
+}
+
+func TestNegativeCachingSubSecondTTL(t *testing.T) {
+	ctx := context.Background()
+	c := newTestRedisCache(t)
+
+	loadCount := 0
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		loadCount++
+		return make([]interface{}, len(keys)), nil
+	}
+	var val string
+	for i := 0; i < 2; i++ {
+		if err := c.Load(ctx, loader, "key", &val, time.Minute, WithNegativeCaching(500*time.Millisecond)); err != ErrCacheMiss {
+			t.Fatalf("expect cache miss, got: %v", err)
+		}
+	}
+	if loadCount != 1 {
+		t.Fatalf("expect the tombstone cached for a sub-second ttl, got load count: %v", loadCount)
+	}
+
+	// the tombstone expires within 1 second after ttl
+	time.Sleep(1600 * time.Millisecond)
+	if err := c.Load(ctx, loader, "key", &val, time.Minute, WithNegativeCaching(500*time.Millisecond)); err != ErrCacheMiss || loadCount != 2 {
+		t.Fatalf("expect the loader called after the tombstone expired, got load count: %v, err: %v", loadCount, err)
+	}
+}
+
+func TestNegativeCachingConfig(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{
+		Address:            server.addr(),
+		ManufacturerConfig: ManufacturerConfig{NegativeCacheConfig: NegativeCacheConfig{TTLMillis: time.Minute.Milliseconds()}},
+	})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	loadCount := 0
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		loadCount++
+		return make([]interface{}, len(keys)), nil
+	}
+	var val string
+	for i := 0; i < 2; i++ {
+		if err = c.Load(ctx, loader, "key", &val, time.Minute); err != ErrCacheMiss {
+			t.Fatalf("expect cache miss, got: %v", err)
+		}
+	}
+	if loadCount != 1 {
+		t.Fatalf("expect the non-existence cached by default, got load count: %v", loadCount)
+	}
+
+	if _, err = NewRedisCache("test_redis", RedisConfig{
+		Address:            server.addr(),
+		ManufacturerConfig: ManufacturerConfig{NegativeCacheConfig: NegativeCacheConfig{TTLMillis: -1}},
+	}); err == nil {
+		t.Fatalf("expect err for negative ttl")
+	}
+}
+
+func TestNegativeCachingEncodingDisabled(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{Address: server.addr(), EncodingConfig: EncodingConfig{DisableEncoding: true}})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	loadCount := 0
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		loadCount++
+		return make([]interface{}, len(keys)), nil
+	}
+	var val string
+	for i := 0; i < 2; i++ {
+		if err = c.Load(ctx, loader, "key", &val, time.Minute, WithNegativeCaching(time.Minute)); err != ErrCacheMiss {
+			t.Fatalf("expect cache miss, got: %v", err)
+		}
+	}
+	if loadCount != 2 {
+		t.Fatalf("expect no tombstone cached without encoding, got load count: %v", loadCount)
+	}
+}
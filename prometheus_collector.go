@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultPrometheusCollector is the built-in collector enabled by ObservationConfig.EnablePrometheusMetrics
+var defaultPrometheusCollector = &defaultCollector{}
+
+const unknown = "unknown"
+
+// defaultDurationBuckets are the upper bounds (in seconds) of the buckets of the operation duration histogram
+var defaultDurationBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// PrometheusHandler returns the http.Handler exposing the metrics of the built-in Prometheus collector
+// in the Prometheus text exposition format.
+func PrometheusHandler() http.Handler {
+	return defaultPrometheusCollector
+}
+
+// defaultCollector aggregates RequestStats into counters and histograms labeled by cache name, cache type and operation
+type defaultCollector struct {
+	mu      sync.Mutex
+	metrics map[metricLabels]*operationMetrics
+}
+
+type metricLabels struct {
+	cacheName string
+	cacheType string
+	operation string
+}
+
+type operationMetrics struct {
+	errTypeCounts  map[ErrorType]uint64
+	hitKeyCount    uint64
+	missKeyCount   uint64
+	bucketCounts   []uint64 // non-cumulative count of each bucket in defaultDurationBuckets
+	durationSum    float64
+	durationCount  uint64
+	mismatchedKeys uint64
+}
+
+// CollectStats (refer to CollectStats of StatsCollector interface)
+func (c *defaultCollector) CollectStats(ctx context.Context, stats RequestStats) {
+	labels := metricLabels{
+		cacheName: labelValueOrUnknown(stats.CacheName),
+		cacheType: labelValueOrUnknown(stats.CacheType),
+		operation: labelValueOrUnknown(stats.CacheOperation),
+	}
+	seconds := stats.Elapsed.Seconds()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.metrics == nil {
+		c.metrics = make(map[metricLabels]*operationMetrics)
+	}
+	m, ok := c.metrics[labels]
+	if !ok {
+		m = &operationMetrics{
+			errTypeCounts: make(map[ErrorType]uint64),
+			bucketCounts:  make([]uint64, len(defaultDurationBuckets)),
+		}
+		c.metrics[labels] = m
+	}
+
+	m.errTypeCounts[stats.ErrType]++
+	if isReadOperation(stats.CacheOperation) {
+		m.hitKeyCount += uint64(stats.SuccessKeyCount)
+		m.missKeyCount += uint64(stats.MissKeyCount)
+	}
+	m.mismatchedKeys += uint64(stats.MismatchKeyCount)
+	if idx := sort.SearchFloat64s(defaultDurationBuckets, seconds); idx < len(defaultDurationBuckets) {
+		m.bucketCounts[idx]++
+	}
+	m.durationSum += seconds
+	m.durationCount++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (c *defaultCollector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	c.writeMetrics(bw)
+	_ = bw.Flush()
+}
+
+func (c *defaultCollector) writeMetrics(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	labelsList := make([]metricLabels, 0, len(c.metrics))
+	for labels := range c.metrics {
+		labelsList = append(labelsList, labels)
+	}
+	sort.Slice(labelsList, func(i, j int) bool {
+		a, b := labelsList[i], labelsList[j]
+		if a.cacheName != b.cacheName {
+			return a.cacheName < b.cacheName
+		}
+		if a.cacheType != b.cacheType {
+			return a.cacheType < b.cacheType
+		}
+		return a.operation < b.operation
+	})
+
+	writeMetricHeader(w, "cache_operations_total", "counter", "Total number of cache operations by error type.")
+	for _, labels := range labelsList {
+		m := c.metrics[labels]
+		errTypes := make([]string, 0, len(m.errTypeCounts))
+		for errType := range m.errTypeCounts {
+			errTypes = append(errTypes, string(errType))
+		}
+		sort.Strings(errTypes)
+		for _, errType := range errTypes {
+			writeMetric(w, "cache_operations_total", labels.format("error_type", labelValueOrUnknown(errType)), float64(m.errTypeCounts[ErrorType(errType)]))
+		}
+	}
+
+	writeMetricHeader(w, "cache_hit_keys_total", "counter", "Total number of keys found by cache read operations.")
+	for _, labels := range labelsList {
+		if isReadOperation(labels.operation) {
+			writeMetric(w, "cache_hit_keys_total", labels.format(), float64(c.metrics[labels].hitKeyCount))
+		}
+	}
+
+	writeMetricHeader(w, "cache_miss_keys_total", "counter", "Total number of keys not found by cache read operations.")
+	for _, labels := range labelsList {
+		if isReadOperation(labels.operation) {
+			writeMetric(w, "cache_miss_keys_total", labels.format(), float64(c.metrics[labels].missKeyCount))
+		}
+	}
+
+	writeMetricHeader(w, "cache_mismatch_keys_total", "counter", "Total number of keys mismatched between the primary and the secondary cache of migration caches.")
+	for _, labels := range labelsList {
+		if labels.operation == cmdCompare {
+			writeMetric(w, "cache_mismatch_keys_total", labels.format(), float64(c.metrics[labels].mismatchedKeys))
+		}
+	}
+
+	writeMetricHeader(w, "cache_operation_duration_seconds", "histogram", "Duration of cache operations in seconds.")
+	for _, labels := range labelsList {
+		m := c.metrics[labels]
+		var cumulativeCount uint64
+		for idx, upperBound := range defaultDurationBuckets {
+			cumulativeCount += m.bucketCounts[idx]
+			writeMetric(w, "cache_operation_duration_seconds_bucket", labels.format("le", formatFloat(upperBound)), float64(cumulativeCount))
+		}
+		writeMetric(w, "cache_operation_duration_seconds_bucket", labels.format("le", "+Inf"), float64(m.durationCount))
+		writeMetric(w, "cache_operation_duration_seconds_sum", labels.format(), m.durationSum)
+		writeMetric(w, "cache_operation_duration_seconds_count", labels.format(), float64(m.durationCount))
+	}
+}
+
+// format formats the labels with the extra label pairs in the Prometheus text exposition format
+func (l metricLabels) format(extraPairs ...string) string {
+	var sb strings.Builder
+	sb.WriteString(`{cache_name="`)
+	sb.WriteString(escapeLabelValue(l.cacheName))
+	sb.WriteString(`",cache_type="`)
+	sb.WriteString(escapeLabelValue(l.cacheType))
+	sb.WriteString(`",operation="`)
+	sb.WriteString(escapeLabelValue(l.operation))
+	sb.WriteString(`"`)
+	for idx := 0; idx+1 < len(extraPairs); idx += 2 {
+		sb.WriteString(`,`)
+		sb.WriteString(extraPairs[idx])
+		sb.WriteString(`="`)
+		sb.WriteString(escapeLabelValue(extraPairs[idx+1]))
+		sb.WriteString(`"`)
+	}
+	sb.WriteString(`}`)
+	return sb.String()
+}
+
+func writeMetricHeader(w *bufio.Writer, name, metricType, help string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func writeMetric(w *bufio.Writer, name, labels string, value float64) {
+	_, _ = fmt.Fprintf(w, "%s%s %s\n", name, labels, formatFloat(value))
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func labelValueOrUnknown(value string) string {
+	if value == "" {
+		return unknown
+	}
+	return value
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultCollector(t *testing.T) {
+	ctx := context.Background()
+	collector := &defaultCollector{}
+	collector.CollectStats(ctx, RequestStats{CacheName: "c", CacheType: "redis", CacheOperation: cmdGetMany,
+		TotalKeyCount: 3, SuccessKeyCount: 2, MissKeyCount: 1, Elapsed: 2 * time.Millisecond, ErrType: ErrorTypeNone})
+	collector.CollectStats(ctx, RequestStats{CacheName: "c", CacheType: "redis", CacheOperation: cmdGetMany,
+		TotalKeyCount: 1, Elapsed: 3 * time.Second, ErrType: ErrorTypeTimeout})
+	collector.CollectStats(ctx, RequestStats{CacheName: `a"b`, CacheOperation: cmdSet, ErrType: ErrorTypeNone})
+
+	recorder := httptest.NewRecorder()
+	collector.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+
+	for _, expected := range []string{
+		"# TYPE cache_operations_total counter",
+		`cache_operations_total{cache_name="c",cache_type="redis",operation="GetMany",error_type="none"} 1`,
+		`cache_operations_total{cache_name="c",cache_type="redis",operation="GetMany",error_type="timeout"} 1`,
+		`cache_operations_total{cache_name="a\"b",cache_type="unknown",operation="Set",error_type="none"} 1`,
+		`cache_hit_keys_total{cache_name="c",cache_type="redis",operation="GetMany"} 2`,
+		`cache_miss_keys_total{cache_name="c",cache_type="redis",operation="GetMany"} 1`,
+		"# TYPE cache_operation_duration_seconds histogram",
+		`cache_operation_duration_seconds_bucket{cache_name="c",cache_type="redis",operation="GetMany",le="0.001"} 0`,
+		`cache_operation_duration_seconds_bucket{cache_name="c",cache_type="redis",operation="GetMany",le="0.0025"} 1`,
+		`cache_operation_duration_seconds_bucket{cache_name="c",cache_type="redis",operation="GetMany",le="2.5"} 1`,
+		`cache_operation_duration_seconds_bucket{cache_name="c",cache_type="redis",operation="GetMany",le="+Inf"} 2`,
+		`cache_operation_duration_seconds_sum{cache_name="c",cache_type="redis",operation="GetMany"} 3.002`,
+		`cache_operation_duration_seconds_count{cache_name="c",cache_type="redis",operation="GetMany"} 2`,
+	} {
+		if !strings.Contains(body, expected+"\n") {
+			t.Fatalf("expect %q in metrics:\n%v", expected, body)
+		}
+	}
+	if strings.Contains(body, `cache_hit_keys_total{cache_name="a\"b"`) {
+		t.Fatalf("expect no hit keys reported for write operations:\n%v", body)
+	}
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewInMemoryCache("test_prometheus", InMemoryCacheConfig{
+		CacheType:         Ristretto,
+		ObservationConfig: ObservationConfig{EnablePrometheusMetrics: true},
+	})
+	if err != nil {
+		t.Fatalf("new in-memory cache err: %v", err)
+	}
+	defer c.Close(ctx)
+
+	var val string
+	_ = c.Get(ctx, "k", &val)
+
+	recorder := httptest.NewRecorder()
+	PrometheusHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	expected := `cache_miss_keys_total{cache_name="test_prometheus",cache_type="inmemory",operation="Get"} 1`
+	if !strings.Contains(recorder.Body.String(), expected) {
+		t.Fatalf("expect %q in metrics:\n%v", expected, recorder.Body.String())
+	}
+}
@@ -22,7 +22,8 @@ const (
 
 	magicPrefix = "_@@_"
 
-	headerFlag = 8 // headerproto flag is the 4th bit in attribute byte
+	headerFlag    = 8  // headerproto flag is the 4th bit in attribute byte
+	tombstoneFlag = 16 // tombstone flag is the 5th bit in attribute byte, marking the cached non-existence of a key
 )
 
 var (
@@ -59,15 +60,34 @@ func (i *reservedBytes) writeHeaderFlag() {
 	*i |= headerFlag
 }
 
+func (i reservedBytes) readTombstoneFlag() bool {
+	return uint32(i)&tombstoneFlag == tombstoneFlag
+}
+
+func (i *reservedBytes) writeTombstoneFlag() {
+	*i |= tombstoneFlag
+}
+
 // metaHeader stores meta info for the data bytes
 type metaHeader struct {
 	SoftTimeoutTs int64
 	HardTimeoutTs int64
+	Tags          []string
+	TagVersions   []int64 // versions of Tags in the shared cache when the data is cached, empty for in-memory cache
+	Compression   string  // the name of the compression algorithm of the encoded data, empty if the data is not encoded
+	Version       int64   // the version of the data generated on every write, refer to CompareAndSwap
+	ComputeTimeMs int64   // the time in milliseconds the DataLoader took to load the data, 0 if the data is not loaded by DataLoader
+	Tombstone     bool    // whether the data is a tombstone caching the non-existence of the key, refer to WithNegativeCaching
 }
 
 type protocolOption struct {
 	softTimeoutTs int64
 	hardTimeoutTs int64
+	tags          []string
+	tagVersions   []int64
+	version       int64
+	computeTimeMs int64
+	tombstone     bool
 }
 
 func newProtocolOption() *protocolOption {
@@ -94,6 +114,35 @@ func withHardTimeoutTs(timeoutTs int64) bytesProtocolOption {
 	}
 }
 
+// withTags sets tags and their versions
+func withTags(tags []string, tagVersions []int64) bytesProtocolOption {
+	return func(option *protocolOption) {
+		option.tags = tags
+		option.tagVersions = tagVersions
+	}
+}
+
+// withVersion sets the version of the data
+func withVersion(version int64) bytesProtocolOption {
+	return func(option *protocolOption) {
+		option.version = version
+	}
+}
+
+// withComputeTimeMs sets the time in milliseconds the DataLoader took to load the data
+func withComputeTimeMs(computeTimeMs int64) bytesProtocolOption {
+	return func(option *protocolOption) {
+		option.computeTimeMs = computeTimeMs
+	}
+}
+
+// withTombstone marks the data as a tombstone
+func withTombstone(tombstone bool) bytesProtocolOption {
+	return func(option *protocolOption) {
+		option.tombstone = tombstone
+	}
+}
+
 // bytesEncode <data_bytes> into <magic_prefix><attr_bytes><header_len><header_bytes><data_len><original/compressed_data_bytes>
 // magic prefix bytes is the identifier of checking whether the bytes has been proceeded by the unified cache lib
 func bytesEncode(byt []byte, compressionType compression.AlgoType, opts ...bytesProtocolOption) ([]byte, error) {
@@ -115,6 +164,14 @@ func bytesEncode(byt []byte, compressionType compression.AlgoType, opts ...bytes
 		CompressionType: &algoType,
 		SoftTimeoutTs:   &softTimoutTs,
 		HardTimeoutTs:   &hardTimeoutTs,
+		Tags:            option.tags,
+		TagVersions:     option.tagVersions,
+	}
+	if option.version != 0 {
+		curHeader.Version = &option.version
+	}
+	if option.computeTimeMs != 0 {
+		curHeader.ComputeTimeMs = &option.computeTimeMs
 	}
 
 	var headerBytes []byte
@@ -125,6 +182,9 @@ func bytesEncode(byt []byte, compressionType compression.AlgoType, opts ...bytes
 
 	var flag reservedBytes
 	flag.writeHeaderFlag()
+	if option.tombstone {
+		flag.writeTombstoneFlag()
+	}
 
 	var finalBytes []byte
 	finalBytes = append(finalBytes, []byte(magicPrefix)...)
@@ -191,6 +251,11 @@ func bytesDecode(byt []byte) ([]byte, metaHeader, error) {
 
 			curHeader.HardTimeoutTs = *receiver.HardTimeoutTs
 			curHeader.SoftTimeoutTs = *receiver.SoftTimeoutTs
+			curHeader.Tags = receiver.Tags
+			curHeader.TagVersions = receiver.TagVersions
+			curHeader.Version = receiver.GetVersion()
+			curHeader.ComputeTimeMs = receiver.GetComputeTimeMs()
+			curHeader.Tombstone = rbi.readTombstoneFlag()
 		} else {
 			// the below logic is to provide smooth migration experience
 			// for old bytes protocol with bytes layout like `<...magic prefix bytes...><...attribute bytes...><...original/compressed data bytes>`
@@ -198,6 +263,8 @@ func bytesDecode(byt []byte) ([]byte, metaHeader, error) {
 			dataByt = byt[idx:]
 		}
 
+		curHeader.Compression = compressionType.String()
+
 		decompressedBytes, err := compression.Decompress(dataByt, compressionType)
 		if err != nil {
 			return nil, metaHeader{}, err
@@ -246,6 +313,10 @@ func inMemoryEncode(val interface{}, opts ...bytesProtocolOption) (inMemoryItem,
 	if option.hardTimeoutTs != 0 {
 		header.HardTimeoutTs = option.hardTimeoutTs
 	}
+	header.Tags = option.tags
+	header.Version = option.version
+	header.ComputeTimeMs = option.computeTimeMs
+	header.Tombstone = option.tombstone
 
 	return inMemoryItem{Header: header, Val: val}, nil
 }
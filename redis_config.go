@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"go-eCache/codec"
+	redisclient "go-eCache/internal/client/redis"
+)
+
+const (
+	// defaultRedisExpiration defines default expiration for redis cache if not set by client explicitly
+	defaultRedisExpiration = time.Duration(86400) * time.Second
+
+	defaultRedisMaxIdle            = 10
+	defaultRedisIdleTimeoutSecs    = 240
+	defaultRedisDialTimeoutMillis  = 1000
+	defaultRedisReadTimeoutMillis  = 500
+	defaultRedisWriteTimeoutMillis = 500
+)
+
+// RedisPoolConfig defines the connection pool behavior of RedisCache
+type RedisPoolConfig struct {
+	// MaxIdle is the maximum number of idle connections kept in the pool. Default value is 10.
+	MaxIdle int `yaml:"max_idle" json:"max_idle"`
+
+	// MaxActive is the maximum number of connections allocated by the pool at a given time.
+	// Default value is 0, means no limit.
+	MaxActive int `yaml:"max_active" json:"max_active"`
+
+	// IdleTimeoutSecs closes connections after remaining idle for this duration. Default value is 240.
+	IdleTimeoutSecs int `yaml:"idle_timeout_secs" json:"idle_timeout_secs"`
+
+	// DialTimeoutMillis is the timeout for establishing new connections. Default value is 1000.
+	DialTimeoutMillis int `yaml:"dial_timeout_millis" json:"dial_timeout_millis"`
+
+	// ReadTimeoutMillis is the timeout for reading a reply, the deadline of ctx takes effect if it is earlier.
+	// Default value is 500.
+	ReadTimeoutMillis int `yaml:"read_timeout_millis" json:"read_timeout_millis"`
+
+	// WriteTimeoutMillis is the timeout for writing a command, the deadline of ctx takes effect if it is earlier.
+	// Default value is 500.
+	WriteTimeoutMillis int `yaml:"write_timeout_millis" json:"write_timeout_millis"`
+}
+
+// Validate checks if the RedisPoolConfig is valid.
+func (c RedisPoolConfig) Validate() error {
+	if c.MaxIdle < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_max_idle: %v", c.MaxIdle))
+	}
+	if c.MaxActive < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_max_active: %v", c.MaxActive))
+	}
+	if c.IdleTimeoutSecs < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_idle_timeout_secs: %v", c.IdleTimeoutSecs))
+	}
+	if c.DialTimeoutMillis < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_dial_timeout_millis: %v", c.DialTimeoutMillis))
+	}
+	if c.ReadTimeoutMillis < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_read_timeout_millis: %v", c.ReadTimeoutMillis))
+	}
+	if c.WriteTimeoutMillis < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_write_timeout_millis: %v", c.WriteTimeoutMillis))
+	}
+	return nil
+}
+
+func (c *RedisPoolConfig) setDefaultValue() {
+	if c.MaxIdle == 0 {
+		c.MaxIdle = defaultRedisMaxIdle
+	}
+	if c.IdleTimeoutSecs == 0 {
+		c.IdleTimeoutSecs = defaultRedisIdleTimeoutSecs
+	}
+	if c.DialTimeoutMillis == 0 {
+		c.DialTimeoutMillis = defaultRedisDialTimeoutMillis
+	}
+	if c.ReadTimeoutMillis == 0 {
+		c.ReadTimeoutMillis = defaultRedisReadTimeoutMillis
+	}
+	if c.WriteTimeoutMillis == 0 {
+		c.WriteTimeoutMillis = defaultRedisWriteTimeoutMillis
+	}
+}
+
+// RedisConfig defines config used to construct a RedisCache
+type RedisConfig struct {
+	// Address is the `host:port` of the redis server
+	Address string `yaml:"address" json:"address"`
+
+	// Password is used to AUTH new connections if not empty
+	Password string `yaml:"password" json:"password"`
+
+	// DB is the database selected by new connections. Default value is 0.
+	DB int `yaml:"db" json:"db"`
+
+	// PoolConfig defines the connection pool behavior
+	PoolConfig RedisPoolConfig `yaml:"pool_config" json:"pool_config"`
+
+	// DefaultExpirationSecs defines default cache data expiration in seconds. If zero, default value 86400 is used.
+	DefaultExpirationSecs int `yaml:"default_expiration_secs" json:"default_expiration_secs"`
+
+	// MaxExpirationSecs defines the max expiration of a key in cache
+	// Default value is 0, same as not set, means no max expiration
+	// It has higher priority than DefaultExpirationSecs, but lower priority than NoExpiration.
+	MaxExpirationSecs int `yaml:"max_expiration_secs" json:"max_expiration_secs"`
+
+	// CodecConfig is the config to control default codec type
+	CodecConfig codec.Config `yaml:"codec_config" json:"codec_config"`
+
+	// EncodingConfig is the config to control built in bytes protocol and compression
+	EncodingConfig EncodingConfig `yaml:"encoding_config" json:"encoding_config"`
+
+	// ManufacturerConfig defines the behavior for `Load` and `LoadMany`
+	ManufacturerConfig ManufacturerConfig `yaml:"manufacturer_config" json:"manufacturer_config"`
+
+	// KeyConfig defines how keys are transformed before being sent to the cache e.g. namespacing
+	KeyConfig KeyConfig `yaml:"key_config" json:"key_config"`
+
+	// DisableConfig defines whether the cache is disabled, refer to DisableConfig for the behavior of operations
+	DisableConfig DisableConfig `yaml:"disable_config" json:"disable_config"`
+
+	// ObservationConfig defines how the operations of the cache are observed e.g. stats collection
+	ObservationConfig ObservationConfig `yaml:"observation_config" json:"observation_config"`
+
+	// HotKeyConfig defines the detection of hot keys
+	HotKeyConfig HotKeyConfig `yaml:"hot_key_config" json:"hot_key_config"`
+}
+
+// Validate checks if config is valid
+func (c RedisConfig) Validate() error {
+	if c.Address == "" {
+		return cacheErr("invalid_config_address_empty")
+	}
+	if c.DB < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_db: %v", c.DB))
+	}
+	if c.DefaultExpirationSecs < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_default_expiration_secs: %v", c.DefaultExpirationSecs))
+	}
+	if c.MaxExpirationSecs < 0 {
+		return cacheErr(fmt.Sprintf("invalid_config_max_expiration_secs: %v", c.MaxExpirationSecs))
+	}
+	if err := c.PoolConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.CodecConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.EncodingConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.ManufacturerConfig.Validate(Redis); err != nil {
+		return err
+	}
+	if err := c.KeyConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.ObservationConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.HotKeyConfig.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Config wraps this RedisConfig in a generic Config struct.
+func (c RedisConfig) Config() Config {
+	return Config{
+		Type:  Redis,
+		Redis: c,
+	}
+}
+
+func (c RedisConfig) defaultExpiration() time.Duration {
+	configDefaultExpire := time.Duration(c.DefaultExpirationSecs) * time.Second
+	if configDefaultExpire == DefaultExpiration {
+		return defaultRedisExpiration
+	}
+	return configDefaultExpire
+}
+
+// poolConfig converts RedisConfig to the config of internal connection pool
+func (c RedisConfig) poolConfig() redisclient.PoolConfig {
+	poolConfig := c.PoolConfig
+	poolConfig.setDefaultValue()
+
+	return redisclient.PoolConfig{
+		Address:      c.Address,
+		Password:     c.Password,
+		DB:           c.DB,
+		MaxIdle:      poolConfig.MaxIdle,
+		MaxActive:    poolConfig.MaxActive,
+		IdleTimeout:  time.Duration(poolConfig.IdleTimeoutSecs) * time.Second,
+		DialTimeout:  time.Duration(poolConfig.DialTimeoutMillis) * time.Millisecond,
+		ReadTimeout:  time.Duration(poolConfig.ReadTimeoutMillis) * time.Millisecond,
+		WriteTimeout: time.Duration(poolConfig.WriteTimeoutMillis) * time.Millisecond,
+	}
+}
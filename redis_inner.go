@@ -0,0 +1,327 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	redisclient "go-eCache/internal/client/redis"
+)
+
+// redisCacheInner is a wrapper of redis connection pool which impl innerCache Interface
+type redisCacheInner struct {
+	pool       unsafe.Pointer // of type *redisclient.Pool
+	poolConfig redisclient.PoolConfig
+}
+
+func newRedisCache(config RedisConfig) (*redisCacheInner, error) {
+	poolConfig := config.poolConfig()
+
+	return &redisCacheInner{
+		pool:       unsafe.Pointer(redisclient.NewPool(poolConfig)),
+		poolConfig: poolConfig,
+	}, nil
+}
+
+func (c *redisCacheInner) get(ctx context.Context, key string) (interface{}, error) {
+	b, err := redisclient.Bytes(c.loadPool().Do(ctx, "GET", key))
+	if err == redisclient.ErrNil {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (c *redisCacheInner) getMany(ctx context.Context, keys ...string) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(keys)+1)
+	args = append(args, "MGET")
+	for _, key := range keys {
+		args = append(args, key)
+	}
+
+	values, err := redisclient.Values(c.loadPool().Do(ctx, args...))
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != len(keys) {
+		return nil, cacheErr("redis_mget_reply_length_not_match")
+	}
+
+	return values, nil
+}
+
+func (c *redisCacheInner) set(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...innerOperationOption) error {
+	_, err := c.loadPool().Do(ctx, genRedisSetArgs(key, value, expire)...)
+	return err
+}
+
+func (c *redisCacheInner) setMany(ctx context.Context, valueMap map[string]interface{}, expire time.Duration, opts ...innerOperationOption) error {
+	options := newInnerCacheOperationOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	cmds := make([][]interface{}, 0, len(valueMap))
+	for key, value := range valueMap {
+		expiration := expire
+		if exp, ok := options.expirationMap[key]; ok {
+			expiration = exp
+		}
+		cmds = append(cmds, genRedisSetArgs(key, value, expiration))
+	}
+
+	replies, err := c.loadPool().Pipeline(ctx, cmds)
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if replyErr, ok := reply.(redisclient.Error); ok {
+			return replyErr
+		}
+	}
+
+	return nil
+}
+
+func (c *redisCacheInner) add(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...innerOperationOption) error {
+	return c.setConditionally(ctx, key, value, expire, "NX")
+}
+
+func (c *redisCacheInner) replace(ctx context.Context, key string, value interface{}, expire time.Duration, opts ...innerOperationOption) error {
+	return c.setConditionally(ctx, key, value, expire, "XX")
+}
+
+// redisCompareAndSwapScript sets ARGV[2] to KEYS[1] only if the current value of KEYS[1] equals to ARGV[1],
+// with ARGV[3] milliseconds expiration, or no expiration if ARGV[3] is 0. Returns 1 if set, 0 otherwise.
+const redisCompareAndSwapScript = `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if ARGV[3] == '0' then
+	redis.call('SET', KEYS[1], ARGV[2])
+else
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+end
+return 1`
+
+// compareAndSwap reads the current value, and sets value by a script only if the value is not changed since the read
+func (c *redisCacheInner) compareAndSwap(ctx context.Context, key string, match func(value interface{}) bool, value interface{}, expire time.Duration, opts ...innerOperationOption) error {
+	pool := c.loadPool()
+	cur, err := redisclient.Bytes(pool.Do(ctx, "GET", key))
+	if err == redisclient.ErrNil {
+		return ErrNotStored
+	}
+	if err != nil {
+		return err
+	}
+	if !match(cur) {
+		return ErrNotStored
+	}
+
+	var expireMillis int64
+	if expire > 0 {
+		expireMillis = redisExpireMillis(expire)
+	}
+	swapped, err := redisclient.Int64(pool.Do(ctx, "EVAL", redisCompareAndSwapScript, 1, key, cur, value, expireMillis))
+	if err != nil {
+		return err
+	}
+	if swapped == 0 {
+		return ErrNotStored
+	}
+	return nil
+}
+
+// setConditionally performs SET with NX or XX condition, returns ErrNotStored if the condition is not satisfied
+func (c *redisCacheInner) setConditionally(ctx context.Context, key string, value interface{}, expire time.Duration, condition string) error {
+	args := append(genRedisSetArgs(key, value, expire), condition)
+
+	reply, err := c.loadPool().Do(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if reply == nil {
+		return ErrNotStored
+	}
+
+	return nil
+}
+
+// nolint:predeclared
+func (c *redisCacheInner) delete(ctx context.Context, key string) error {
+	n, err := redisclient.Int64(c.loadPool().Do(ctx, "DEL", key))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrCacheMiss
+	}
+
+	return nil
+}
+
+func (c *redisCacheInner) deleteMany(ctx context.Context, keys []string, opts ...innerOperationOption) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, key)
+	}
+
+	_, err := c.loadPool().Do(ctx, args...)
+	return err
+}
+
+func (c *redisCacheInner) deleteManyWithResult(ctx context.Context, keys []string) ([]bool, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	cmds := make([][]interface{}, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, []interface{}{"DEL", key})
+	}
+
+	replies, err := c.loadPool().Pipeline(ctx, cmds)
+	if err != nil {
+		return nil, err
+	}
+
+	existed := make([]bool, len(keys))
+	for idx, reply := range replies {
+		n, err := redisclient.Int64(reply, nil)
+		if err != nil {
+			return nil, err
+		}
+		existed[idx] = n > 0
+	}
+
+	return existed, nil
+}
+
+func (c *redisCacheInner) increment(ctx context.Context, key string, delta uint64, opts ...innerOperationOption) (int64, error) {
+	return c.incrDecr(ctx, key, delta, "INCRBY", opts...)
+}
+
+func (c *redisCacheInner) decrement(ctx context.Context, key string, delta uint64, opts ...innerOperationOption) (int64, error) {
+	return c.incrDecr(ctx, key, delta, "DECRBY", opts...)
+}
+
+// incrDecr performs INCRBY or DECRBY. If `initNonExistKey` is false, the key existence is checked beforehand,
+// which is not atomic with the following operation, a key deleted in between will be re-created with 0.
+func (c *redisCacheInner) incrDecr(ctx context.Context, key string, delta uint64, command string, opts ...innerOperationOption) (int64, error) {
+	options := newInnerCacheOperationOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if delta > math.MaxInt64 {
+		return 0, cacheErr("redis_delta_overflow")
+	}
+
+	pool := c.loadPool()
+	if !options.initNonExistKey {
+		exists, err := redisclient.Int64(pool.Do(ctx, "EXISTS", key))
+		if err != nil {
+			return 0, err
+		}
+		if exists == 0 {
+			return 0, ErrCacheMiss
+		}
+	}
+
+	return redisclient.Int64(pool.Do(ctx, command, key, int64(delta)))
+}
+
+func (c *redisCacheInner) expire(ctx context.Context, key string, expire time.Duration, opts ...innerOperationOption) error {
+	var cmd []interface{}
+	if expire > 0 {
+		cmd = []interface{}{"PEXPIRE", key, redisExpireMillis(expire)}
+	} else {
+		cmd = []interface{}{"PERSIST", key}
+	}
+
+	replies, err := c.loadPool().Pipeline(ctx, [][]interface{}{{"EXISTS", key}, cmd})
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if replyErr, ok := reply.(redisclient.Error); ok {
+			return replyErr
+		}
+	}
+
+	exists, err := redisclient.Int64(replies[0], nil)
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrCacheMiss
+	}
+
+	return nil
+}
+
+func (c *redisCacheInner) flush(ctx context.Context) error {
+	_, err := c.loadPool().Do(ctx, "FLUSHDB")
+	return err
+}
+
+func (c *redisCacheInner) ping(ctx context.Context) error {
+	_, err := c.loadPool().Do(ctx, "PING")
+	return err
+}
+
+// rawClient returns the underlying *redisclient.Pool
+func (c *redisCacheInner) rawClient() interface{} {
+	return c.loadPool()
+}
+
+// nolint:predeclared
+func (c *redisCacheInner) close() error {
+	return c.loadPool().Close()
+}
+
+func (c *redisCacheInner) loadPool() *redisclient.Pool {
+	return (*redisclient.Pool)(atomic.LoadPointer(&c.pool))
+}
+
+// updateConfig replaces the connection pool if any connection related config is changed.
+// The old pool is closed, its connections in use are closed once returned.
+func (c *redisCacheInner) updateConfig(newConfig RedisConfig) error {
+	poolConfig := newConfig.poolConfig()
+	if poolConfig == c.poolConfig {
+		return nil
+	}
+
+	oldPool := c.loadPool()
+	atomic.StorePointer(&c.pool, unsafe.Pointer(redisclient.NewPool(poolConfig)))
+	c.poolConfig = poolConfig
+
+	return oldPool.Close()
+}
+
+func genRedisSetArgs(key string, value interface{}, expire time.Duration) []interface{} {
+	args := []interface{}{"SET", key, value}
+	if expire > 0 {
+		args = append(args, "PX", redisExpireMillis(expire))
+	}
+	return args
+}
+
+// redisExpireMillis converts expire to milliseconds, sub-millisecond expiration is rounded up to avoid being treated as invalid
+func redisExpireMillis(expire time.Duration) int64 {
+	millis := expire.Milliseconds()
+	if millis <= 0 {
+		millis = 1
+	}
+	return millis
+}
@@ -0,0 +1,458 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedisServer is an in-process RESP stand-in which supports the commands used by RedisCache
+type fakeRedisServer struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	data    map[string][]byte
+	expires map[string]time.Time
+}
+
+func newFakeRedisServer(t *testing.T) *fakeRedisServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen err: %v", err)
+	}
+
+	s := &fakeRedisServer{
+		listener: listener,
+		data:     make(map[string][]byte),
+		expires:  make(map[string]time.Time),
+	}
+	go s.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+
+	return s
+}
+
+func (s *fakeRedisServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedisServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeRedisServer) handle(conn net.Conn) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	bw := bufio.NewWriter(conn)
+
+	for {
+		args, err := readFakeRedisCommand(br)
+		if err != nil {
+			return
+		}
+		s.exec(bw, args)
+		if br.Buffered() == 0 {
+			if err = bw.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func readFakeRedisCommand(br *bufio.Reader) ([]string, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, errors.New("inline command not supported")
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		if line, err = br.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		b := make([]byte, size+2)
+		if _, err = readFullFake(br, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:size])
+	}
+	return args, nil
+}
+
+func readFullFake(br *bufio.Reader, b []byte) (int, error) {
+	n := 0
+	for n < len(b) {
+		m, err := br.Read(b[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// lookup returns the value of an unexpired key, must be called with lock held
+func (s *fakeRedisServer) lookup(key string) ([]byte, bool) {
+	if exp, ok := s.expires[key]; ok && !time.Now().Before(exp) {
+		delete(s.data, key)
+		delete(s.expires, key)
+	}
+	val, ok := s.data[key]
+	return val, ok
+}
+
+// nolint:funlen,gocyclo
+func (s *fakeRedisServer) exec(bw *bufio.Writer, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		writeFakeSimple(bw, "PONG")
+	case "AUTH", "SELECT":
+		writeFakeSimple(bw, "OK")
+	case "GET":
+		val, ok := s.lookup(args[1])
+		writeFakeBulk(bw, val, ok)
+	case "MGET":
+		bw.WriteString("*" + strconv.Itoa(len(args)-1) + "\r\n")
+		for _, key := range args[1:] {
+			val, ok := s.lookup(key)
+			writeFakeBulk(bw, val, ok)
+		}
+	case "SET":
+		key := args[1]
+		_, exists := s.lookup(key)
+		var expire time.Duration
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				if exists {
+					writeFakeBulk(bw, nil, false)
+					return
+				}
+			case "XX":
+				if !exists {
+					writeFakeBulk(bw, nil, false)
+					return
+				}
+			case "PX":
+				ms, _ := strconv.Atoi(args[i+1])
+				expire = time.Duration(ms) * time.Millisecond
+				i++
+			}
+		}
+		s.data[key] = []byte(args[2])
+		delete(s.expires, key)
+		if expire > 0 {
+			s.expires[key] = time.Now().Add(expire)
+		}
+		writeFakeSimple(bw, "OK")
+	case "DEL", "EXISTS":
+		count := 0
+		for _, key := range args[1:] {
+			if _, ok := s.lookup(key); ok {
+				count++
+				if strings.ToUpper(args[0]) == "DEL" {
+					delete(s.data, key)
+					delete(s.expires, key)
+				}
+			}
+		}
+		writeFakeInt(bw, int64(count))
+	case "EVAL":
+		// only redisCompareAndSwapScript is supported
+		key := args[3]
+		if val, ok := s.lookup(key); !ok || string(val) != args[4] {
+			writeFakeInt(bw, 0)
+			return
+		}
+		s.data[key] = []byte(args[5])
+		delete(s.expires, key)
+		if millis, _ := strconv.ParseInt(args[6], 10, 64); millis > 0 {
+			s.expires[key] = time.Now().Add(time.Duration(millis) * time.Millisecond)
+		}
+		writeFakeInt(bw, 1)
+	case "INCRBY", "DECRBY":
+		val, _ := s.lookup(args[1])
+		cur := int64(0)
+		if val != nil {
+			var err error
+			if cur, err = strconv.ParseInt(string(val), 10, 64); err != nil {
+				bw.WriteString("-ERR value is not an integer or out of range\r\n")
+				return
+			}
+		}
+		delta, _ := strconv.ParseInt(args[2], 10, 64)
+		if strings.ToUpper(args[0]) == "DECRBY" {
+			delta = -delta
+		}
+		cur += delta
+		s.data[args[1]] = []byte(strconv.FormatInt(cur, 10))
+		writeFakeInt(bw, cur)
+	case "PEXPIRE":
+		if _, ok := s.lookup(args[1]); !ok {
+			writeFakeInt(bw, 0)
+			return
+		}
+		ms, _ := strconv.Atoi(args[2])
+		s.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		writeFakeInt(bw, 1)
+	case "PERSIST":
+		_, hasExpire := s.expires[args[1]]
+		delete(s.expires, args[1])
+		if hasExpire {
+			writeFakeInt(bw, 1)
+		} else {
+			writeFakeInt(bw, 0)
+		}
+	case "FLUSHDB":
+		s.data = make(map[string][]byte)
+		s.expires = make(map[string]time.Time)
+		writeFakeSimple(bw, "OK")
+	default:
+		bw.WriteString("-ERR unknown command '" + args[0] + "'\r\n")
+	}
+}
+
+func writeFakeSimple(bw *bufio.Writer, s string) {
+	bw.WriteString("+" + s + "\r\n")
+}
+
+func writeFakeInt(bw *bufio.Writer, n int64) {
+	bw.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func writeFakeBulk(bw *bufio.Writer, val []byte, ok bool) {
+	if !ok {
+		bw.WriteString("$-1\r\n")
+		return
+	}
+	bw.WriteString("$" + strconv.Itoa(len(val)) + "\r\n")
+	bw.Write(val)
+	bw.WriteString("\r\n")
+}
+
+func newTestRedisCache(t *testing.T) *RedisCache {
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{Address: server.addr()})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+	return c
+}
+
+func TestRedisCacheGetSet(t *testing.T) {
+	ctx := context.Background()
+	c := newTestRedisCache(t)
+
+	msg := "hello"
+	if err := c.Set(ctx, "k1", &cacheResp{Id: 1, Msg: "one", PointerMsg: &msg}, time.Minute); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+
+	var resp cacheResp
+	if err := c.Get(ctx, "k1", &resp); err != nil {
+		t.Fatalf("get err: %v", err)
+	}
+	if resp.Id != 1 || resp.Msg != "one" || resp.PointerMsg == nil || *resp.PointerMsg != msg {
+		t.Fatalf("unexpected resp: %+v", resp)
+	}
+
+	if err := c.Get(ctx, "not_exist", &resp); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss, got: %v", err)
+	}
+
+	if err := c.SetMany(ctx, map[string]interface{}{"k2": 2, "k3": 3}, time.Minute); err != nil {
+		t.Fatalf("set many err: %v", err)
+	}
+	var v2, v3, v4 int
+	receiverMap := map[string]interface{}{"k2": &v2, "k3": &v3, "k4": &v4}
+	if err := c.GetMany(ctx, receiverMap); err != nil {
+		t.Fatalf("get many err: %v", err)
+	}
+	if v2 != 2 || v3 != 3 || receiverMap["k4"] != nil {
+		t.Fatalf("unexpected get many result: %v, %v, %v", v2, v3, receiverMap["k4"])
+	}
+
+	if err := c.Delete(ctx, "k2"); err != nil {
+		t.Fatalf("delete err: %v", err)
+	}
+	if err := c.Delete(ctx, "k2"); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss for deleted key, got: %v", err)
+	}
+}
+
+func TestRedisCacheConditionalOperations(t *testing.T) {
+	ctx := context.Background()
+	c := newTestRedisCache(t)
+
+	if err := c.Replace(ctx, "k", "v1", time.Minute); err != ErrNotStored {
+		t.Fatalf("expect not stored on replacing non-exist key, got: %v", err)
+	}
+	if err := c.Add(ctx, "k", "v1", time.Minute); err != nil {
+		t.Fatalf("add err: %v", err)
+	}
+	if err := c.Add(ctx, "k", "v2", time.Minute); err != ErrNotStored {
+		t.Fatalf("expect not stored on adding exist key, got: %v", err)
+	}
+	if err := c.Replace(ctx, "k", "v3", time.Minute); err != nil {
+		t.Fatalf("replace err: %v", err)
+	}
+	var val string
+	if err := c.Get(ctx, "k", &val); err != nil || val != "v3" {
+		t.Fatalf("unexpected value %v, err: %v", val, err)
+	}
+
+	if _, err := c.Increment(ctx, "counter", 1, WithInitNonExistKey(false)); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss on incrementing non-exist key, got: %v", err)
+	}
+	if n, err := c.Increment(ctx, "counter", 5); err != nil || n != 5 {
+		t.Fatalf("unexpected increment result %v, err: %v", n, err)
+	}
+	if n, err := c.Decrement(ctx, "counter", 2); err != nil || n != 3 {
+		t.Fatalf("unexpected decrement result %v, err: %v", n, err)
+	}
+
+	if err := c.Expire(ctx, "k", 50*time.Millisecond); err != nil {
+		t.Fatalf("expire err: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := c.Get(ctx, "k", &val); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss after expiration, got: %v", err)
+	}
+}
+
+func TestRedisCacheLoad(t *testing.T) {
+	ctx := context.Background()
+	c := newTestRedisCache(t)
+
+	calls := 0
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		calls++
+		res := make([]interface{}, len(keys))
+		for i, key := range keys {
+			res[i] = "loaded_" + key
+		}
+		return res, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		var val string
+		if err := c.Load(ctx, loader, "k", &val, time.Minute); err != nil {
+			t.Fatalf("load err: %v", err)
+		}
+		if val != "loaded_k" {
+			t.Fatalf("unexpected loaded value: %v", val)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expect loader to be called once, got %v", calls)
+	}
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("ping err: %v", err)
+	}
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("flush err: %v", err)
+	}
+	var val string
+	if err := c.Get(ctx, "k", &val); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss after flush, got: %v", err)
+	}
+}
+
+func TestRedisCacheKeyConfig(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	config := RedisConfig{Address: server.addr(), KeyConfig: KeyConfig{Namespace: "ns", Version: 1, MaxKeyLength: 100}}
+	c, err := NewRedisCache("test_redis", config)
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	longKey := strings.Repeat("k", 200)
+	if err := c.SetMany(ctx, map[string]interface{}{"k": 1, longKey: 2}, time.Minute); err != nil {
+		t.Fatalf("set many err: %v", err)
+	}
+	if _, ok := server.lookup("ns:v1:k"); !ok {
+		t.Fatalf("expect key prefixed by namespace and version")
+	}
+	var v1, v2 int
+	receiverMap := map[string]interface{}{"k": &v1, longKey: &v2}
+	if err := c.GetMany(ctx, receiverMap); err != nil || v1 != 1 || v2 != 2 {
+		t.Fatalf("expect values got by original keys, got: %v, %v, err: %v", v1, v2, err)
+	}
+	server.mu.Lock()
+	for key := range server.data {
+		if len(key) > config.KeyConfig.MaxKeyLength {
+			t.Errorf("expect long key hashed, got: %v", key)
+		}
+	}
+	server.mu.Unlock()
+	if err := c.Expire(ctx, "k", time.Hour); err != nil {
+		t.Fatalf("expire err: %v", err)
+	}
+
+	// bumping the version invalidates all the cached data logically
+	config.KeyConfig.Version = 2
+	if err := c.UpdateConfig(config); err != nil {
+		t.Fatalf("update config err: %v", err)
+	}
+	if err := c.Get(ctx, "k", &v1); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss after version bumped, got: %v", err)
+	}
+
+	if err := (KeyConfig{Namespace: "ns", MaxKeyLength: 10}).Validate(); err == nil {
+		t.Fatalf("expect error on max key length shorter than hashed keys")
+	}
+}
+
+func TestRedisCacheDeleteMany(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{Address: server.addr(), KeyConfig: KeyConfig{Namespace: "ns"}})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	if err := c.SetMany(ctx, map[string]interface{}{"k1": 1, "k2": 2, "k3": 3}, time.Minute); err != nil {
+		t.Fatalf("set many err: %v", err)
+	}
+	if err := c.DeleteMany(ctx, []string{"k1"}); err != nil {
+		t.Fatalf("delete many err: %v", err)
+	}
+	if _, ok := server.lookup("ns:k1"); ok {
+		t.Fatalf("expect k1 deleted")
+	}
+
+	deletedKeys := make(map[string]bool)
+	if err := c.DeleteMany(ctx, []string{"k1", "k2"}, WithDeletedKeys(deletedKeys)); err != nil {
+		t.Fatalf("delete many err: %v", err)
+	}
+	if len(deletedKeys) != 2 || deletedKeys["k1"] || !deletedKeys["k2"] {
+		t.Fatalf("expect only k2 existed, got: %v", deletedKeys)
+	}
+	if _, ok := server.lookup("ns:k3"); !ok {
+		t.Fatalf("expect k3 kept")
+	}
+}
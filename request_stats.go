@@ -2,11 +2,31 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
 type observationConfig struct {
 	slowOperationLogConfig slowOperationLogConfig
+	statsCollectors        []StatsCollector
+	logger                 Logger
+	tracer                 Tracer
+	tracePayloadConfig     tracePayloadConfig
+}
+
+func newObservationConfig(config ObservationConfig) observationConfig {
+	statsCollectors := config.StatsCollectors
+	if config.EnablePrometheusMetrics {
+		statsCollectors = append(append([]StatsCollector{}, statsCollectors...), defaultPrometheusCollector)
+	}
+
+	return observationConfig{
+		slowOperationLogConfig: newSlowOperationLogConfig(config.SlowOperationLogConfig),
+		statsCollectors:        statsCollectors,
+		logger:                 config.Logger,
+		tracer:                 config.Tracer,
+		tracePayloadConfig:     newTracePayloadConfig(config.TracePayloadConfig),
+	}
 }
 
 type slowOperationLogConfig struct {
@@ -14,9 +34,14 @@ type slowOperationLogConfig struct {
 	elapseThresholdForSlowLog time.Duration // the minimum time threshold for logging slow operations, default as 100ms.
 }
 
+type tracePayloadConfig struct {
+	enable          bool
+	maxPayloadBytes int // the max bytes of each captured payload, default as 512.
+}
+
 // RequestStats defines stats to be used to record metrics
 type RequestStats struct {
-	CacheType      string // "redis", or "memcached"
+	CacheType      string // "redis", "memcached", "inmemory" or "migration"
 	CacheName      string // the name defined in config
 	CacheOperation string // e.g. "Get", "GetMany", ...
 	hostName       string // host name can be the format of ip:port, used by tracing in Redis only
@@ -27,12 +52,16 @@ type RequestStats struct {
 	ResponseSize    int         // size of response (in bytes) for this cache operation
 	TotalKeyCount   int         // total key count for this cache operation
 	SuccessKeyCount int         // success key count for this cache operation, can be used to calculate cache hit ratio
+	MissKeyCount    int         // missing key count for read operations (i.e. "Get" and "GetMany"), only set when there is no other error
+
+	MismatchKeyCount int // mismatched key count between the primary and the secondary cache, reported by MigrationCache in ShadowCompare mode
 
-	startTime time.Time
-	endTime   time.Time
+	StartTime time.Time
+	EndTime   time.Time
 	Elapsed   time.Duration // the duration of this single cache internal operation
 
-	Err error // error occurred during cache operation (used by tracing only)
+	Err     error     // error occurred during cache operation
+	ErrType ErrorType // classification of Err
 
 	skipOperationLogs bool // to determine if needed to skip logs on the operation
 }
@@ -42,24 +71,80 @@ func (rs *RequestStats) needToReportOperationLogs() bool {
 }
 
 // requestStatsDecorator injects metrics into f. Target function should populate stats through closure.
-func requestStatsDecorator(ctx context.Context, stats *RequestStats, f func() error) {
-	startTime := time.Now()
-
-	stats.Err = f()
+// The operation is traced by the tracer in observation, f is called with the ctx carrying the span of the operation,
+// so that the spans started within f are children of it. The finished stats are reported to the stats collectors in observation.
+func requestStatsDecorator(ctx context.Context, observation observationConfig, stats *RequestStats, f func(ctx context.Context) error) {
+	spanCtx, span := observation.startSpan(ctx, spanNamePrefix+stats.CacheOperation)
+	stats.StartTime = time.Now()
+
+	stats.Err = f(spanCtx)
+
+	stats.EndTime = time.Now()
+	stats.Elapsed = stats.EndTime.Sub(stats.StartTime)
+	stats.ErrType = classifyError(stats.Err)
+	if isReadOperation(stats.CacheOperation) && (stats.ErrType == ErrorTypeNone || stats.ErrType == ErrorTypeCacheMiss) {
+		stats.MissKeyCount = stats.TotalKeyCount - stats.SuccessKeyCount
+	}
+
+	if observation.tracer != nil {
+		span.SetAttributes(observation.genSpanAttributes(stats)...)
+	}
+	span.Finish(stats.Err)
+
+	if stats.needToReportOperationLogs() {
+		observation.logSlowOperation(ctx, stats)
+	}
+
+	for _, collector := range observation.statsCollectors {
+		collector.CollectStats(ctx, *stats)
+	}
+}
 
-	stats.Elapsed = time.Since(startTime)
+// ErrorType is the classification of the error occurred during cache operation
+type ErrorType string
+
+const (
+	// ErrorTypeNone means no error occurred
+	ErrorTypeNone ErrorType = "none"
+	// ErrorTypeCacheMiss means the error is ErrCacheMiss
+	ErrorTypeCacheMiss ErrorType = "cache_miss"
+	// ErrorTypeNotStored means the error is ErrNotStored
+	ErrorTypeNotStored ErrorType = "not_stored"
+	// ErrorTypeClosed means the error is ErrCacheClosed
+	ErrorTypeClosed ErrorType = "cache_closed"
+	// ErrorTypeTimeout means the operation timed out
+	ErrorTypeTimeout ErrorType = "timeout"
+	// ErrorTypeCanceled means the context of the operation is canceled
+	ErrorTypeCanceled ErrorType = "canceled"
+	// ErrorTypeOther means any other error e.g. network error or codec error
+	ErrorTypeOther ErrorType = "other"
+)
 
-	//TODO 可以在这里后续添加监控等信息
+// classifyError classifies err into ErrorType
+func classifyError(err error) ErrorType {
+	switch {
+	case err == nil:
+		return ErrorTypeNone
+	case err == ErrCacheMiss:
+		return ErrorTypeCacheMiss
+	case err == ErrNotStored:
+		return ErrorTypeNotStored
+	case err == ErrCacheClosed:
+		return ErrorTypeClosed
+	case errors.Is(err, context.Canceled):
+		return ErrorTypeCanceled
+	case errors.Is(err, context.DeadlineExceeded) || isTimeoutError(err):
+		return ErrorTypeTimeout
+	default:
+		return ErrorTypeOther
+	}
+}
 
+func isReadOperation(operation string) bool {
+	return operation == cmdGet || operation == cmdGetMany
 }
 
 // StatsCollector defines interface of stats collector
 type StatsCollector interface {
 	CollectStats(ctx context.Context, stats RequestStats)
 }
-
-var defaultPrometheusCollector = &defaultCollector{}
-
-const unknown = "unknown"
-
-type defaultCollector struct{}
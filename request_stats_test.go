@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingCollector struct {
+	mu    sync.Mutex
+	stats []RequestStats
+}
+
+func (c *recordingCollector) CollectStats(ctx context.Context, stats RequestStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats = append(c.stats, stats)
+}
+
+func (c *recordingCollector) last() RequestStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats[len(c.stats)-1]
+}
+
+func TestRequestStatsCollected(t *testing.T) {
+	ctx := context.Background()
+	collector := &recordingCollector{}
+	c, err := NewInMemoryCache("test_stats", InMemoryCacheConfig{
+		CacheType:         Ristretto,
+		ObservationConfig: ObservationConfig{StatsCollectors: []StatsCollector{collector}},
+	})
+	if err != nil {
+		t.Fatalf("new in-memory cache err: %v", err)
+	}
+	defer c.Close(ctx)
+
+	if err = c.Set(ctx, "k1", "v1", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	stats := collector.last()
+	if stats.CacheName != "test_stats" || stats.CacheType != "inmemory" || stats.CacheOperation != cmdSet ||
+		stats.ErrType != ErrorTypeNone || stats.EndTime.Before(stats.StartTime) || stats.Elapsed != stats.EndTime.Sub(stats.StartTime) {
+		t.Fatalf("unexpected set stats: %+v", stats)
+	}
+
+	var val string
+	if err = c.Get(ctx, "non_exist", &val); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss, got: %v", err)
+	}
+	if stats = collector.last(); stats.ErrType != ErrorTypeCacheMiss || stats.MissKeyCount != 1 {
+		t.Fatalf("unexpected get stats: %+v", stats)
+	}
+
+	var v1, v2 string
+	if err = c.GetMany(ctx, map[string]interface{}{"k1": &v1, "k2": &v2}); err != nil {
+		t.Fatalf("get many err: %v", err)
+	}
+	if stats = collector.last(); stats.TotalKeyCount != 2 || stats.SuccessKeyCount != 1 || stats.MissKeyCount != 1 {
+		t.Fatalf("unexpected get many stats: %+v", stats)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	for err, expected := range map[error]ErrorType{
+		nil:                       ErrorTypeNone,
+		ErrCacheMiss:              ErrorTypeCacheMiss,
+		ErrNotStored:              ErrorTypeNotStored,
+		ErrCacheClosed:            ErrorTypeClosed,
+		context.Canceled:          ErrorTypeCanceled,
+		context.DeadlineExceeded:  ErrorTypeTimeout,
+		errors.New("i/o timeout"): ErrorTypeTimeout,
+		errors.New("broken pipe"): ErrorTypeOther,
+	} {
+		if got := classifyError(err); got != expected {
+			t.Fatalf("expect %v classified as %v, got %v", err, expected, got)
+		}
+	}
+}
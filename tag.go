@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// tagVersionKeyPrefix is the prefix of the keys storing the versions of tags in shared caches
+const tagVersionKeyPrefix = "_tag_:"
+
+// invalidateTags invalidates the data tagged by any of tags, refer to WithTags.
+// For in-memory cache, the keys indexed by tags are deleted, otherwise the versions of tags are incremented,
+// and the versions lost are re-initialized rather than incremented from zero.
+func (c *cacheWrapper) invalidateTags(ctx context.Context, tags []string) (err error) {
+	inner := c.loadCacheWrapperInner()
+
+	if inner.isCacheClosed() {
+		return ErrCacheClosed
+	}
+	if inner.isWriteSkipped(ctx) {
+		return nil
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	stats := &RequestStats{
+		CacheName:      inner.name,
+		CacheType:      inner.cacheType.String(),
+		CacheOperation: cmdInvalidateTags,
+		TotalKeyCount:  len(tags),
+		hostName:       inner.cacheHostName,
+	}
+
+	requestStatsDecorator(ctx, inner.observationConfig, stats, func(ctx context.Context) error {
+		stats.req = tags
+		if inMemCache, ok := inner.cache.(*inMemoryCacheInner); ok {
+			err = inMemCache.invalidateTags(ctx, tags)
+			return err
+		}
+
+		for _, tagKey := range inner.getTagVersionKeys(ctx, tags) {
+			stats.RequestSize += len(tagKey)
+			if err = inner.incrementTagVersion(ctx, tagKey); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return err
+}
+
+// resolveTagVersions sets the current versions of option.tags to option.tagVersions for shared caches
+func (c *cacheWrapperInner) resolveTagVersions(ctx context.Context, option *cacheOperationOptions) error {
+	if len(option.tags) == 0 || c.cacheType == inMemory {
+		return nil
+	}
+
+	tagVersionMap, err := c.getTagVersions(ctx, option.tags)
+	if err != nil {
+		return err
+	}
+	option.tagVersions = genTagVersions(option.tags, tagVersionMap)
+	return nil
+}
+
+// getTagVersions returns the current versions of tags, the versions not existing are initialized
+func (c *cacheWrapperInner) getTagVersions(ctx context.Context, tags []string) (map[string]int64, error) {
+	tagKeys := c.getTagVersionKeys(ctx, tags)
+	values, err := c.cache.getMany(ctx, tagKeys...)
+	if err != nil {
+		return nil, err
+	}
+
+	tagVersionMap := make(map[string]int64, len(tags))
+	for idx, value := range values {
+		version, ok := parseTagVersion(value)
+		if !ok {
+			version, err = c.initTagVersion(ctx, tagKeys[idx])
+			if err != nil {
+				return nil, err
+			}
+		}
+		tagVersionMap[tags[idx]] = version
+	}
+	return tagVersionMap, nil
+}
+
+// incrementTagVersion increments the version of a tag, the version not existing is initialized by initTagVersion,
+// since incrementing from zero may reuse a version lost (e.g. evicted).
+func (c *cacheWrapperInner) incrementTagVersion(ctx context.Context, tagKey string) error {
+	_, err := c.cache.increment(ctx, tagKey, 1, withInitNonExistKey(false))
+	if err == ErrCacheMiss {
+		_, err = c.initTagVersion(ctx, tagKey)
+	}
+	return err
+}
+
+// initTagVersion initializes the version of a tag with the current time,
+// so that a version lost (e.g. evicted) is never reused by the re-initialized version.
+func (c *cacheWrapperInner) initTagVersion(ctx context.Context, tagKey string) (int64, error) {
+	version := time.Now().UnixNano()
+	err := c.cache.add(ctx, tagKey, []byte(strconv.FormatInt(version, 10)), NoExpiration)
+	if err != ErrNotStored {
+		return version, err
+	}
+
+	// initialized by others concurrently
+	value, err := c.cache.get(ctx, tagKey)
+	if err != nil {
+		return 0, err
+	}
+	version, ok := parseTagVersion(value)
+	if !ok {
+		return 0, errInvalidTagVersion
+	}
+	return version, nil
+}
+
+// getStaleByTags returns whether the data of each header is stale, i.e. any of its tags is invalidated after the data is cached.
+// The data is treated as stale as well if the versions of its tags can not be read.
+func (c *cacheWrapperInner) getStaleByTags(ctx context.Context, headers []metaHeader) []bool {
+	stale := make([]bool, len(headers))
+
+	var tags []string
+	tagIdxMap := make(map[string]int)
+	for _, header := range headers {
+		if !isTagVersioned(header) {
+			continue
+		}
+		for _, tag := range header.Tags {
+			if _, ok := tagIdxMap[tag]; !ok {
+				tagIdxMap[tag] = len(tags)
+				tags = append(tags, tag)
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return stale
+	}
+
+	values, err := c.cache.getMany(ctx, c.getTagVersionKeys(ctx, tags)...)
+	for idx, header := range headers {
+		if !isTagVersioned(header) {
+			continue
+		}
+		if err != nil {
+			stale[idx] = true
+			continue
+		}
+		for i, tag := range header.Tags {
+			version, ok := parseTagVersion(values[tagIdxMap[tag]])
+			if !ok || version != header.TagVersions[i] {
+				stale[idx] = true
+				break
+			}
+		}
+	}
+	return stale
+}
+
+// indexTags indexes fixedKeys by tags for in-memory cache
+func (c *cacheWrapperInner) indexTags(tags []string, fixedKeys ...string) {
+	if len(tags) == 0 {
+		return
+	}
+	if inMemCache, ok := c.cache.(*inMemoryCacheInner); ok {
+		inMemCache.indexTags(tags, fixedKeys...)
+	}
+}
+
+// lockTags locks tags for in-memory cache while writing and indexing the data tagged by them,
+// so that the data are not missed by a concurrent invalidation. It returns the function to unlock.
+func (c *cacheWrapperInner) lockTags(tags []string) (unlock func()) {
+	if len(tags) > 0 {
+		if inMemCache, ok := c.cache.(*inMemoryCacheInner); ok {
+			return inMemCache.lockTags(tags)
+		}
+	}
+	return func() {}
+}
+
+func (c *cacheWrapperInner) getTagVersionKeys(ctx context.Context, tags []string) []string {
+	tagKeys := make([]string, len(tags))
+	for idx, tag := range tags {
+		tagKeys[idx] = tagVersionKeyPrefix + tag
+	}
+	fixedKeys, _ := c.getFixedKeys(ctx, tagKeys)
+	return fixedKeys
+}
+
+// isTagVersioned returns if the data of header is tagged with the versions of tags
+func isTagVersioned(header metaHeader) bool {
+	return len(header.Tags) > 0 && len(header.Tags) == len(header.TagVersions)
+}
+
+func genTagVersions(tags []string, tagVersionMap map[string]int64) []int64 {
+	versions := make([]int64, len(tags))
+	for idx, tag := range tags {
+		versions[idx] = tagVersionMap[tag]
+	}
+	return versions
+}
+
+func parseTagVersion(value interface{}) (int64, bool) {
+	var s string
+	switch v := value.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(s, 10, 64)
+	return version, err == nil
+}
@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRedisCacheTags(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{Address: server.addr()})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	if err := c.Set(ctx, "page:1", "p1", time.Minute, WithTags("user:42")); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	if err := c.SetMany(ctx, map[string]interface{}{"page:2": "p2", "page:3": "p3"}, time.Minute, WithTags("user:42", "site")); err != nil {
+		t.Fatalf("set many err: %v", err)
+	}
+	if err := c.Set(ctx, "other", "o", time.Minute, WithTags("user:7")); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	var val string
+	if err := c.Get(ctx, "page:1", &val); err != nil || val != "p1" {
+		t.Fatalf("expect p1 before invalidation, got: %v, err: %v", val, err)
+	}
+
+	if err := c.InvalidateTags(ctx, "user:42"); err != nil {
+		t.Fatalf("invalidate tags err: %v", err)
+	}
+	if err := c.Get(ctx, "page:1", &val); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss after invalidation, got: %v", err)
+	}
+	receiverMap := map[string]interface{}{"page:2": new(string), "other": new(string)}
+	if err := c.GetMany(ctx, receiverMap, WithNonExistKeyStrategy(RemoveKey)); err != nil {
+		t.Fatalf("get many err: %v", err)
+	}
+	if _, ok := receiverMap["page:2"]; ok || *receiverMap["other"].(*string) != "o" {
+		t.Fatalf("expect only the data of the invalidated tag missing, got: %v", receiverMap)
+	}
+
+	// the data loaded after invalidation is tagged with the new version
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return []interface{}{"reloaded"}, nil
+	}
+	if err := c.Load(ctx, loader, "page:3", &val, time.Minute, WithTags("user:42", "site")); err != nil || val != "reloaded" {
+		t.Fatalf("expect reloaded, got: %v, err: %v", val, err)
+	}
+	if err := c.Get(ctx, "page:3", &val); err != nil || val != "reloaded" {
+		t.Fatalf("expect reloaded data cached, got: %v, err: %v", val, err)
+	}
+
+	// the data is treated as stale once the version of its tag is lost
+	server.mu.Lock()
+	delete(server.data, tagVersionKeyPrefix+"site")
+	server.mu.Unlock()
+	if err := c.Get(ctx, "page:3", &val); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss after tag version lost, got: %v", err)
+	}
+}
+
+func TestRedisCacheInvalidateLostTag(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedisServer(t)
+	c, err := NewRedisCache("test_redis", RedisConfig{Address: server.addr()})
+	if err != nil {
+		t.Fatalf("new redis cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	// the data is tagged with a low version, which would be reused by incrementing a lost version from zero
+	tagKey := tagVersionKeyPrefix + "user:42"
+	server.mu.Lock()
+	server.data[tagKey] = []byte("1")
+	server.mu.Unlock()
+	if err := c.Set(ctx, "page:1", "p1", time.Minute, WithTags("user:42")); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+
+	server.mu.Lock()
+	delete(server.data, tagKey)
+	server.mu.Unlock()
+	if err := c.InvalidateTags(ctx, "user:42"); err != nil {
+		t.Fatalf("invalidate tags err: %v", err)
+	}
+	var val string
+	if err := c.Get(ctx, "page:1", &val); err != ErrCacheMiss {
+		t.Fatalf("expect the older data kept missing, got: %v", err)
+	}
+}
+
+func TestInMemoryCacheTags(t *testing.T) {
+	ctx := context.Background()
+	c := newTestInMemoryCache(t)
+
+	if err := c.SetMany(ctx, map[string]interface{}{"page:1": "p1", "page:2": "p2"}, time.Minute, WithTags("user:42"), WithWaitRistretto()); err != nil {
+		t.Fatalf("set many err: %v", err)
+	}
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return []interface{}{"p3"}, nil
+	}
+	var val string
+	if err := c.Load(ctx, loader, "page:3", &val, time.Minute, WithTags("user:42"), WithWaitRistretto()); err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+	if err := c.Set(ctx, "other", "o", time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+
+	if err := c.InvalidateTags(ctx, "user:42"); err != nil {
+		t.Fatalf("invalidate tags err: %v", err)
+	}
+	for _, key := range []string{"page:1", "page:2", "page:3"} {
+		if err := c.Get(ctx, key, &val); err != ErrCacheMiss {
+			t.Fatalf("expect %v deleted by invalidation, got: %v", key, err)
+		}
+	}
+	if err := c.Get(ctx, "other", &val); err != nil || val != "o" {
+		t.Fatalf("expect untagged data kept, got: %v, err: %v", val, err)
+	}
+}
+
+func TestInMemoryCacheInvalidateTagsWaitsForTaggedWrites(t *testing.T) {
+	ctx := context.Background()
+	c := newTestInMemoryCache(t)
+	inner := c.inner.loadCacheWrapperInner()
+
+	// a tagged write in progress: the data are written but not indexed yet
+	unlock := inner.lockTags([]string{"user:42"})
+	if err := inner.cache.set(ctx, inner.getFixedKey(ctx, "page:1"), "p1", time.Minute, withWaitRistretto(true)); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- c.InvalidateTags(ctx, "user:42") }()
+	select {
+	case err := <-done:
+		t.Fatalf("expect invalidation waiting for the tagged write, got: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	inner.indexTags([]string{"user:42"}, inner.getFixedKey(ctx, "page:1"))
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("invalidate tags err: %v", err)
+	}
+	var val string
+	if err := c.Get(ctx, "page:1", &val); err != ErrCacheMiss {
+		t.Fatalf("expect data written before invalidation deleted, got: %v", err)
+	}
+}
+
+func TestMultiLayerCacheTags(t *testing.T) {
+	ctx := context.Background()
+	c, local, remote := newTestMultiLayerCache(t)
+
+	if err := remote.Set(ctx, "page:1", "p1", time.Minute, WithTags("user:42")); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	// the tags are carried when the data is backfilled to the outer layer
+	var val string
+	if err := c.Get(ctx, "page:1", &val, WithWaitRistretto()); err != nil || val != "p1" {
+		t.Fatalf("expect p1, got: %v, err: %v", val, err)
+	}
+	if err := c.InvalidateTags(ctx, "user:42"); err != nil {
+		t.Fatalf("invalidate tags err: %v", err)
+	}
+	if err := local.Get(ctx, "page:1", &val); err != ErrCacheMiss {
+		t.Fatalf("expect backfilled data invalidated, got: %v", err)
+	}
+	if err := c.Get(ctx, "page:1", &val); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss after invalidation, got: %v", err)
+	}
+}
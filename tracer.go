@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// spanNamePrefix is the prefix of the names of spans started by cache
+	spanNamePrefix = "cache."
+
+	// spanNameDataLoader is the name of the span of DataLoader calls
+	spanNameDataLoader = spanNamePrefix + "DataLoader"
+	// spanNameAcquireDlock is the name of the span of dlock acquisition
+	spanNameAcquireDlock = spanNamePrefix + "AcquireDlock"
+	// spanNameBackgroundRefresh is the name of the span of refreshing soft expired data in background
+	spanNameBackgroundRefresh = spanNamePrefix + "BackgroundRefresh"
+)
+
+// Tracer starts spans for cache operations
+type Tracer interface {
+	// StartSpan starts a span of name, the returned context carries the span, so that spans started with it are its children
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by Tracer
+type Span interface {
+	// SetAttributes sets attributes to the span
+	SetAttributes(attributes ...SpanAttribute)
+	// Finish ends the span, err is the error of the traced operation, nil if succeeded
+	Finish(err error)
+}
+
+// SpanAttribute is a key-value attribute of Span
+type SpanAttribute struct {
+	Key   string
+	Value interface{}
+}
+
+// TracerAdapter adapts tracing APIs in user code to Tracer, e.g. with OpenTelemetry:
+//
+//	cache.TracerAdapter{
+//		Start: func(ctx context.Context, name string) (context.Context, interface{}) {
+//			return otelTracer.Start(ctx, name)
+//		},
+//		SetAttribute: func(span interface{}, key string, value interface{}) {
+//			span.(trace.Span).SetAttributes(attribute.String(key, fmt.Sprint(value)))
+//		},
+//		Finish: func(span interface{}, err error) {
+//			if err != nil {
+//				span.(trace.Span).RecordError(err)
+//			}
+//			span.(trace.Span).End()
+//		},
+//	}
+type TracerAdapter struct {
+	// Start starts a span, which is passed to SetAttribute and Finish
+	Start func(ctx context.Context, name string) (context.Context, interface{})
+	// SetAttribute sets an attribute to span, optional
+	SetAttribute func(span interface{}, key string, value interface{})
+	// Finish ends span, optional
+	Finish func(span interface{}, err error)
+}
+
+// StartSpan (refer to StartSpan of Tracer interface)
+func (a TracerAdapter) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	if a.Start == nil {
+		return ctx, noopSpan{}
+	}
+	spanCtx, span := a.Start(ctx, name)
+	return spanCtx, adaptedSpan{adapter: a, span: span}
+}
+
+type adaptedSpan struct {
+	adapter TracerAdapter
+	span    interface{}
+}
+
+func (s adaptedSpan) SetAttributes(attributes ...SpanAttribute) {
+	if s.adapter.SetAttribute == nil {
+		return
+	}
+	for _, attribute := range attributes {
+		s.adapter.SetAttribute(s.span, attribute.Key, attribute.Value)
+	}
+}
+
+func (s adaptedSpan) Finish(err error) {
+	if s.adapter.Finish != nil {
+		s.adapter.Finish(s.span, err)
+	}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...SpanAttribute) {}
+
+func (noopSpan) Finish(error) {}
+
+// startSpan starts a span of name, it returns a no-op span if there is no tracer
+func (c observationConfig) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return c.tracer.StartSpan(ctx, name)
+}
+
+// genSpanAttributes generates the attributes of the span of the operation from stats
+func (c observationConfig) genSpanAttributes(stats *RequestStats) []SpanAttribute {
+	attributes := []SpanAttribute{
+		{Key: "cache.name", Value: stats.CacheName},
+		{Key: "cache.type", Value: stats.CacheType},
+		{Key: "cache.operation", Value: stats.CacheOperation},
+		{Key: "cache.request_size", Value: stats.RequestSize},
+		{Key: "cache.response_size", Value: stats.ResponseSize},
+		{Key: "cache.total_key_count", Value: stats.TotalKeyCount},
+		{Key: "cache.success_key_count", Value: stats.SuccessKeyCount},
+		{Key: "cache.error_type", Value: string(stats.ErrType)},
+	}
+	if stats.hostName != "" {
+		attributes = append(attributes, SpanAttribute{Key: "cache.host", Value: stats.hostName})
+	}
+	if c.tracePayloadConfig.enable {
+		attributes = append(attributes,
+			SpanAttribute{Key: "cache.request", Value: formatPayload(stats.req, c.tracePayloadConfig.maxPayloadBytes)},
+			SpanAttribute{Key: "cache.response", Value: formatPayload(stats.resp, c.tracePayloadConfig.maxPayloadBytes)},
+		)
+	}
+	return attributes
+}
+
+// formatPayload formats payload as a string with at most maxBytes bytes
+func formatPayload(payload interface{}, maxBytes int) string {
+	if payload == nil {
+		return ""
+	}
+
+	var s string
+	switch p := payload.(type) {
+	case []byte:
+		s = string(p)
+	default:
+		s = fmt.Sprintf("%+v", p)
+	}
+	if len(s) > maxBytes {
+		return s[:maxBytes] + "...(truncated)"
+	}
+	return s
+}
+
+func newTracePayloadConfig(config TracePayloadConfig) tracePayloadConfig {
+	maxPayloadBytes := config.MaxPayloadBytes
+	if maxPayloadBytes == 0 {
+		maxPayloadBytes = defaultMaxPayloadBytes
+	}
+
+	return tracePayloadConfig{
+		enable:          config.Enable,
+		maxPayloadBytes: maxPayloadBytes,
+	}
+}
+
+// startBackgroundRefreshSpan starts the span of refreshing soft expired keys in background
+func startBackgroundRefreshSpan(ctx context.Context, inner *cacheWrapperInner, keys []string) (context.Context, Span) {
+	ctx, span := inner.observationConfig.startSpan(ctx, spanNameBackgroundRefresh)
+	span.SetAttributes(SpanAttribute{Key: "cache.name", Value: inner.name}, SpanAttribute{Key: "cache.total_key_count", Value: len(keys)})
+	return ctx, span
+}
+
+// getFirstLoadResultErr gets the error of the first failed key in loadResultMap by key order, nil if all succeeded
+func getFirstLoadResultErr(loadResultMap map[string]loadResult) error {
+	var firstKey string
+	var firstErr error
+	for key, result := range loadResultMap {
+		if result.err != nil && (firstErr == nil || key < firstKey) {
+			firstKey, firstErr = key, result.err
+		}
+	}
+	return firstErr
+}
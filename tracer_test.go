@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	attributes map[string]interface{}
+	err        error
+	finished   bool
+}
+
+// recordedSpanCtxKey is the key of the current span in ctx
+type recordedSpanCtxKey struct{}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *recordingTracer) adapter() TracerAdapter {
+	return TracerAdapter{
+		Start: func(ctx context.Context, name string) (context.Context, interface{}) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			parent, _ := ctx.Value(recordedSpanCtxKey{}).(*recordedSpan)
+			span := &recordedSpan{name: name, parent: parent, attributes: make(map[string]interface{})}
+			r.spans = append(r.spans, span)
+			return context.WithValue(ctx, recordedSpanCtxKey{}, span), span
+		},
+		SetAttribute: func(span interface{}, key string, value interface{}) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			span.(*recordedSpan).attributes[key] = value
+		},
+		Finish: func(span interface{}, err error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			span.(*recordedSpan).err = err
+			span.(*recordedSpan).finished = true
+		},
+	}
+}
+
+func (r *recordingTracer) findSpan(name string) *recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, span := range r.spans {
+		if span.name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestTracerSpans(t *testing.T) {
+	ctx := context.Background()
+	tracer := &recordingTracer{}
+	c, err := NewInMemoryCache("test_tracer", InMemoryCacheConfig{
+		CacheType:         Ristretto,
+		ObservationConfig: ObservationConfig{Tracer: tracer.adapter()},
+	})
+	if err != nil {
+		t.Fatalf("new in-memory cache err: %v", err)
+	}
+	defer func() { _ = c.Close(ctx) }()
+
+	var val string
+	if err := c.Get(ctx, "k", &val); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss, got: %v", err)
+	}
+	span := tracer.findSpan("cache.Get")
+	if span == nil || !span.finished || span.err != ErrCacheMiss {
+		t.Fatalf("expect finished Get span with cache miss, got: %+v", span)
+	}
+	for key, expected := range map[string]interface{}{
+		"cache.name":       "test_tracer",
+		"cache.operation":  cmdGet,
+		"cache.error_type": string(ErrorTypeCacheMiss),
+	} {
+		if span.attributes[key] != expected {
+			t.Fatalf("expect attribute %v as %v, got: %v", key, expected, span.attributes[key])
+		}
+	}
+	if _, ok := span.attributes["cache.request"]; ok {
+		t.Fatalf("expect no payload captured by default")
+	}
+
+	loader := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return []interface{}{"v"}, nil
+	}
+	if err := c.Load(ctx, loader, "k", &val, time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+	span = tracer.findSpan(spanNameDataLoader)
+	if span == nil || !span.finished || span.err != nil || span.attributes["cache.total_key_count"] != 1 {
+		t.Fatalf("expect finished DataLoader span, got: %+v", span)
+	}
+}
+
+func TestTracerSpanNesting(t *testing.T) {
+	ctx := context.Background()
+	tracer := &recordingTracer{}
+	observation := newObservationConfig(ObservationConfig{Tracer: tracer.adapter()})
+
+	// the spans started within the operation are children of the operation span
+	requestStatsDecorator(ctx, observation, &RequestStats{CacheOperation: cmdCompare}, func(ctx context.Context) error {
+		requestStatsDecorator(ctx, observation, &RequestStats{CacheOperation: cmdGetMany}, func(ctx context.Context) error { return nil })
+		_, span := observation.startSpan(ctx, spanNameAcquireDlock)
+		span.Finish(nil)
+		return nil
+	})
+
+	for _, name := range []string{"cache.GetMany", spanNameAcquireDlock} {
+		span := tracer.findSpan(name)
+		if span == nil || span.parent == nil || span.parent.name != "cache.Compare" {
+			t.Fatalf("expect %v span as a child of Compare span, got: %+v", name, span)
+		}
+	}
+	if span := tracer.findSpan("cache.Compare"); span == nil || span.parent != nil {
+		t.Fatalf("expect Compare span as the root, got: %+v", span)
+	}
+}
+
+func TestTracePayload(t *testing.T) {
+	ctx := context.Background()
+	tracer := &recordingTracer{}
+	observation := newObservationConfig(ObservationConfig{
+		Tracer:             tracer.adapter(),
+		TracePayloadConfig: TracePayloadConfig{Enable: true, MaxPayloadBytes: 8},
+	})
+
+	requestStatsDecorator(ctx, observation, &RequestStats{CacheOperation: cmdSet, req: "key", resp: []byte(strings.Repeat("v", 20))}, func(ctx context.Context) error { return nil })
+
+	span := tracer.findSpan("cache.Set")
+	if span == nil {
+		t.Fatalf("expect Set span")
+	}
+	if span.attributes["cache.request"] != "key" {
+		t.Fatalf("expect request captured, got: %v", span.attributes["cache.request"])
+	}
+	if span.attributes["cache.response"] != "vvvvvvvv...(truncated)" {
+		t.Fatalf("expect response truncated, got: %v", span.attributes["cache.response"])
+	}
+
+	if err := (ObservationConfig{TracePayloadConfig: TracePayloadConfig{MaxPayloadBytes: -1}}).Validate(); err == nil {
+		t.Fatalf("expect error on negative max payload bytes")
+	}
+}
@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"go-eCache/internal/utils"
+)
+
+// TypedDataLoader is the typed version of DataLoader, refer to DataLoader for the contract.
+// Unlike DataLoader, every returned value is set to cache, including zero values.
+type TypedDataLoader[T any] func(ctx context.Context, keys []string) ([]T, error)
+
+// TypedCache is a type-safe wrapper of InMemoryCache for values of type T.
+//
+// Values are stored as is (refer to WithSkipCodec) and are set to T without reflection.
+// A value stored with another type through the underlying InMemoryCache is reported as errTypedValueMismatch.
+type TypedCache[T any] struct {
+	cache *InMemoryCache
+}
+
+// NewTypedCache creates a new typed cache over the in-memory cache
+func NewTypedCache[T any](cache *InMemoryCache) *TypedCache[T] {
+	return &TypedCache[T]{cache: cache}
+}
+
+// Get (refer to Get of Cache interface)
+func (c *TypedCache[T]) Get(ctx context.Context, key string, opts ...OperationOption) (T, error) {
+	receiver := &typedReceiver[T]{}
+	err := c.cache.Get(ctx, key, receiver, withTypedOptions(opts)...)
+	return receiver.val, err
+}
+
+// GetWithMeta (refer to GetWithMeta of Cache interface)
+func (c *TypedCache[T]) GetWithMeta(ctx context.Context, key string, opts ...OperationOption) (T, ItemMeta, error) {
+	receiver := &typedReceiver[T]{}
+	meta, err := c.cache.GetWithMeta(ctx, key, receiver, withTypedOptions(opts)...)
+	return receiver.val, meta, err
+}
+
+// GetWithVersion (refer to GetWithVersion of Cache interface)
+func (c *TypedCache[T]) GetWithVersion(ctx context.Context, key string, opts ...OperationOption) (T, int64, error) {
+	receiver := &typedReceiver[T]{}
+	version, err := c.cache.GetWithVersion(ctx, key, receiver, withTypedOptions(opts)...)
+	return receiver.val, version, err
+}
+
+// TTL (refer to TTL of Cache interface)
+func (c *TypedCache[T]) TTL(ctx context.Context, key string, opts ...OperationOption) (ItemMeta, error) {
+	return c.cache.TTL(ctx, key, withTypedOptions(opts)...)
+}
+
+// GetMany (refer to GetMany of Cache interface), keys not cached are absent from the returned map.
+func (c *TypedCache[T]) GetMany(ctx context.Context, keys []string, opts ...OperationOption) (map[string]T, error) {
+	receiverMap := genTypedReceiverMap[T](keys)
+	if err := c.cache.GetMany(ctx, receiverMap, withTypedOptions(opts)...); err != nil {
+		return nil, err
+	}
+	return getTypedReceiverMapValues[T](receiverMap), nil
+}
+
+// Set (refer to Set of Cache interface)
+func (c *TypedCache[T]) Set(ctx context.Context, key string, value T, expire time.Duration, opts ...OperationOption) error {
+	return c.cache.Set(ctx, key, typedValue[T]{val: value}, expire, withTypedOptions(opts)...)
+}
+
+// SetMany (refer to SetMany of Cache interface)
+func (c *TypedCache[T]) SetMany(ctx context.Context, valueMap map[string]T, expire time.Duration, opts ...OperationOption) error {
+	typedValueMap := make(map[string]interface{}, len(valueMap))
+	for key, value := range valueMap {
+		typedValueMap[key] = typedValue[T]{val: value}
+	}
+	return c.cache.SetMany(ctx, typedValueMap, expire, withTypedOptions(opts)...)
+}
+
+// CompareAndSwap (refer to CompareAndSwap of Cache interface)
+func (c *TypedCache[T]) CompareAndSwap(ctx context.Context, key string, value T, version int64, expire time.Duration, opts ...OperationOption) error {
+	return c.cache.CompareAndSwap(ctx, key, typedValue[T]{val: value}, version, expire, withTypedOptions(opts)...)
+}
+
+// Delete (refer to Delete of Cache interface)
+func (c *TypedCache[T]) Delete(ctx context.Context, key string, opts ...OperationOption) error {
+	return c.cache.Delete(ctx, key, opts...)
+}
+
+// DeleteMany (refer to DeleteMany of Cache interface)
+func (c *TypedCache[T]) DeleteMany(ctx context.Context, keys []string, opts ...OperationOption) error {
+	return c.cache.DeleteMany(ctx, keys, opts...)
+}
+
+// InvalidateTags (refer to InvalidateTags of Cache interface)
+func (c *TypedCache[T]) InvalidateTags(ctx context.Context, tags ...string) error {
+	return c.cache.InvalidateTags(ctx, tags...)
+}
+
+// Load (refer to Load of Cache interface)
+func (c *TypedCache[T]) Load(ctx context.Context, loader TypedDataLoader[T], key string, expire time.Duration, opts ...OperationOption) (T, error) {
+	receiver := &typedReceiver[T]{}
+	err := c.cache.Load(ctx, loader.dataLoader(), key, receiver, expire, withTypedOptions(opts)...)
+	return receiver.val, err
+}
+
+// LoadMany (refer to LoadMany of Cache interface), keys neither cached nor loaded are absent from the returned map.
+func (c *TypedCache[T]) LoadMany(ctx context.Context, loader TypedDataLoader[T], keys []string, expire time.Duration, opts ...OperationOption) (map[string]T, error) {
+	receiverMap := genTypedReceiverMap[T](keys)
+	if err := c.cache.LoadMany(ctx, loader.dataLoader(), receiverMap, expire, withTypedOptions(opts)...); err != nil {
+		return nil, err
+	}
+	return getTypedReceiverMapValues[T](receiverMap), nil
+}
+
+// Cache returns the underlying in-memory cache
+func (c *TypedCache[T]) Cache() *InMemoryCache {
+	return c.cache
+}
+
+// dataLoader converts the typed loader to DataLoader, whose results are wrapped by typedValue
+func (l TypedDataLoader[T]) dataLoader() DataLoader {
+	if l == nil {
+		return nil
+	}
+	return func(ctx context.Context, keys []string) ([]interface{}, error) {
+		values, err := l(ctx, keys)
+		if values == nil {
+			return nil, err
+		}
+		dataList := make([]interface{}, len(values))
+		for idx, value := range values {
+			dataList[idx] = typedValue[T]{val: value}
+		}
+		return dataList, err
+	}
+}
+
+// withTypedOptions appends WithSkipCodec to opts, so that it can not be overridden
+func withTypedOptions(opts []OperationOption) []OperationOption {
+	typedOpts := make([]OperationOption, 0, len(opts)+1)
+	typedOpts = append(typedOpts, opts...)
+	return append(typedOpts, WithSkipCodec())
+}
+
+func genTypedReceiverMap[T any](keys []string) map[string]interface{} {
+	receiverMap := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		receiverMap[key] = &typedReceiver[T]{}
+	}
+	return receiverMap
+}
+
+func getTypedReceiverMapValues[T any](receiverMap map[string]interface{}) map[string]T {
+	values := make(map[string]T, len(receiverMap))
+	for key, receiver := range receiverMap {
+		if r, ok := receiver.(*typedReceiver[T]); ok {
+			values[key] = r.val
+		}
+	}
+	return values
+}
+
+// skipCodecValue is the value whose data can be stored without reflection when codec is skipped
+type skipCodecValue interface {
+	skipCodecData() interface{}
+}
+
+// skipCodecReceiver is the receiver which can be set without reflection when codec is skipped
+type skipCodecReceiver interface {
+	setSkipCodecData(data interface{}) error
+}
+
+type typedValue[T any] struct {
+	val T
+}
+
+func (v typedValue[T]) skipCodecData() interface{} {
+	return v.val
+}
+
+type typedReceiver[T any] struct {
+	val T
+}
+
+func (r *typedReceiver[T]) setSkipCodecData(data interface{}) error {
+	if data == nil {
+		var zero T
+		r.val = zero
+		return nil
+	}
+
+	val, ok := data.(T)
+	if !ok {
+		return errTypedValueMismatch
+	}
+	r.val = val
+	return nil
+}
+
+// getSkipCodecData gets the data to store when codec is skipped
+func getSkipCodecData(value interface{}) interface{} {
+	if v, ok := value.(skipCodecValue); ok {
+		return v.skipCodecData()
+	}
+	return utils.GetValue(value)
+}
+
+// setSkipCodecData sets the stored data to receiver when codec is skipped
+func setSkipCodecData(data interface{}, receiver interface{}) error {
+	if r, ok := receiver.(skipCodecReceiver); ok {
+		return r.setSkipCodecData(data)
+	}
+	return utils.SetValue(data, receiver)
+}
+
+// setSkipCodecLoadResultData sets the data of loadResult to receiver when codec is skipped.
+// The data may come from DataLoader, which is dereferenced for untyped receivers in the same way as it is stored.
+func setSkipCodecLoadResultData(data interface{}, receiver interface{}) error {
+	if r, ok := receiver.(skipCodecReceiver); ok {
+		if v, ok := data.(skipCodecValue); ok {
+			data = v.skipCodecData()
+		}
+		return r.setSkipCodecData(data)
+	}
+	return utils.SetValue(getSkipCodecData(data), receiver)
+}
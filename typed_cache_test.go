@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type typedTestItem struct {
+	ID   int64
+	Name string
+}
+
+func TestTypedCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewTypedCache[*typedTestItem](newTestInMemoryCache(t))
+
+	item := &typedTestItem{ID: 1, Name: "a"}
+	if err := c.Set(ctx, "k1", item, time.Minute, WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	got, err := c.Get(ctx, "k1")
+	if err != nil || got != item {
+		t.Fatalf("expect the same pointer stored as is, got %v, err: %v", got, err)
+	}
+	if _, err = c.Get(ctx, "non_exist"); err != ErrCacheMiss {
+		t.Fatalf("expect cache miss, got: %v", err)
+	}
+
+	calls := 0
+	loader := func(ctx context.Context, keys []string) ([]*typedTestItem, error) {
+		calls++
+		res := make([]*typedTestItem, len(keys))
+		for i, key := range keys {
+			res[i] = &typedTestItem{Name: key}
+		}
+		return res, nil
+	}
+	loaded, err := c.Load(ctx, loader, "k2", time.Minute, WithWaitRistretto())
+	if err != nil || loaded.Name != "k2" {
+		t.Fatalf("unexpected loaded value %v, err: %v", loaded, err)
+	}
+	if got, err = c.Get(ctx, "k2"); err != nil || got != loaded {
+		t.Fatalf("expect loaded value cached, got %v, err: %v", got, err)
+	}
+
+	values, err := c.LoadMany(ctx, loader, []string{"k1", "k2", "k3"}, time.Minute)
+	if err != nil || len(values) != 3 || values["k1"] != item || values["k3"].Name != "k3" {
+		t.Fatalf("unexpected load many result %v, err: %v", values, err)
+	}
+	if calls != 2 {
+		t.Fatalf("expect loader to be called twice, got %v", calls)
+	}
+
+	values, err = c.GetMany(ctx, []string{"k1", "non_exist"})
+	if err != nil || len(values) != 1 || values["k1"] != item {
+		t.Fatalf("unexpected get many result %v, err: %v", values, err)
+	}
+
+	// value stored with another type through the underlying cache
+	if err = c.Cache().Set(ctx, "other", "v", time.Minute, WithSkipCodec(), WithWaitRistretto()); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+	if _, err = c.Get(ctx, "other"); err != errTypedValueMismatch {
+		t.Fatalf("expect type mismatch error, got: %v", err)
+	}
+}
@@ -11,10 +11,11 @@ import (
 var _ InternalUnifiedCacheCache = &internalUnifiedCacheClient{}
 var internalUnifiedCacheClientImp InternalUnifiedCacheCache
 
-// unifiedCache
-var (
-	inMemoryCache *InMemoryCache
-)
+// unifiedCacheName is the name of the in-memory cache backing the unified cache
+const unifiedCacheName = "in_memory_ristretto_cache"
+
+// unifiedCacheManager manages the caches of the unified cache
+var unifiedCacheManager *Manager
 
 type internalUnifiedCacheClient struct {
 	CacheClient Cache
@@ -27,13 +28,17 @@ func GetUnifiedCache() InternalUnifiedCacheCache {
 
 // InitUnifiedCache is a function to initial cache
 func InitUnifiedCache(maxCapacity int64, maxNumCounters int64) {
-	initInMemoryCache(maxCapacity, maxNumCounters)
+	initUnifiedCacheManager(maxCapacity, maxNumCounters)
+	cacheClient, err := unifiedCacheManager.Get(unifiedCacheName)
+	if err != nil {
+		panic(err)
+	}
 	internalUnifiedCacheClientImp = &internalUnifiedCacheClient{
-		CacheClient: inMemoryCache,
+		CacheClient: cacheClient,
 	}
 }
 
-func initInMemoryCache(maxCapacity int64, maxNumCounters int64) {
+func initUnifiedCacheManager(maxCapacity int64, maxNumCounters int64) {
 	if maxCapacity == 0 {
 		maxCapacity = 268435456
 	}
@@ -55,12 +60,13 @@ func initInMemoryCache(maxCapacity int64, maxNumCounters int64) {
 			Type: codec.Jsoniter,
 		},
 	}
-	var err error
-	inMemoryCache, err = NewInMemoryCache("in_memory_ristretto_cache", unifiedConfig)
+	manager, err := NewManager(map[string]Config{
+		unifiedCacheName: {Type: InMemory, InMemory: unifiedConfig},
+	})
 	if err != nil {
 		panic(err)
 	}
-
+	unifiedCacheManager = manager
 }
 
 // InternalUnifiedCacheCache is the interface of a cache store